@@ -2,101 +2,168 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/shubhamjaiswar43/restify/internal/auth"
 	"github.com/shubhamjaiswar43/restify/internal/config"
 	"github.com/shubhamjaiswar43/restify/internal/handler"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/mailer"
+	"github.com/shubhamjaiswar43/restify/internal/oidc"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
 	"github.com/shubhamjaiswar43/restify/internal/storage/mongodb"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func rootMessage(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Welcome to Restaurant Management System"))
 }
 
-func main() {
-	// Load config
-	cfg := config.MustLoad()
-	// MongoDB setup
-	dbClient, err := mongodb.New(cfg)
+func runMigrations(db *mongodb.MongoDb) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	applied, err := db.Migrate(ctx)
 	if err != nil {
-		slog.Error("DB connection failed", slog.String("error", err.Error()))
-		return
+		return err
 	}
+	slog.Info("Database schema up to date", slog.Any("applied_versions", applied))
+	return nil
+}
 
-	// Initialize stores
-	userStore := mongodb.NewUserStore(dbClient.Db.Collection("users"))
-	restaurantStore := mongodb.NewRestaurantStore(dbClient.Db.Collection("restaurants"))
-	menuStore := mongodb.NewMenuStore(dbClient.Db.Collection("menu"))
-	orderStore := mongodb.NewOrderStore(dbClient.Db.Collection("orders"))
-
-	// Initialize handlers
-	jwtManager := auth.NewJWTManager(cfg.JWTSecret, 24*time.Hour)
-	userHandler := handler.NewUserHandler(userStore, jwtManager, cfg.AdminSecret)
-	restaurantHandler := handler.NewRestaurantHandler(restaurantStore)
-	menuHandler := handler.NewMenuHandler(menuStore, restaurantStore)
-	orderHandler := handler.NewOrderHandler(orderStore)
-
-	// middlewares
-	authMiddleware := auth.NewAuthMiddleware(cfg.JWTSecret)
-
-	// Router
-	router := http.NewServeMux()
-	router.HandleFunc("/", rootMessage)
-
-	// Auth routes
-	router.HandleFunc("/signup", userHandler.Signup)
-	router.HandleFunc("/login", userHandler.Login)
+// newJWTManager signs with the configured asymmetric keys, falling back to
+// HS256 with the shared secret when there are none.
+func newJWTManager(cfg *config.Config) (*auth.JWTManager, error) {
+	if len(cfg.JWTKeys) == 0 {
+		return auth.NewJWTManager(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.JWTIssuer), nil
+	}
+	keys := make([]*auth.Key, 0, len(cfg.JWTKeys))
+	for _, k := range cfg.JWTKeys {
+		key, err := auth.LoadKey(k.ID, k.Algorithm, k.PrivateKeyFile, k.PublicKeyFile, k.VerifyUntil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	keySet, err := auth.NewKeySet(cfg.JWTActiveKey, keys...)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Signing access tokens with asymmetric key", slog.String("kid", cfg.JWTActiveKey))
+	return auth.NewKeyedJWTManager(keySet, cfg.AccessTokenTTL, cfg.JWTIssuer), nil
+}
 
-	// Restaurant routes with role-based JWT middleware
-	router.HandleFunc("/restaurants", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			authMiddleware("admin", "customer")(restaurantHandler.GetRestaurants)(w, r)
-		case http.MethodPost:
-			authMiddleware("admin")(restaurantHandler.CreateRestaurant)(w, r)
-		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			w.Write([]byte("Method Not Allowed"))
-			slog.Warn("method not allowed", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+// newMailer picks the mail driver from config.
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "log":
+		return mailer.NewLogMailer(cfg.Mail.From, cfg.Mail.Dir), nil
+	case "smtp":
+		if cfg.Mail.Host == "" {
+			return nil, errors.New("mail.smtp_host is required by the smtp driver")
 		}
-	})
+		return mailer.NewSMTPMailer(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
 
-	// Menu routes
-	router.HandleFunc("/menu-items", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			authMiddleware("admin", "customer")(menuHandler.GetMenuItems)(w, r)
-		} else if r.Method == http.MethodPost {
-			authMiddleware("admin")(menuHandler.CreateMenuItem)(w, r)
+// newOIDCHandler sets up single sign-on through the configured identity
+// provider, checking the group mappings up front.
+func newOIDCHandler(cfg *config.Config, users storage.UserStore, members storage.MembershipStore, sessions *handler.UserHandler) (*handler.OIDCHandler, error) {
+	c := cfg.OIDC
+	if c.IssuerURL == "" || c.ClientID == "" || c.RedirectURL == "" {
+		return nil, errors.New("oidc.issuer_url, oidc.client_id and oidc.redirect_url are required")
+	}
+	var groupRoles []handler.GroupRole
+	for _, m := range c.GroupRoles {
+		gr := handler.GroupRole{Group: m.Group, Role: m.Role}
+		if m.RestaurantID == "" {
+			if m.Role != "admin" && m.Role != "customer" {
+				return nil, fmt.Errorf("group %q: global role must be admin or customer, got %q", m.Group, m.Role)
+			}
 		} else {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			id, err := primitive.ObjectIDFromHex(m.RestaurantID)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: invalid restaurant_id %q", m.Group, m.RestaurantID)
+			}
+			if !slices.Contains(types.StaffRoles, m.Role) {
+				return nil, fmt.Errorf("group %q: unknown staff role %q", m.Group, m.Role)
+			}
+			gr.RestaurantID = id
 		}
+		groupRoles = append(groupRoles, gr)
+	}
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    c.IssuerURL,
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Scopes:       c.Scopes,
+		GroupsClaim:  c.GroupsClaim,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
 	})
+	return handler.NewOIDCHandler(provider, oidc.NewMemoryStateStore(), c.StateTTL, groupRoles, users, members, sessions), nil
+}
 
-	// Order routes
-	router.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			authMiddleware("admin", "customer")(orderHandler.CreateOrder)(w, r)
-		case http.MethodGet:
-			authMiddleware("admin")(orderHandler.GetAllOrders)(w, r)
-		default:
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
+func main() {
+	// Load config
+	cfg := config.MustLoad()
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	command := flag.Arg(0) // "" to serve, "migrate" to only apply migrations
+	if err := helper.ValidateVar(cfg.DefaultCurrency, "required,iso4217"); err != nil {
+		slog.Error("default_currency must be an ISO 4217 currency code", slog.String("default_currency", cfg.DefaultCurrency))
+		os.Exit(1)
+	}
 
-	router.HandleFunc("/orders/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			authMiddleware("admin", "customer")(orderHandler.GetOrderByID)(w, r)
-		} else {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	// Storage setup
+	var store storage.Storage
+	switch cfg.StorageBackend {
+	case "memory":
+		if command == "migrate" {
+			slog.Info("In-memory storage has no schema to migrate")
+			return
 		}
-	})
+		slog.Warn("Using in-memory storage, data will be lost on shutdown")
+		store = memory.New()
+	case "mongodb":
+		dbClient, err := mongodb.New(cfg)
+		if err != nil {
+			slog.Error("DB connection failed", slog.String("error", err.Error()))
+			return
+		}
+		if command == "migrate" || cfg.MigrateOnStart {
+			if err := runMigrations(dbClient); err != nil {
+				slog.Error("Migration failed", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}
+		if command == "migrate" {
+			return
+		}
+		store = dbClient
+	default:
+		slog.Error("Unknown storage backend", slog.String("storage_backend", cfg.StorageBackend))
+		return
+	}
+
+	router, err := newRouter(cfg, store)
+	if err != nil {
+		slog.Error("Failed to set up server", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	// HTTP server setup
 	server := http.Server{
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/config"
+	"github.com/shubhamjaiswar43/restify/internal/handler"
+	"github.com/shubhamjaiswar43/restify/internal/lockout"
+	"github.com/shubhamjaiswar43/restify/internal/server"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+)
+
+// newRouter builds the handlers on top of store and registers every route of
+// the API with its middleware.
+func newRouter(cfg *config.Config, store storage.Storage) (*server.Router, error) {
+	// Initialize stores
+	userStore := store.Users()
+	restaurantStore := store.Restaurants()
+	menuStore := store.Menu()
+	orderStore := store.Orders()
+	tokenStore := store.Tokens()
+	membershipStore := store.Memberships()
+	oneTimeStore := store.OneTimeTokens()
+	invitationStore := store.Invitations()
+	apiKeyStore := store.APIKeys()
+	promotionStore := store.Promotions()
+
+	// Authorization policy
+	policy, err := authz.LoadPolicy(cfg.PolicyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load authorization policy: %w", err)
+	}
+	authorizer := authz.New(policy, membershipStore)
+
+	// Initialize handlers
+	jwtManager, err := newJWTManager(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("load JWT signing keys: %w", err)
+	}
+	attempts := lockout.NewMemoryStore(24 * time.Hour)
+	accountGuard := lockout.NewGuard(attempts, lockout.Policy{
+		MaxFailures:     cfg.Lockout.MaxAccountFailures,
+		LockoutDuration: cfg.Lockout.Duration,
+		BackoffBase:     cfg.Lockout.BackoffBase,
+		BackoffMax:      cfg.Lockout.BackoffMax,
+	})
+	// Many users can share an IP, so it is only locked out, without backoff
+	ipGuard := lockout.NewGuard(attempts, lockout.Policy{
+		MaxFailures:     cfg.Lockout.MaxIPFailures,
+		LockoutDuration: cfg.Lockout.Duration,
+	})
+	mail, err := newMailer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("set up mailer: %w", err)
+	}
+	resetGuard := lockout.NewGuard(attempts, lockout.Policy{
+		MaxFailures:     5,
+		LockoutDuration: cfg.PasswordResetTTL,
+		BackoffBase:     cfg.PasswordResetInterval,
+		BackoffMax:      cfg.PasswordResetTTL,
+	})
+	accountHandler := handler.NewAccountHandler(userStore, oneTimeStore, tokenStore, mail, cfg.Mail.LinkBaseURL, cfg.PasswordResetTTL, cfg.EmailVerificationTTL, ipGuard, resetGuard)
+	invitationHandler := handler.NewInvitationHandler(invitationStore, userStore, membershipStore, restaurantStore, authorizer, accountHandler, cfg.InvitationTTL)
+	userHandler := handler.NewUserHandler(userStore, tokenStore, jwtManager, cfg.RefreshTokenTTL, cfg.AdminSecret, accountGuard, ipGuard, accountHandler, handler.MFAPolicy{
+		Issuer:        cfg.MFA.Issuer,
+		RequiredRoles: cfg.MFA.RequiredRoles,
+		ChallengeTTL:  cfg.MFA.ChallengeTTL,
+	}, invitationHandler, membershipStore, authorizer)
+	restaurantHandler := handler.NewRestaurantHandler(restaurantStore, authorizer, cfg.DefaultCurrency)
+	menuHandler := handler.NewMenuHandler(menuStore, restaurantStore, authorizer)
+	orderHandler := handler.NewOrderHandler(orderStore, menuStore, restaurantStore, promotionStore, authorizer, cfg.RequireVerifiedEmail)
+	promotionHandler := handler.NewPromotionHandler(promotionStore, restaurantStore, authorizer)
+	staffHandler := handler.NewStaffHandler(membershipStore, userStore, restaurantStore, authorizer)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyStore, userStore)
+	var oidcHandler *handler.OIDCHandler
+	if cfg.OIDC.Enabled {
+		oidcHandler, err = newOIDCHandler(cfg, userStore, membershipStore, userHandler)
+		if err != nil {
+			return nil, fmt.Errorf("set up OIDC login: %w", err)
+		}
+	}
+
+	// middlewares
+	authMiddleware := auth.NewAuthMiddleware(jwtManager, auth.TokenRevocations{Store: tokenStore}, auth.APIKeys{
+		Keys:          apiKeyStore,
+		Users:         userStore,
+		Tokens:        tokenStore,
+		TouchInterval: cfg.APIKeyTouchInterval,
+	})
+	authenticated := authMiddleware()
+	require := authorizer.Require
+
+	// Router
+	trustedProxies, err := server.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	router := server.NewRouter()
+	router.Use(server.Recoverer, server.RealIP(trustedProxies), server.RequestLogger, auth.ProvideUsers(userStore))
+	router.Get("/{$}", rootMessage)
+	router.Get("/.well-known/jwks.json", userHandler.JWKS)
+
+	// Auth routes
+	router.Post("/signup", userHandler.Signup)
+	router.Post("/login", userHandler.Login)
+	router.Post("/token/refresh", userHandler.RefreshToken)
+	router.Post("/login/mfa", userHandler.LoginMFA)
+	router.Post("/logout", userHandler.Logout, authenticated)
+	router.Post("/password/forgot", accountHandler.ForgotPassword)
+	router.Post("/password/reset", accountHandler.ResetPassword)
+	router.Post("/email/verify", accountHandler.VerifyEmail)
+	router.Post("/email/verify/resend", accountHandler.ResendVerification, authenticated)
+	if oidcHandler != nil {
+		router.Get("/oidc/login", oidcHandler.Login)
+		router.Get("/oidc/callback", oidcHandler.Callback)
+	}
+
+	// Two-factor routes; enrollment also accepts the challenge token of a
+	// user whose role requires MFA
+	enrolling := auth.AcceptChallenge(jwtManager, auth.PurposeMFAEnrollment, authenticated)
+	router.Post("/mfa/enroll", userHandler.EnrollMFA, enrolling)
+	router.Post("/mfa/enable", userHandler.EnableMFA, enrolling)
+	router.Post("/mfa/disable", userHandler.DisableMFA, authenticated)
+	router.Post("/mfa/recovery-codes", userHandler.RegenerateRecoveryCodes, authenticated)
+
+	// Routes guarded by a global permission; restaurant- and owner-scoped
+	// permissions are checked by the handlers through the same authorizer.
+
+	// User management routes
+	router.Get("/users", userHandler.GetAllUsers, authenticated, require("users:read"))
+	router.Get("/users/{id}", userHandler.GetUser, authenticated, require("users:read"))
+	router.Patch("/users/{id}", userHandler.UpdateUser, authenticated, require("users:write"))
+	router.Delete("/users/{id}", userHandler.DeleteUser, authenticated, require("users:delete"))
+	router.Delete("/users/{id}/sessions", userHandler.RevokeUserSessions, authenticated, require("users:revoke-sessions"))
+	router.Post("/users/{id}/unlock", userHandler.UnlockUser, authenticated, require("users:unlock"))
+	router.Delete("/users/{id}/mfa", userHandler.ResetUserMFA, authenticated, require("users:reset-mfa"))
+
+	// API key routes
+	router.Post("/api-keys", apiKeyHandler.CreateAPIKey, authenticated, require("api-keys:write"))
+	router.Get("/api-keys", apiKeyHandler.ListAPIKeys, authenticated, require("api-keys:read"))
+	router.Delete("/api-keys/{id}", apiKeyHandler.RevokeAPIKey, authenticated, require("api-keys:write"))
+
+	// Invitation routes; who may invite to which role is checked by the handlers
+	router.Post("/invitations", invitationHandler.CreateInvitation, authenticated)
+	router.Get("/invitations", invitationHandler.ListInvitations, authenticated)
+	router.Post("/invitations/{id}/revoke", invitationHandler.RevokeInvitation, authenticated)
+	router.Post("/invitations/accept", invitationHandler.AcceptInvitation, authenticated)
+
+	// Restaurant routes
+	router.Get("/restaurants", restaurantHandler.GetRestaurants, authenticated, require("restaurants:read"))
+	router.Post("/restaurants", restaurantHandler.CreateRestaurant, authenticated, require("restaurants:create"))
+	router.Get("/restaurants/{id}", restaurantHandler.GetRestaurantByID, authenticated, require("restaurants:read"))
+	router.Put("/restaurants/{id}", restaurantHandler.UpdateRestaurant, authenticated)
+	router.Patch("/restaurants/{id}", restaurantHandler.PatchRestaurant, authenticated)
+	router.Delete("/restaurants/{id}", restaurantHandler.DeleteRestaurant, authenticated)
+	router.Get("/restaurants/{id}/tax", restaurantHandler.GetTaxPolicy, authenticated, require("restaurants:read"))
+	router.Put("/restaurants/{id}/tax", restaurantHandler.SetTaxPolicy, authenticated)
+	router.Get("/restaurants/{id}/orders", orderHandler.GetRestaurantOrders, authenticated)
+	router.Get("/restaurants/{id}/staff", staffHandler.ListStaff, authenticated)
+	router.Put("/restaurants/{id}/staff/{user_id}", staffHandler.SetStaffRole, authenticated)
+	router.Delete("/restaurants/{id}/staff/{user_id}", staffHandler.RemoveStaff, authenticated)
+
+	// Menu routes
+	router.Get("/menu-items", menuHandler.GetMenuItems, authenticated, require("menu:read"))
+	router.Post("/menu-items", menuHandler.CreateMenuItem, authenticated)
+	router.Get("/menu-items/{id}", menuHandler.GetMenuItemByID, authenticated, require("menu:read"))
+	router.Put("/menu-items/{id}", menuHandler.UpdateMenuItem, authenticated)
+	router.Patch("/menu-items/{id}", menuHandler.PatchMenuItem, authenticated)
+	router.Delete("/menu-items/{id}", menuHandler.DeleteMenuItem, authenticated)
+	router.Patch("/menu-items/{id}/availability", menuHandler.SetAvailability, authenticated)
+
+	// Promotion routes, authorized per restaurant in the handler
+	router.Get("/promotions", promotionHandler.GetPromotions, authenticated)
+	router.Post("/promotions", promotionHandler.CreatePromotion, authenticated)
+	router.Get("/promotions/{id}", promotionHandler.GetPromotionByID, authenticated)
+	router.Patch("/promotions/{id}", promotionHandler.PatchPromotion, authenticated)
+
+	// Order routes
+	router.Post("/orders", orderHandler.CreateOrder, authenticated)
+	router.Get("/orders", orderHandler.GetAllOrders, authenticated, require("orders:read"))
+	router.Get("/orders/{id}", orderHandler.GetOrderByID, authenticated)
+	router.Patch("/orders/{id}/status", orderHandler.UpdateOrderStatus, authenticated)
+	router.Get("/me", userHandler.GetMe, authenticated)
+	router.Patch("/me", userHandler.UpdateMe, authenticated)
+	router.Get("/me/orders", orderHandler.GetMyOrders, authenticated)
+	router.Get("/me/memberships", staffHandler.GetMyMemberships, authenticated)
+
+	return router, nil
+}
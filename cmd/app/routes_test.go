@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/config"
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
+)
+
+// id is a well-formed ObjectID for the paths that need one.
+const id = "65f1c0ffee0000000000cafe"
+
+func newTestRouter(t *testing.T) http.Handler {
+	cfg := &config.Config{
+		JWTSecret:   "test-secret",
+		AdminSecret: "admin-secret",
+		JWTIssuer:   "restify",
+		Lockout: config.Lockout{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			Duration:           15 * time.Minute,
+		},
+		Mail:                  config.Mail{Driver: "log"},
+		MFA:                   config.MFA{Issuer: "Restify", ChallengeTTL: 5 * time.Minute},
+		AccessTokenTTL:        15 * time.Minute,
+		RefreshTokenTTL:       time.Hour,
+		PasswordResetTTL:      time.Hour,
+		PasswordResetInterval: time.Minute,
+		EmailVerificationTTL:  time.Hour,
+		InvitationTTL:         time.Hour,
+		DefaultCurrency:       "USD",
+	}
+	router, err := newRouter(cfg, memory.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	router := newTestRouter(t)
+
+	// One route of each group: protected routes answer 401 before their
+	// handler runs, public ones reach it and reject the empty body
+	for _, tc := range []struct {
+		group, method, path string
+		status              int
+	}{
+		{"root", http.MethodGet, "/", http.StatusOK},
+		{"jwks", http.MethodGet, "/.well-known/jwks.json", http.StatusOK},
+		{"auth", http.MethodPost, "/login", http.StatusBadRequest},
+		{"auth", http.MethodPost, "/logout", http.StatusUnauthorized},
+		{"two-factor", http.MethodPost, "/mfa/enroll", http.StatusUnauthorized},
+		{"users", http.MethodGet, "/users", http.StatusUnauthorized},
+		{"api keys", http.MethodPost, "/api-keys", http.StatusUnauthorized},
+		{"invitations", http.MethodPost, "/invitations/accept", http.StatusUnauthorized},
+		{"restaurants", http.MethodGet, "/restaurants/" + id + "/staff", http.StatusUnauthorized},
+		{"menu", http.MethodGet, "/menu-items", http.StatusUnauthorized},
+		{"promotions", http.MethodPost, "/promotions", http.StatusUnauthorized},
+		{"orders", http.MethodPatch, "/orders/" + id + "/status", http.StatusUnauthorized},
+		{"me", http.MethodGet, "/me/orders", http.StatusUnauthorized},
+		{"unknown", http.MethodGet, "/nothing", http.StatusNotFound},
+		{"recovery", http.MethodGet, "/panic", http.StatusInternalServerError},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader("")))
+		if rec.Code != tc.status {
+			t.Errorf("%s: %s %s = %d, want %d", tc.group, tc.method, tc.path, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestRoutesMethodNotAllowed(t *testing.T) {
+	router := newTestRouter(t)
+
+	for _, tc := range []struct {
+		method, path, allow string
+	}{
+		{http.MethodGet, "/login", "POST"},
+		{http.MethodGet, "/mfa/enable", "POST"},
+		{http.MethodPut, "/users/" + id, "GET, PATCH, DELETE, HEAD"},
+		{http.MethodPatch, "/api-keys", "POST, GET, HEAD"},
+		{http.MethodDelete, "/invitations", "POST, GET, HEAD"},
+		{http.MethodPost, "/restaurants/" + id + "/tax", "GET, PUT, HEAD"},
+		{http.MethodPost, "/menu-items/" + id + "/availability", "PATCH"},
+		{http.MethodDelete, "/promotions/" + id, "GET, PATCH, HEAD"},
+		{http.MethodDelete, "/orders", "POST, GET, HEAD"},
+		{http.MethodDelete, "/me", "GET, PATCH, HEAD"},
+	} {
+		// Authentication is route middleware, so a wrong method is a 405
+		// even without credentials
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != tc.allow {
+			t.Errorf("%s %s = %d, Allow %q, want 405, Allow %q", tc.method, tc.path, rec.Code, rec.Header().Get("Allow"), tc.allow)
+		}
+	}
+}
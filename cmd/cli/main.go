@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"text/tabwriter"
 
+	"github.com/shubhamjaiswar43/restify/internal/types"
 	"golang.org/x/term"
 )
 
@@ -21,18 +22,19 @@ var jwtToken string
 var userRole string
 
 type Restaurant struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Address string `json:"address"`
-	Phone   string `json:"phone"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Phone    string `json:"phone"`
+	Currency string `json:"currency"`
 }
 
 type MenuItem struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Category    string  `json:"category"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Category    string      `json:"category"`
+	Description string      `json:"description"`
+	Price       types.Money `json:"price"`
 }
 
 type Order struct {
@@ -40,14 +42,15 @@ type Order struct {
 	UserID       string      `json:"user_id"`
 	RestaurantID string      `json:"restaurant_id"`
 	Status       string      `json:"status"`
-	TotalPrice   float64     `json:"total_price"`
+	TotalPrice   types.Money `json:"total_price"`
 	Items        []OrderItem `json:"items"`
 }
 
 type OrderItem struct {
-	MenuItemID string  `json:"menu_item_id"`
-	Quantity   int     `json:"quantity"`
-	Price      float64 `json:"price"`
+	MenuItemID string      `json:"menu_item_id"`
+	Quantity   int         `json:"quantity"`
+	Price      types.Money `json:"price"`
+	LineTotal  types.Money `json:"line_total"`
 }
 
 func main() {
@@ -87,14 +90,24 @@ func signup() {
 	passwordBytes, _ := term.ReadPassword(int(syscall.Stdin))
 	fmt.Println()
 	password := string(passwordBytes)
-	fmt.Print("Enter role (admin/customer): ")
-	role, _ := reader.ReadString('\n')
+	fmt.Print("Enter invitation token (leave empty if you have none): ")
+	inviteToken, _ := reader.ReadString('\n')
+	inviteToken = strings.TrimSpace(inviteToken)
+	role := "customer"
+	if inviteToken == "" {
+		fmt.Print("Enter role (admin only for the first admin/customer): ")
+		role, _ = reader.ReadString('\n')
+		role = strings.TrimSpace(role)
+	}
 
 	payload := map[string]string{
 		"name":     strings.TrimSpace(name),
 		"email":    strings.TrimSpace(email),
 		"password": strings.TrimSpace(password),
-		"role":     strings.TrimSpace(role),
+		"role":     role,
+	}
+	if inviteToken != "" {
+		payload["invite_token"] = inviteToken
 	}
 	data, _ := json.Marshal(payload)
 
@@ -277,7 +290,7 @@ func restaurantPanel(r Restaurant) {
 		case 1:
 			listMenu(r.ID)
 		case 2:
-			addMenu(r.ID)
+			addMenu(r)
 		case 3:
 			listOrders(r.ID)
 		case 4:
@@ -315,13 +328,13 @@ func listMenu(restaurantID string) []MenuItem {
 	w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
 	fmt.Fprintln(w, "No\tID\tName\tCategory\tPrice\tDescription")
 	for i, m := range parsed.MenuItems {
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%.2f\t%s\n", i+1, m.ID, m.Name, m.Category, m.Price, m.Description)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", i+1, m.ID, m.Name, m.Category, m.Price, m.Description)
 	}
 	w.Flush()
 	return parsed.MenuItems
 }
 
-func addMenu(restaurantID string) {
+func addMenu(r Restaurant) {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Print("Enter menu name: ")
 	name, _ := reader.ReadString('\n')
@@ -329,16 +342,20 @@ func addMenu(restaurantID string) {
 	category, _ := reader.ReadString('\n')
 	fmt.Print("Enter description: ")
 	description, _ := reader.ReadString('\n')
-	fmt.Print("Enter price: ")
-	var price float64
-	fmt.Scanln(&price)
+	fmt.Printf("Enter price (%s): ", r.Currency)
+	priceStr, _ := reader.ReadString('\n')
+	price, err := types.ParseMoney(priceStr, r.Currency)
+	if err != nil {
+		fmt.Println("Invalid price:", err)
+		return
+	}
 
 	payload := map[string]interface{}{
 		"name":          strings.TrimSpace(name),
 		"category":      strings.TrimSpace(category),
 		"description":   strings.TrimSpace(description),
 		"price":         price,
-		"restaurant_id": restaurantID,
+		"restaurant_id": r.ID,
 	}
 	data, _ := json.Marshal(payload)
 
@@ -357,7 +374,7 @@ func addMenu(restaurantID string) {
 }
 
 func listOrders(restaurantID string) {
-	url := fmt.Sprintf("%s/orders/?restaurant_id=%s", baseURL, restaurantID)
+	url := fmt.Sprintf("%s/restaurants/%s/orders", baseURL, restaurantID)
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("Authorization", "Bearer "+jwtToken)
 	resp, err := http.DefaultClient.Do(req)
@@ -381,7 +398,7 @@ func listOrders(restaurantID string) {
 	w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
 	fmt.Fprintln(w, "No\tID\tStatus\tTotal Price\tItems")
 	for i, o := range parsed.Orders {
-		fmt.Fprintf(w, "%d\t%s\t%s\t%.2f\t%d items\n", i+1, o.ID, o.Status, o.TotalPrice, len(o.Items))
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d items\n", i+1, o.ID, o.Status, o.TotalPrice, len(o.Items))
 	}
 	w.Flush()
 }
@@ -413,11 +430,8 @@ func addOrder(restaurantID string) {
 			{
 				"menu_item_id": selected.ID,
 				"quantity":     qty,
-				"price":        selected.Price,
 			},
 		},
-		"total_price": selected.Price * float64(qty),
-		"status":      "preparing",
 	}
 	data, _ := json.Marshal(payload)
 
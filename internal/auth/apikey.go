@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+)
+
+// APIKeyHeader carries the API key of a machine client.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyScheme starts every API key, so a leaked one is easy to recognize.
+const apiKeyScheme = "rfy_"
+
+// NewAPIKey returns a random API key for the client, the public prefix that
+// identifies it and the hash to persist. The key itself is never stored.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	p := make([]byte, 6)
+	if _, err := rand.Read(p); err != nil {
+		return "", "", "", err
+	}
+	secret, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(p)
+	key = apiKeyScheme + prefix + "_" + secret
+	return key, prefix, HashToken(key), nil
+}
+
+// apiKeyPrefix extracts the lookup prefix of key, or reports a malformed key.
+func apiKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyScheme)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	return prefix, ok && prefix != "" && secret != ""
+}
+
+// APIKeyResolver turns the API key of a machine client into claims.
+type APIKeyResolver interface {
+	// ResolveAPIKey returns (nil, nil) for an unknown, revoked or expired key.
+	ResolveAPIKey(ctx context.Context, key string) (*Claims, error)
+}
+
+// APIKeys resolves keys kept in a storage.APIKeyStore to the claims of the
+// key's user, narrowed to the key's scopes. The role is read from the user on
+// every request, so a demoted or deleted user's keys lose their rights too.
+// Keys created before the user's sessions were last revoked are turned away
+// like the sessions themselves.
+type APIKeys struct {
+	Keys   storage.APIKeyStore
+	Users  storage.UserStore
+	Tokens storage.TokenStore
+	// TouchInterval limits how often a busy key's last-used time is written.
+	TouchInterval time.Duration
+}
+
+func (a APIKeys) ResolveAPIKey(ctx context.Context, key string) (*Claims, error) {
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return nil, nil
+	}
+	k, err := a.Keys.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil || k == nil {
+		return nil, err
+	}
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(HashToken(key)), []byte(k.KeyHash)) != 1 || !k.Active(now) {
+		return nil, nil
+	}
+	cutoff, err := a.Tokens.UserTokensRevokedAt(ctx, k.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Millisecond precision, as in TokenRevocations.IsRevoked
+	if !cutoff.IsZero() && !k.CreatedAt.Truncate(time.Millisecond).After(cutoff.Truncate(time.Millisecond)) {
+		return nil, nil
+	}
+	user, err := a.Users.GetUserByID(ctx, k.UserID.Hex())
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= a.TouchInterval {
+		// Usage tracking must not turn a valid key away
+		if err := a.Keys.TouchAPIKey(ctx, k.ID, now); err != nil {
+			slog.Error("failed to record API key use", slog.String("api_key_id", k.ID.Hex()), slog.String("error", err.Error()))
+		}
+	}
+	return &Claims{
+		UserID:   user.ID.Hex(),
+		Role:     user.Role,
+		APIKeyID: k.ID.Hex(),
+		Scopes:   k.Scopes,
+	}, nil
+}
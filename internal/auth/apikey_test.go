@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveAPIKey(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserStore()
+	resolver := APIKeys{Keys: memory.NewAPIKeyStore(), Users: users, Tokens: memory.NewTokenStore(), TouchInterval: time.Minute}
+
+	newUser := func(role string) primitive.ObjectID {
+		t.Helper()
+		u := &types.User{ID: primitive.NewObjectID(), Name: "Client", Email: primitive.NewObjectID().Hex() + "@example.com", Role: role, CreatedAt: time.Now()}
+		if err := users.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		return u.ID
+	}
+	newKey := func(userID primitive.ObjectID, expiresAt *time.Time, scopes ...string) (string, *types.APIKey) {
+		t.Helper()
+		key, prefix, hash, err := NewAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := &types.APIKey{Name: "ci", Prefix: prefix, KeyHash: hash, UserID: userID, Scopes: scopes, CreatedAt: time.Now(), ExpiresAt: expiresAt}
+		if err := resolver.Keys.CreateAPIKey(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+		return key, k
+	}
+	resolves := func(key string) *Claims {
+		t.Helper()
+		claims, err := resolver.ResolveAPIKey(ctx, key)
+		if err != nil {
+			t.Fatalf("ResolveAPIKey: %v", err)
+		}
+		return claims
+	}
+
+	owner := newUser("customer")
+	key, k := newKey(owner, nil, "orders:read", "menu:*")
+	claims := resolves(key)
+	if claims == nil || claims.UserID != owner.Hex() || claims.Role != "customer" || claims.APIKeyID != k.ID.Hex() || !slices.Equal(claims.Scopes, k.Scopes) {
+		t.Fatalf("claims = %+v", claims)
+	}
+	if stored, _ := resolver.Keys.GetAPIKey(ctx, k.ID); stored.LastUsedAt == nil {
+		t.Error("last use was not recorded")
+	}
+
+	// The role is the user's current one
+	if err := users.SetRole(ctx, owner, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if claims := resolves(key); claims == nil || claims.Role != "admin" {
+		t.Errorf("after a role change claims = %+v", claims)
+	}
+
+	prefix := strings.SplitN(key, "_", 3)[1]
+	for name, bad := range map[string]string{
+		"empty":                      "",
+		"missing scheme":             strings.TrimPrefix(key, apiKeyScheme),
+		"missing secret":             apiKeyScheme + prefix + "_",
+		"missing prefix":             apiKeyScheme + "_secret",
+		"no separator":               apiKeyScheme + prefix,
+		"bearer token":               "eyJhbGciOiJIUzI1NiJ9.e30.sig",
+		"unknown prefix":             apiKeyScheme + "000000000000_" + strings.SplitN(key, "_", 3)[2],
+		"wrong secret, valid prefix": apiKeyScheme + prefix + "_wrong-secret",
+		"truncated":                  key[:len(key)-1],
+	} {
+		if claims := resolves(bad); claims != nil {
+			t.Errorf("%s key resolved to %+v", name, claims)
+		}
+	}
+
+	revoked, revokedKey := newKey(owner, nil, "orders:read")
+	if err := resolver.Keys.RevokeAPIKey(ctx, revokedKey.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if claims := resolves(revoked); claims != nil {
+		t.Error("revoked key resolved")
+	}
+
+	expiry := time.Now().Add(-time.Second)
+	expired, _ := newKey(owner, &expiry, "orders:read")
+	if claims := resolves(expired); claims != nil {
+		t.Error("expired key resolved")
+	}
+
+	// Revoking a user's sessions also cuts off the keys created before
+	other := newUser("customer")
+	before, _ := newKey(other, nil, "orders:read")
+	time.Sleep(2 * time.Millisecond)
+	if err := resolver.Tokens.RevokeUserTokens(ctx, other, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	after, _ := newKey(other, nil, "orders:read")
+	if claims := resolves(before); claims != nil {
+		t.Error("key created before the revocation cutoff resolved")
+	}
+	if claims := resolves(after); claims == nil {
+		t.Error("key created after the revocation cutoff was refused")
+	}
+
+	// A deleted user's keys stop working
+	if err := users.DeleteUser(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if claims := resolves(after); claims != nil {
+		t.Error("key of a deleted user resolved")
+	}
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// contextKey is unexported so no other package can read or overwrite the
+// values stored by this one.
+type contextKey int
+
+const (
+	identityKey contextKey = iota
+	userStoreKey
+)
+
+// ErrUnauthenticated is returned by CurrentUser when the context carries no claims.
+var ErrUnauthenticated = errors.New("auth: request is not authenticated")
+
+// identity is what the auth middleware attaches to a request: the verified
+// claims plus the user they belong to, loaded at most once.
+type identity struct {
+	claims *Claims
+
+	once sync.Once
+	user *types.User
+	err  error
+}
+
+// WithClaims returns a copy of ctx carrying claims, as the auth middleware does
+// for every authenticated request. Tests use it to act as any user.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, identityKey, &identity{claims: claims})
+}
+
+// WithUser is WithClaims for an already loaded user, so CurrentUser returns
+// it without touching a store.
+func WithUser(ctx context.Context, user *types.User) context.Context {
+	id := &identity{
+		claims: &Claims{UserID: user.ID.Hex(), Role: user.Role},
+		user:   user,
+	}
+	id.once.Do(func() {})
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// ClaimsFrom returns the claims of the authenticated caller, if any.
+func ClaimsFrom(ctx context.Context) (*Claims, bool) {
+	id, ok := ctx.Value(identityKey).(*identity)
+	if !ok || id.claims == nil {
+		return nil, false
+	}
+	return id.claims, true
+}
+
+// WithUserStore makes users available to CurrentUser for requests below ctx.
+func WithUserStore(ctx context.Context, users storage.UserStore) context.Context {
+	return context.WithValue(ctx, userStoreKey, users)
+}
+
+// ProvideUsers is middleware putting users into every request context for CurrentUser.
+func ProvideUsers(users storage.UserStore) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r.WithContext(WithUserStore(r.Context(), users)))
+		}
+	}
+}
+
+// CurrentUser loads the authenticated caller's user document on first use and
+// caches it for the rest of the request. It returns (nil, nil) when the user
+// no longer exists, matching the stores.
+func CurrentUser(ctx context.Context) (*types.User, error) {
+	id, ok := ctx.Value(identityKey).(*identity)
+	if !ok || id.claims == nil {
+		return nil, ErrUnauthenticated
+	}
+	id.once.Do(func() {
+		users, ok := ctx.Value(userStoreKey).(storage.UserStore)
+		if !ok {
+			id.err = errors.New("auth: no user store in context")
+			return
+		}
+		if _, err := primitive.ObjectIDFromHex(id.claims.UserID); err != nil {
+			return
+		}
+		id.user, id.err = users.GetUserByID(ctx, id.claims.UserID)
+	})
+	return id.user, id.err
+}
@@ -1,62 +1,179 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// JWTManager issues and verifies access tokens. With Keys set tokens are
+// signed asymmetrically and carry a kid; otherwise HS256 with SecretKey is
+// used, which suits a single service in development.
 type JWTManager struct {
 	SecretKey     string
+	Keys          *KeySet
 	TokenDuration time.Duration
+	Issuer        string
 }
 
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	// Purpose is set on challenge tokens, which only prove a step of a login
+	// and are never accepted as access tokens.
+	Purpose string `json:"purpose,omitempty"`
+	// APIKeyID and Scopes are set when the caller authenticated with an API
+	// key instead of a token; Scopes then limit the permissions of Role.
+	APIKeyID string   `json:"api_key_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// IssuedAtMs is iat in Unix milliseconds. iat only has whole seconds,
+	// too coarse to tell a token issued right after a revocation from one
+	// issued before it.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Challenge token purposes
+const (
+	// PurposeMFA marks a user who passed the password check and still has to
+	// enter a second factor.
+	PurposeMFA = "mfa"
+	// PurposeMFAEnrollment marks a user who passed the password check but must
+	// enroll in MFA before getting an access token.
+	PurposeMFAEnrollment = "mfa_enrollment"
+)
+
 // Create a new JWTManager instance
-func NewJWTManager(secretKey string, duration time.Duration) *JWTManager {
+func NewJWTManager(secretKey string, duration time.Duration, issuer string) *JWTManager {
 	return &JWTManager{
 		SecretKey:     secretKey,
 		TokenDuration: duration,
+		Issuer:        issuer,
 	}
 }
 
-// Generate a new JWT for given userID and role
+// NewKeyedJWTManager creates a JWTManager signing with the active key of keys.
+func NewKeyedJWTManager(keys *KeySet, duration time.Duration, issuer string) *JWTManager {
+	return &JWTManager{
+		Keys:          keys,
+		TokenDuration: duration,
+		Issuer:        issuer,
+	}
+}
+
+// Generate a new access token for given userID and role. Every token gets a
+// unique jti so it can be revoked on its own.
 func (j *JWTManager) Generate(userID string, role string) (string, error) {
+	return j.sign(userID, role, "", j.TokenDuration)
+}
+
+// GenerateChallenge issues a short-lived token for one step of a login, such
+// as entering an MFA code. Verify refuses it; use VerifyChallenge.
+func (j *JWTManager) GenerateChallenge(userID, role, purpose string, ttl time.Duration) (string, error) {
+	return j.sign(userID, role, purpose, ttl)
+}
+
+func (j *JWTManager) sign(userID, role, purpose string, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:     userID,
+		Role:       role,
+		Purpose:    purpose,
+		IssuedAtMs: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.TokenDuration)),
+			ID:        jti,
+			Issuer:    j.Issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
 
+	if j.Keys != nil {
+		key := j.Keys.active
+		token := jwt.NewWithClaims(key.method(), claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.Private)
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(j.SecretKey))
 }
 
-// Verify and parse token
+// Verify and parse an access token
 func (j *JWTManager) Verify(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(j.SecretKey), nil
-	})
+	claims, err := j.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("challenge token used as access token")
+	}
+	return claims, nil
+}
+
+// VerifyChallenge parses a challenge token issued for purpose.
+func (j *JWTManager) VerifyChallenge(tokenStr, purpose string) (*Claims, error) {
+	claims, err := j.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid challenge token")
+	}
+	return claims, nil
+}
+
+func (j *JWTManager) parse(tokenStr string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, j.verificationKey,
+		jwt.WithIssuer(j.Issuer),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, err
 	}
 
 	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
+	if !ok || !token.Valid || claims.ID == "" {
 		return nil, errors.New("invalid token")
 	}
 
 	return claims, nil
 }
+
+// verificationKey picks the key a token must be signed with. With a key set
+// only asymmetric tokens naming a known kid are accepted, and the algorithm
+// must be the key's own, so a public key can never be used as an HMAC secret.
+func (j *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	if j.Keys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(j.SecretKey), nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.Keys.lookup(kid, time.Now())
+	if !ok {
+		return nil, errors.New("unknown or retired signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Public, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported asymmetric signing algorithms.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key is one asymmetric JWT key, identified in token headers by its kid.
+// Keys without a private part can only verify, which is how a retired key
+// keeps accepting the tokens it signed until they expire.
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer // nil for verify-only keys
+	Public    crypto.PublicKey
+	// VerifyUntil stops the key from being accepted and published after that
+	// time. The zero value keeps it valid.
+	VerifyUntil time.Time
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+func (k *Key) usable(now time.Time) bool {
+	return k.VerifyUntil.IsZero() || now.Before(k.VerifyUntil)
+}
+
+// LoadKey reads a key from PEM files. privateFile may be empty for a
+// verify-only key, in which case publicFile is required.
+func LoadKey(id, algorithm, privateFile, publicFile string, verifyUntil time.Time) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("auth: JWT key without id")
+	}
+	if algorithm != AlgRS256 && algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("auth: key %s: unsupported algorithm %q, use %s or %s", id, algorithm, AlgRS256, AlgEdDSA)
+	}
+	k := &Key{ID: id, Algorithm: algorithm, VerifyUntil: verifyUntil}
+
+	switch {
+	case privateFile != "":
+		block, err := readPEM(privateFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %s: %w", id, err)
+		}
+		var parsed any
+		if block.Type == "RSA PRIVATE KEY" {
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		} else {
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %s: parse private key: %w", id, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("auth: key %s: private key cannot sign", id)
+		}
+		k.Private = signer
+		k.Public = signer.Public()
+	case publicFile != "":
+		block, err := readPEM(publicFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %s: %w", id, err)
+		}
+		k.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %s: parse public key: %w", id, err)
+		}
+	default:
+		return nil, fmt.Errorf("auth: key %s: needs a private or public key file", id)
+	}
+
+	switch k.Public.(type) {
+	case *rsa.PublicKey:
+		if algorithm != AlgRS256 {
+			return nil, fmt.Errorf("auth: key %s: RSA key used with %s", id, algorithm)
+		}
+	case ed25519.PublicKey:
+		if algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("auth: key %s: Ed25519 key used with %s", id, algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("auth: key %s: unsupported key type %T", id, k.Public)
+	}
+	return k, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from. To rotate, add the new key as active and keep the old
+// one, ideally verify-only with VerifyUntil set past the access token lifetime.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	order  []*Key // configuration order, for a stable JWKS
+}
+
+// NewKeySet builds a key set signing with the key named activeID.
+func NewKeySet(activeID string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if _, dup := ks.keys[k.ID]; dup {
+			return nil, fmt.Errorf("auth: duplicate JWT key id %s", k.ID)
+		}
+		ks.keys[k.ID] = k
+		ks.order = append(ks.order, k)
+	}
+	ks.active = ks.keys[activeID]
+	if ks.active == nil {
+		return nil, fmt.Errorf("auth: active JWT key %q is not configured", activeID)
+	}
+	if ks.active.Private == nil {
+		return nil, fmt.Errorf("auth: active JWT key %s has no private key", activeID)
+	}
+	return ks, nil
+}
+
+// lookup returns the key a token names in its kid header, if still accepted.
+func (ks *KeySet) lookup(kid string, now time.Time) (*Key, bool) {
+	k, ok := ks.keys[kid]
+	if !ok || !k.usable(now) {
+		return nil, false
+	}
+	return k, true
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 and EC
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	// EC
+	Y string `json:"y,omitempty"`
+}
+
+// PublicKey decodes the key. RSA, Ed25519 and P-256/P-384 EC keys are
+// supported, which covers what identity providers publish in practice.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	b64 := base64.RawURLEncoding
+	switch k.KeyType {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %s: bad modulus: %w", k.KeyID, err)
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("auth: key %s: bad exponent", k.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		x, err := b64.DecodeString(k.X)
+		if k.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("auth: key %s: unsupported or malformed OKP key", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("auth: key %s: unsupported curve %q", k.KeyID, k.Curve)
+		}
+		x, errX := b64.DecodeString(k.X)
+		y, errY := b64.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("auth: key %s: malformed EC point", k.KeyID)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("auth: key %s: point is not on the curve", k.KeyID)
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("auth: key %s: unsupported key type %q", k.KeyID, k.KeyType)
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that tokens are currently accepted from.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if ks == nil {
+		return set
+	}
+	now := time.Now()
+	for _, k := range ks.order {
+		if !k.usable(now) {
+			continue
+		}
+		jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
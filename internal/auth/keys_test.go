@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newRSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Key{ID: id, Algorithm: AlgRS256, Private: priv, Public: priv.Public()}
+}
+
+func newEdDSAKey(t *testing.T, id string) *Key {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Key{ID: id, Algorithm: AlgEdDSA, Private: priv, Public: pub}
+}
+
+// verifyOnly returns k without its private key.
+func verifyOnly(k *Key, until time.Time) *Key {
+	return &Key{ID: k.ID, Algorithm: k.Algorithm, Public: k.Public, VerifyUntil: until}
+}
+
+func newKeyedManager(t *testing.T, activeID string, keys ...*Key) *JWTManager {
+	t.Helper()
+	ks, err := NewKeySet(activeID, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKeyedJWTManager(ks, 15*time.Minute, "restify")
+}
+
+func TestKeyedJWTRoundTrip(t *testing.T) {
+	for alg, newKey := range map[string]func(*testing.T, string) *Key{AlgRS256: newRSAKey, AlgEdDSA: newEdDSAKey} {
+		t.Run(alg, func(t *testing.T) {
+			key := newKey(t, "key-1")
+			m := newKeyedManager(t, key.ID, key)
+			token, err := m.Generate("user-1", "customer")
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != alg {
+				t.Errorf("header = %v", parsed.Header)
+			}
+			claims, err := m.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.UserID != "user-1" || claims.Role != "customer" {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			// A signature by another key under the same kid is rejected
+			forged, _ := newKeyedManager(t, key.ID, newKey(t, key.ID)).Generate("user-1", "admin")
+			if _, err := m.Verify(forged); err == nil {
+				t.Error("accepted a token signed by another key")
+			}
+		})
+	}
+}
+
+func TestKeyedJWTRejectsUnknownKeys(t *testing.T) {
+	current := newEdDSAKey(t, "current")
+	m := newKeyedManager(t, "current", current)
+
+	other, _ := newKeyedManager(t, "other", newEdDSAKey(t, "other")).Generate("user-1", "admin")
+	if _, err := m.Verify(other); err == nil {
+		t.Error("accepted a token with an unknown kid")
+	}
+
+	// Tokens without a kid, or signed with the shared secret, are refused
+	// once keys are configured
+	plain := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{Issuer: "restify", ID: "x", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	noKid, _ := plain.SignedString(current.Private)
+	if _, err := m.Verify(noKid); err == nil {
+		t.Error("accepted a token without a kid")
+	}
+	hs, _ := NewJWTManager("secret", time.Minute, "restify").Generate("user-1", "admin")
+	if _, err := m.Verify(hs); err == nil {
+		t.Error("accepted an HS256 token")
+	}
+
+	// The kid must name a key of the token's algorithm
+	rsaKey := newRSAKey(t, "rsa")
+	both := newKeyedManager(t, "current", current, verifyOnly(rsaKey, time.Time{}))
+	mislabeled := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &Claims{RegisteredClaims: jwt.RegisteredClaims{Issuer: "restify", ID: "x", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}})
+	mislabeled.Header["kid"] = "rsa"
+	token, _ := mislabeled.SignedString(current.Private)
+	if _, err := both.Verify(token); err == nil {
+		t.Error("accepted an EdDSA token naming an RSA key")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, next := newRSAKey(t, "2025"), newEdDSAKey(t, "2026")
+	oldToken, err := newKeyedManager(t, "2025", old).Generate("user-1", "customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Retired but still within VerifyUntil: old tokens verify, new ones use the new key
+	rotated := newKeyedManager(t, "2026", next, verifyOnly(old, time.Now().Add(time.Hour)))
+	if _, err := rotated.Verify(oldToken); err != nil {
+		t.Errorf("token of the retired key rejected: %v", err)
+	}
+	newToken, _ := rotated.Generate("user-1", "customer")
+	if parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &Claims{}); parsed.Header["kid"] != "2026" {
+		t.Errorf("signed with %v", parsed.Header["kid"])
+	}
+	if got := len(rotated.Keys.JWKS().Keys); got != 2 {
+		t.Errorf("JWKS has %d keys during rotation, want 2", got)
+	}
+
+	// Past VerifyUntil the key is neither accepted nor published
+	expired := newKeyedManager(t, "2026", next, verifyOnly(old, time.Now().Add(-time.Second)))
+	if _, err := expired.Verify(oldToken); err == nil {
+		t.Error("accepted a token of an expired key")
+	}
+	if jwks := expired.Keys.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "2026" {
+		t.Errorf("JWKS after expiry = %+v", jwks)
+	}
+}
+
+func TestNewKeySet(t *testing.T) {
+	a := newEdDSAKey(t, "a")
+	for name, tc := range map[string]struct {
+		active string
+		keys   []*Key
+	}{
+		"missing active key":     {"b", []*Key{a}},
+		"verify-only active key": {"a", []*Key{verifyOnly(a, time.Time{})}},
+		"duplicate kid":          {"a", []*Key{a, newRSAKey(t, "a")}},
+	} {
+		if _, err := NewKeySet(tc.active, tc.keys...); err == nil {
+			t.Errorf("%s: NewKeySet succeeded", name)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, edKey := newRSAKey(t, "rsa"), newEdDSAKey(t, "ed")
+	ks, err := NewKeySet("ed", edKey, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS = %+v", jwks)
+	}
+	for i, want := range []struct {
+		key      *Key
+		kty, crv string
+	}{
+		{edKey, "OKP", "Ed25519"},
+		{rsaKey, "RSA", ""},
+	} {
+		jwk := jwks.Keys[i]
+		if jwk.KeyID != want.key.ID || jwk.KeyType != want.kty || jwk.Curve != want.crv || jwk.Algorithm != want.key.Algorithm || jwk.Use != "sig" {
+			t.Errorf("key %d = %+v", i, jwk)
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+		if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(want.key.Public) {
+			t.Errorf("key %d does not decode to the configured public key", i)
+		}
+	}
+
+	if jwks := (*KeySet)(nil).JWKS(); jwks.Keys == nil || len(jwks.Keys) != 0 {
+		t.Errorf("JWKS without keys = %#v, want an empty list", jwks)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	rsaKey, edKey := newRSAKey(t, "rsa"), newEdDSAKey(t, "ed")
+	rsaPKCS1 := write("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey.Private.(*rsa.PrivateKey)))
+	edPKCS8, _ := x509.MarshalPKCS8PrivateKey(edKey.Private)
+	edPrivate := write("ed.pem", "PRIVATE KEY", edPKCS8)
+	edPKIX, _ := x509.MarshalPKIXPublicKey(edKey.Public)
+	edPublic := write("ed.pub", "PUBLIC KEY", edPKIX)
+
+	if k, err := LoadKey("rsa", AlgRS256, rsaPKCS1, "", time.Time{}); err != nil || k.Private == nil {
+		t.Errorf("load RSA private key: %v", err)
+	}
+	if k, err := LoadKey("ed", AlgEdDSA, edPrivate, "", time.Time{}); err != nil || k.Private == nil {
+		t.Errorf("load Ed25519 private key: %v", err)
+	}
+	k, err := LoadKey("ed", AlgEdDSA, "", edPublic, time.Now().Add(time.Hour))
+	if err != nil || k.Private != nil || !edKey.Public.(ed25519.PublicKey).Equal(k.Public) {
+		t.Errorf("load verify-only key = %+v, %v", k, err)
+	}
+
+	for name, load := range map[string]func() (*Key, error){
+		"algorithm mismatch":    func() (*Key, error) { return LoadKey("rsa", AlgEdDSA, rsaPKCS1, "", time.Time{}) },
+		"unsupported algorithm": func() (*Key, error) { return LoadKey("ed", "HS256", edPrivate, "", time.Time{}) },
+		"no key file":           func() (*Key, error) { return LoadKey("ed", AlgEdDSA, "", "", time.Time{}) },
+		"missing id":            func() (*Key, error) { return LoadKey("", AlgEdDSA, edPrivate, "", time.Time{}) },
+		"missing file":          func() (*Key, error) { return LoadKey("ed", AlgEdDSA, filepath.Join(dir, "nope.pem"), "", time.Time{}) },
+	} {
+		if _, err := load(); err == nil {
+			t.Errorf("%s: LoadKey succeeded", name)
+		} else if !strings.HasPrefix(err.Error(), "auth: ") {
+			t.Errorf("%s: error %q lacks the package prefix", name, err)
+		}
+	}
+}
@@ -1,46 +1,36 @@
 package auth
 
 import (
-	"context"
 	"log/slog"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/shubhamjaiswar43/restify/internal/helper"
 )
 
-// AuthMiddleware checks JWT and user roles
-func NewAuthMiddleware(secret string) func(allowedRoles ...string) func(http.HandlerFunc) http.HandlerFunc {
+// AuthMiddleware checks the Bearer JWT and its revocation, or the X-API-Key
+// header of a machine client, and then the user's role. revocations and
+// apiKeys may be nil; without apiKeys API keys are not accepted.
+// Called without roles it only authenticates, leaving permissions to authz.
+func NewAuthMiddleware(jwtManager *JWTManager, revocations RevocationChecker, apiKeys APIKeyResolver) func(allowedRoles ...string) func(http.HandlerFunc) http.HandlerFunc {
 	return func(allowedRoles ...string) func(http.HandlerFunc) http.HandlerFunc {
 		return func(next http.HandlerFunc) http.HandlerFunc {
 			return func(w http.ResponseWriter, r *http.Request) {
-				authHeader := r.Header.Get("Authorization")
-				if authHeader == "" {
-					slog.Warn("missing Authorization header")
-					helper.WriteSimpleError(w, http.StatusUnauthorized, "Missing Authorization header")
-					return
-				}
-
-				tokenParts := strings.Split(authHeader, " ")
-				if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-					slog.Warn("invalid Authorization header format", slog.String("header", authHeader))
-					helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid Authorization header format")
-					return
+				var (
+					claims *Claims
+					ok     bool
+				)
+				if key := r.Header.Get(APIKeyHeader); key != "" && apiKeys != nil {
+					claims, ok = apiKeyClaims(w, r, apiKeys, key)
+				} else {
+					claims, ok = bearerClaims(w, r, jwtManager, revocations)
 				}
-
-				tokenStr := tokenParts[1]
-				jwtManager := NewJWTManager(secret, 24*time.Hour)
-
-				claims, err := jwtManager.Verify(tokenStr)
-				if err != nil {
-					slog.Error("invalid or expired token", slog.String("error", err.Error()))
-					helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired token")
+				if !ok {
 					return
 				}
 
-				// Check allowed roles
-				allowed := false
+				// Check allowed roles; without any, every authenticated user passes
+				allowed := len(allowedRoles) == 0
 				for _, role := range allowedRoles {
 					if claims.Role == role {
 						allowed = true
@@ -54,7 +44,7 @@ func NewAuthMiddleware(secret string) func(allowedRoles ...string) func(http.Han
 				}
 
 				// Add claims to request context
-				ctx := context.WithValue(r.Context(), "claims", claims)
+				ctx := WithClaims(r.Context(), claims)
 				slog.Info("authenticated request",
 					slog.String("user_id", claims.UserID),
 					slog.String("role", claims.Role),
@@ -66,3 +56,86 @@ func NewAuthMiddleware(secret string) func(allowedRoles ...string) func(http.Han
 		}
 	}
 }
+
+// bearerClaims verifies the Bearer access token of the request, writing the
+// error response when it is missing, invalid or revoked.
+func bearerClaims(w http.ResponseWriter, r *http.Request, jwtManager *JWTManager, revocations RevocationChecker) (*Claims, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		slog.Warn("missing Authorization header")
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Missing Authorization header")
+		return nil, false
+	}
+
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		slog.Warn("invalid Authorization header format", slog.String("header", authHeader))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid Authorization header format")
+		return nil, false
+	}
+
+	tokenStr := tokenParts[1]
+
+	claims, err := jwtManager.Verify(tokenStr)
+	if err != nil {
+		slog.Error("invalid or expired token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return nil, false
+	}
+
+	if revocations != nil {
+		revoked, err := revocations.IsRevoked(r.Context(), claims)
+		if err != nil {
+			slog.Error("revocation check failed", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to verify token")
+			return nil, false
+		}
+		if revoked {
+			slog.Warn("revoked token used", slog.String("user_id", claims.UserID), slog.String("jti", claims.ID))
+			helper.WriteSimpleError(w, http.StatusUnauthorized, "Token has been revoked")
+			return nil, false
+		}
+	}
+	return claims, true
+}
+
+// apiKeyClaims resolves the API key of the request, writing the error
+// response when it is unknown, revoked or expired.
+func apiKeyClaims(w http.ResponseWriter, r *http.Request, apiKeys APIKeyResolver, key string) (*Claims, bool) {
+	claims, err := apiKeys.ResolveAPIKey(r.Context(), key)
+	if err != nil {
+		slog.Error("API key check failed", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to verify API key")
+		return nil, false
+	}
+	if claims == nil {
+		slog.Warn("invalid API key used", slog.String("path", r.URL.Path))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid, expired or revoked API key")
+		return nil, false
+	}
+	slog.Info("API key accepted", slog.String("api_key_id", claims.APIKeyID), slog.String("user_id", claims.UserID))
+	return claims, true
+}
+
+// AcceptChallenge lets a Bearer challenge token issued for purpose stand in
+// for an access token; every other request goes through authenticated.
+// Handlers see the challenge claims, with Purpose set, through ClaimsFrom.
+func AcceptChallenge(jwtManager *JWTManager, purpose string, authenticated func(http.HandlerFunc) http.HandlerFunc) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		withAccessToken := authenticated(next)
+		return func(w http.ResponseWriter, r *http.Request) {
+			if tokenStr, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				if claims, err := jwtManager.VerifyChallenge(tokenStr, purpose); err == nil {
+					slog.Info("challenge token accepted",
+						slog.String("user_id", claims.UserID),
+						slog.String("purpose", purpose),
+						slog.String("path", r.URL.Path),
+					)
+					next(w, r.WithContext(WithClaims(r.Context(), claims)))
+					return
+				}
+			}
+			withAccessToken(w, r)
+		}
+	}
+}
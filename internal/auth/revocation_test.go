@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIsRevokedUserCutoff(t *testing.T) {
+	ctx := context.Background()
+	jwtManager := NewJWTManager("secret", time.Minute, "restify")
+	tokens := memory.NewTokenStore()
+	revocations := TokenRevocations{Store: tokens}
+	userID := primitive.NewObjectID()
+
+	issue := func() *Claims {
+		t.Helper()
+		tok, err := jwtManager.Generate(userID.Hex(), "customer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := jwtManager.Verify(tok)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return claims
+	}
+
+	before := issue()
+	time.Sleep(2 * time.Millisecond)
+	if err := tokens.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// A login right after the revocation, usually within the same second
+	time.Sleep(2 * time.Millisecond)
+	after := issue()
+
+	if revoked, err := revocations.IsRevoked(ctx, before); err != nil || !revoked {
+		t.Errorf("token issued before the cutoff: revoked = %v, %v; want true", revoked, err)
+	}
+	if revoked, err := revocations.IsRevoked(ctx, after); err != nil || revoked {
+		t.Errorf("token issued after the cutoff: revoked = %v, %v; want false", revoked, err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package authz decides whether the authenticated caller may perform an
+// action, based on a role-to-permission policy and restaurant memberships.
+package authz
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Resource describes what a permission is checked against. Zero fields mean
+// the resource is not tied to a restaurant or owner, so only global role
+// grants can allow it.
+type Resource struct {
+	RestaurantID primitive.ObjectID
+	OwnerID      primitive.ObjectID
+}
+
+// DeniedError is returned when the policy does not allow the action.
+type DeniedError struct {
+	Permission string
+	Reason     string
+}
+
+func (e *DeniedError) Error() string {
+	return "authz: " + e.Permission + " denied: " + e.Reason
+}
+
+// IsDenied reports whether err is a policy denial rather than a failed check.
+func IsDenied(err error) bool {
+	var denied *DeniedError
+	return errors.As(err, &denied)
+}
+
+// Authorizer evaluates a Policy for the caller found in the request context.
+type Authorizer struct {
+	Policy  *Policy
+	Members storage.MembershipStore
+}
+
+func New(policy *Policy, members storage.MembershipStore) *Authorizer {
+	return &Authorizer{Policy: policy, Members: members}
+}
+
+// Authorize returns nil when the caller may perform permission on res, and a
+// *DeniedError, logged with the reason, when they may not.
+func (a *Authorizer) Authorize(ctx context.Context, permission string, res Resource) error {
+	d, err := a.decide(ctx, permission, res)
+	if err != nil {
+		return err
+	}
+	if d.reason != "" {
+		slog.Warn("authorization denied",
+			slog.String("permission", permission),
+			slog.String("user_id", d.userID),
+			slog.String("role", d.role),
+			slog.String("staff_role", d.staffRole),
+			slog.String("restaurant_id", hexOrEmpty(res.RestaurantID)),
+			slog.String("owner_id", hexOrEmpty(res.OwnerID)),
+			slog.String("reason", d.reason),
+		)
+		return &DeniedError{Permission: permission, Reason: d.reason}
+	}
+	return nil
+}
+
+// Can is Authorize for optional capabilities, such as seeing extra data: a
+// denial is the expected answer for most callers, so it is not logged.
+func (a *Authorizer) Can(ctx context.Context, permission string, res Resource) (bool, error) {
+	d, err := a.decide(ctx, permission, res)
+	return err == nil && d.reason == "", err
+}
+
+// ActingRole returns the role the caller acts in at a restaurant: their staff
+// role there, or else their global role. It is meant for audit records.
+func (a *Authorizer) ActingRole(ctx context.Context, restaurantID primitive.ObjectID) (string, error) {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return "", nil
+	}
+	if claims.Role == "admin" || restaurantID.IsZero() {
+		return claims.Role, nil
+	}
+	staffRole, err := a.staffRole(ctx, claims, restaurantID)
+	if err != nil || staffRole == "" {
+		return claims.Role, err
+	}
+	return staffRole, nil
+}
+
+// Require is route middleware allowing only callers that hold permission
+// globally. Restaurant- or owner-scoped checks belong in the handler.
+func (a *Authorizer) Require(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if err := a.Authorize(r.Context(), permission, Resource{}); err != nil {
+				WriteError(w, err)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// WriteError answers a failed Authorize: 403 for a denial, 500 when the check
+// itself could not be made.
+func WriteError(w http.ResponseWriter, err error) {
+	var denied *DeniedError
+	if errors.As(err, &denied) {
+		helper.WriteSimpleError(w, http.StatusForbidden, "Access denied: missing permission "+denied.Permission)
+		return
+	}
+	slog.Error("authorization check failed", slog.String("error", err.Error()))
+	helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to check permissions: "+err.Error())
+}
+
+// decision is the outcome of evaluating the policy; an empty reason means allowed.
+type decision struct {
+	userID, role, staffRole string
+	reason                  string
+}
+
+func (a *Authorizer) decide(ctx context.Context, permission string, res Resource) (decision, error) {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return decision{reason: "unauthenticated"}, nil
+	}
+	d := decision{userID: claims.UserID, role: claims.Role}
+	owned := !res.OwnerID.IsZero() && res.OwnerID.Hex() == claims.UserID
+
+	// An API key only narrows what its user may do
+	if claims.APIKeyID != "" {
+		if allowed, _ := match(claims.Scopes, permission, owned); !allowed {
+			d.reason = "API key scopes do not grant " + permission
+			return d, nil
+		}
+	}
+
+	allowed, ownOnly := match(a.Policy.Roles[claims.Role], permission, owned)
+	if allowed {
+		return d, nil
+	}
+
+	if !res.RestaurantID.IsZero() {
+		staffRole, err := a.staffRole(ctx, claims, res.RestaurantID)
+		if err != nil {
+			return d, err
+		}
+		d.staffRole = staffRole
+		if staffRole != "" {
+			allowed, staffOwnOnly := match(a.Policy.StaffRoles[staffRole], permission, owned)
+			if allowed {
+				return d, nil
+			}
+			ownOnly = ownOnly || staffOwnOnly
+		}
+	}
+
+	switch {
+	case ownOnly && !res.OwnerID.IsZero():
+		d.reason = "resource belongs to another user"
+	case d.staffRole != "":
+		d.reason = "staff role " + d.staffRole + " does not grant " + permission
+	case !res.RestaurantID.IsZero():
+		d.reason = "role " + claims.Role + " does not grant " + permission + " and user is not staff of the restaurant"
+	default:
+		d.reason = "role " + claims.Role + " does not grant " + permission
+	}
+	return d, nil
+}
+
+// staffRole returns the caller's membership role at the restaurant, or "".
+func (a *Authorizer) staffRole(ctx context.Context, claims *auth.Claims, restaurantID primitive.ObjectID) (string, error) {
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil || a.Members == nil {
+		return "", nil
+	}
+	m, err := a.Members.GetMembership(ctx, userID, restaurantID)
+	if err != nil || m == nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+func hexOrEmpty(id primitive.ObjectID) string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.Hex()
+}
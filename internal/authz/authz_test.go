@@ -0,0 +1,163 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	members := memory.NewMembershipStore()
+	a := New(DefaultPolicy(), members)
+
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	manager, kitchen := primitive.NewObjectID(), primitive.NewObjectID()
+	diner, cafe := primitive.NewObjectID(), primitive.NewObjectID()
+	for user, role := range map[primitive.ObjectID]string{manager: "manager", kitchen: "kitchen"} {
+		if _, err := members.SetMembership(ctx, &types.Membership{UserID: user, RestaurantID: diner, Role: role}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	customer := func(id primitive.ObjectID) *auth.Claims { return &auth.Claims{UserID: id.Hex(), Role: "customer"} }
+	apiKey := func(claims *auth.Claims, scopes ...string) *auth.Claims {
+		claims.APIKeyID = primitive.NewObjectID().Hex()
+		claims.Scopes = scopes
+		return claims
+	}
+	admin := func() *auth.Claims { return &auth.Claims{UserID: primitive.NewObjectID().Hex(), Role: "admin"} }
+
+	for _, tc := range []struct {
+		name       string
+		claims     *auth.Claims
+		permission string
+		res        Resource
+		allowed    bool
+		reason     string
+	}{
+		{name: "unauthenticated", permission: "menu:read", reason: "unauthenticated"},
+		{name: "global grant", claims: customer(alice), permission: "menu:read", allowed: true},
+		{name: "admin wildcard", claims: admin(), permission: "users:delete", allowed: true},
+
+		// :own allows only the caller's own resources
+		{name: "own order", claims: customer(alice), permission: "orders:read", res: Resource{RestaurantID: diner, OwnerID: alice}, allowed: true},
+		{name: "another user's order", claims: customer(bob), permission: "orders:read", res: Resource{RestaurantID: diner, OwnerID: alice},
+			reason: "resource belongs to another user"},
+		{name: "own grant without an owner", claims: customer(alice), permission: "orders:read",
+			reason: "role customer does not grant orders:read"},
+		{name: "own status change", claims: customer(alice), permission: "orders:status:cancelled", res: Resource{RestaurantID: diner, OwnerID: alice}, allowed: true},
+
+		// Staff roles come from memberships and apply only at their restaurant
+		{name: "staff :any", claims: customer(manager), permission: "orders:read", res: Resource{RestaurantID: diner, OwnerID: alice}, allowed: true},
+		{name: "staff at another restaurant", claims: customer(manager), permission: "orders:read", res: Resource{RestaurantID: cafe, OwnerID: alice},
+			reason: "resource belongs to another user"},
+		{name: "staff permission at another restaurant", claims: customer(manager), permission: "menu:write", res: Resource{RestaurantID: cafe},
+			reason: "role customer does not grant menu:write and user is not staff of the restaurant"},
+		{name: "staff permission without a restaurant", claims: customer(manager), permission: "menu:write",
+			reason: "role customer does not grant menu:write"},
+		{name: "staff role lacks permission", claims: customer(kitchen), permission: "menu:write", res: Resource{RestaurantID: diner},
+			reason: "staff role kitchen does not grant menu:write"},
+
+		// "orders:status:*" covers every status, a listed status only itself
+		{name: "prefix wildcard", claims: customer(manager), permission: "orders:status:ready", res: Resource{RestaurantID: diner}, allowed: true},
+		{name: "prefix wildcard needs the prefix", claims: customer(manager), permission: "orders:status", res: Resource{RestaurantID: diner},
+			reason: "staff role manager does not grant orders:status"},
+		{name: "listed status", claims: customer(kitchen), permission: "orders:status:ready", res: Resource{RestaurantID: diner}, allowed: true},
+		{name: "unlisted status", claims: customer(kitchen), permission: "orders:status:completed", res: Resource{RestaurantID: diner},
+			reason: "staff role kitchen does not grant orders:status:completed"},
+
+		// API key scopes narrow what the role grants, never widen it
+		{name: "scope within role", claims: apiKey(admin(), "orders:read"), permission: "orders:read", allowed: true},
+		{name: "scope narrows admin", claims: apiKey(admin(), "orders:read"), permission: "users:delete",
+			reason: "API key scopes do not grant users:delete"},
+		{name: "scope beyond role", claims: apiKey(customer(alice), "orders:*"), permission: "orders:status:ready", res: Resource{RestaurantID: diner},
+			reason: "role customer does not grant orders:status:ready and user is not staff of the restaurant"},
+		{name: "own scope on own resource", claims: apiKey(customer(alice), "orders:read:own"), permission: "orders:read", res: Resource{OwnerID: alice}, allowed: true},
+		{name: "own scope on staff resource", claims: apiKey(customer(manager), "orders:read:own"), permission: "orders:read", res: Resource{RestaurantID: diner, OwnerID: alice},
+			reason: "API key scopes do not grant orders:read"},
+		{name: "no scopes", claims: apiKey(customer(alice)), permission: "menu:read",
+			reason: "API key scopes do not grant menu:read"},
+
+		// Default deny
+		{name: "unknown permission", claims: customer(alice), permission: "reports:export", res: Resource{RestaurantID: diner, OwnerID: alice},
+			reason: "role customer does not grant reports:export and user is not staff of the restaurant"},
+		{name: "unknown staff permission", claims: customer(manager), permission: "reports:export", res: Resource{RestaurantID: diner},
+			reason: "staff role manager does not grant reports:export"},
+		{name: "unknown role", claims: &auth.Claims{UserID: alice.Hex(), Role: "owner"}, permission: "menu:read",
+			reason: "role owner does not grant menu:read"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := ctx
+			if tc.claims != nil {
+				ctx = auth.WithClaims(ctx, tc.claims)
+			}
+			err := a.Authorize(ctx, tc.permission, tc.res)
+			if tc.allowed {
+				if err != nil {
+					t.Fatalf("Authorize(%s) = %v, want allowed", tc.permission, err)
+				}
+				return
+			}
+			var denied *DeniedError
+			if !errors.As(err, &denied) {
+				t.Fatalf("Authorize(%s) = %v, want a denial", tc.permission, err)
+			}
+			if denied.Reason != tc.reason {
+				t.Errorf("reason = %q, want %q", denied.Reason, tc.reason)
+			}
+			if can, err := a.Can(ctx, tc.permission, tc.res); can || err != nil {
+				t.Errorf("Can(%s) = %v, %v after a denial", tc.permission, can, err)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	a := New(DefaultPolicy(), memory.NewMembershipStore())
+	h := a.Require("users:read")(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	for _, tc := range []struct {
+		role   string
+		status int
+	}{
+		{"admin", http.StatusNoContent},
+		{"customer", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		r = r.WithContext(auth.WithClaims(r.Context(), &auth.Claims{UserID: primitive.NewObjectID().Hex(), Role: tc.role}))
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.role, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte("roles:\n  clerk: [menu:read, \"orders:*\"]\nstaff_roles:\n  host: [orders:read:any]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Roles["clerk"]) != 2 || len(p.StaffRoles["host"]) != 1 {
+		t.Errorf("parsed %#v", p)
+	}
+
+	for _, bad := range []string{
+		"roles:\n  clerk: [\"orders:*:read\"]\n",
+		"roles:\n  clerk: [\"\"]\n",
+		"staff_roles:\n  host: [\"**\"]\n",
+		"roles: [admin]\n",
+	} {
+		if _, err := ParsePolicy([]byte(bad)); err == nil {
+			t.Errorf("ParsePolicy(%q) accepted an invalid policy", bad)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package authz
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_policy.yaml
+var defaultPolicy []byte
+
+// Policy maps roles to the permissions they grant. Global roles come from the
+// user's token, staff roles from their restaurant memberships.
+type Policy struct {
+	Roles      map[string][]string `yaml:"roles"`
+	StaffRoles map[string][]string `yaml:"staff_roles"`
+}
+
+// DefaultPolicy returns the policy built into the binary.
+func DefaultPolicy() *Policy {
+	p, err := ParsePolicy(defaultPolicy)
+	if err != nil {
+		panic("authz: invalid default policy: " + err.Error())
+	}
+	return p
+}
+
+// LoadPolicy reads a policy file, or returns the default policy when path is empty.
+func LoadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		return DefaultPolicy(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("authz: read policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy decodes and checks a YAML policy document.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("authz: parse policy: %w", err)
+	}
+	for _, roles := range []map[string][]string{p.Roles, p.StaffRoles} {
+		for role, grants := range roles {
+			for _, g := range grants {
+				if !ValidGrant(g) {
+					return nil, fmt.Errorf("authz: role %s has invalid grant %q", role, g)
+				}
+			}
+		}
+	}
+	return &p, nil
+}
+
+// ValidGrant reports whether g is well-formed: non-empty, with "*" only as
+// its last character.
+func ValidGrant(g string) bool {
+	return g != "" && !strings.Contains(strings.TrimSuffix(g, "*"), "*")
+}
+
+// match reports whether one of grants allows permission. owned tells whether
+// the resource belongs to the caller; ownOnly reports that a grant would have
+// matched had it been theirs.
+func match(grants []string, permission string, owned bool) (allowed, ownOnly bool) {
+	for _, g := range grants {
+		switch {
+		case g == permission, g == permission+":any":
+			return true, false
+		case strings.HasSuffix(g, "*") && strings.HasPrefix(permission, strings.TrimSuffix(g, "*")):
+			return true, false
+		case g == permission+":own":
+			if owned {
+				return true, false
+			}
+			ownOnly = true
+		}
+	}
+	return false, ownOnly
+}
@@ -4,22 +4,134 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Http struct {
 	Addr string `yaml:"address"`
+	// TrustedProxies lists the reverse proxies, as CIDR ranges or IPs, whose
+	// X-Forwarded-For header gives the client address. Without them the
+	// header is ignored and the peer address is the client's.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+}
+
+// JWTKey configures one asymmetric signing key. Keys without private_key_file
+// only verify tokens, which is how a rotated-out key stays accepted.
+type JWTKey struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"` // RS256 or EdDSA
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+	// VerifyUntil stops accepting the key after this time, once every token it signed has expired.
+	VerifyUntil time.Time `yaml:"verify_until"`
+}
+
+// Lockout throttles failed logins and Admin-Secret guesses: each failure
+// doubles the wait before the next attempt, and reaching the limit locks the
+// account or IP for Duration.
+type Lockout struct {
+	MaxAccountFailures int           `yaml:"max_account_failures" env:"LOCKOUT_MAX_ACCOUNT_FAILURES" env-default:"5"`
+	MaxIPFailures      int           `yaml:"max_ip_failures" env:"LOCKOUT_MAX_IP_FAILURES" env-default:"20"`
+	Duration           time.Duration `yaml:"duration" env:"LOCKOUT_DURATION" env-default:"15m"`
+	BackoffBase        time.Duration `yaml:"backoff_base" env:"LOCKOUT_BACKOFF_BASE" env-default:"1s"`
+	BackoffMax         time.Duration `yaml:"backoff_max" env:"LOCKOUT_BACKOFF_MAX" env-default:"30s"`
+}
+
+// Mail configures outgoing email. The "log" driver only logs each message,
+// and writes it to Dir when set; the "smtp" driver relays it through Host.
+type Mail struct {
+	Driver   string `yaml:"driver" env:"MAIL_DRIVER" env-default:"log"`
+	From     string `yaml:"from" env:"MAIL_FROM" env-default:"Restify <no-reply@restify.local>"`
+	Dir      string `yaml:"dir" env:"MAIL_DIR"`
+	Host     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	Port     int    `yaml:"smtp_port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	Password string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	// LinkBaseURL is the frontend that links in emails point to; without it
+	// emails carry the bare token.
+	LinkBaseURL string `yaml:"link_base_url" env:"MAIL_LINK_BASE_URL"`
+}
+
+// MFA configures TOTP two-factor authentication.
+type MFA struct {
+	Issuer string `yaml:"issuer" env:"MFA_ISSUER" env-default:"Restify"`
+	// RequiredRoles must use MFA; their users enroll at their next login.
+	RequiredRoles []string      `yaml:"required_roles" env:"MFA_REQUIRED_ROLES" env-separator:","`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env:"MFA_CHALLENGE_TTL" env-default:"5m"`
+}
+
+// OIDC configures login through an external OpenID Connect provider. Users
+// log in only if one of their groups is mapped; the mappings decide their
+// global role and their staff roles at the restaurants they name.
+type OIDC struct {
+	Enabled      bool     `yaml:"enabled" env:"OIDC_ENABLED"`
+	IssuerURL    string   `yaml:"issuer_url" env:"OIDC_ISSUER_URL"`
+	ClientID     string   `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `yaml:"client_secret" env:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string   `yaml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	Scopes       []string `yaml:"scopes" env:"OIDC_SCOPES" env-separator:"," env-default:"openid,email,profile,groups"`
+	GroupsClaim  string   `yaml:"groups_claim" env:"OIDC_GROUPS_CLAIM" env-default:"groups"`
+	// StateTTL is how long a user has to finish logging in at the provider.
+	StateTTL   time.Duration   `yaml:"state_ttl" env:"OIDC_STATE_TTL" env-default:"10m"`
+	GroupRoles []OIDCGroupRole `yaml:"group_roles"`
+}
+
+// OIDCGroupRole grants Role to members of Group: a global role (admin or
+// customer) without RestaurantID, a staff role at RestaurantID otherwise.
+type OIDCGroupRole struct {
+	Group        string `yaml:"group"`
+	Role         string `yaml:"role"`
+	RestaurantID string `yaml:"restaurant_id"`
 }
 
 type Config struct {
-	Env          string `yaml:"env"`
-	StoragePath  string `yaml:"storage_path"`
-	DatabaseName string `yaml:"database_name"`
-	Http         `yaml:"http_server"`
+	Env string `yaml:"env"`
+	// StorageBackend selects the persistence layer: "mongodb" (default) or "memory".
+	StorageBackend string `yaml:"storage_backend" env:"STORAGE_BACKEND" env-default:"mongodb"`
+	StoragePath    string `yaml:"storage_path"`
+	// MigrateOnStart applies pending MongoDB migrations before serving. Turn it
+	// off to run them separately with the "migrate" subcommand.
+	MigrateOnStart bool   `yaml:"migrate_on_start" env:"MIGRATE_ON_START" env-default:"true"`
+	DatabaseName   string `yaml:"database_name"`
+	Http           `yaml:"http_server"`
 
-	JWTSecret   string `yaml:"jwt_secret" env:"JWT_SECRET"`
+	JWTSecret string `yaml:"jwt_secret" env:"JWT_SECRET"`
+	// AdminSecret lets the very first admin sign up; later admins are invited.
 	AdminSecret string `yaml:"admin_secret" env:"ADMIN_SECRET"`
+	// JWTKeys switches token signing from HS256 with JWTSecret to the key
+	// named by JWTActiveKey; the public keys are served as a JWKS.
+	JWTKeys      []JWTKey `yaml:"jwt_keys"`
+	JWTActiveKey string   `yaml:"jwt_active_key" env:"JWT_ACTIVE_KEY"`
+
+	JWTIssuer       string        `yaml:"jwt_issuer" env:"JWT_ISSUER" env-default:"restify"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" env-default:"720h"`
+	// APIKeyTouchInterval limits how often an API key's last-used time is written.
+	APIKeyTouchInterval time.Duration `yaml:"api_key_touch_interval" env:"API_KEY_TOUCH_INTERVAL" env-default:"1m"`
+
+	Lockout Lockout `yaml:"lockout"`
+	MFA     MFA     `yaml:"mfa"`
+	OIDC    OIDC    `yaml:"oidc"`
+
+	Mail             Mail          `yaml:"mail"`
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" env:"PASSWORD_RESET_TTL" env-default:"1h"`
+	// PasswordResetInterval is the least time between two password reset
+	// emails to one address. It doubles with every further request, and an
+	// address gets at most five within PasswordResetTTL.
+	PasswordResetInterval time.Duration `yaml:"password_reset_interval" env:"PASSWORD_RESET_INTERVAL" env-default:"1m"`
+	EmailVerificationTTL  time.Duration `yaml:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL" env-default:"48h"`
+	InvitationTTL         time.Duration `yaml:"invitation_ttl" env:"INVITATION_TTL" env-default:"168h"`
+	// RequireVerifiedEmail blocks users from ordering until they verify their email.
+	RequireVerifiedEmail bool `yaml:"require_verified_email" env:"REQUIRE_VERIFIED_EMAIL"`
+
+	// DefaultCurrency is the ISO 4217 currency of restaurants created without
+	// one, and of the restaurants that predate currencies when prices are migrated.
+	DefaultCurrency string `yaml:"default_currency" env:"DEFAULT_CURRENCY" env-default:"USD"`
+
+	// PolicyPath points to an authorization policy file; the built-in policy is used when empty.
+	PolicyPath string `yaml:"policy_path" env:"POLICY_PATH"`
 }
 
 func MustLoad() *Config {
@@ -39,8 +151,8 @@ func MustLoad() *Config {
 		log.Fatalf("error in reading config file : %s!!", err.Error())
 	}
 
-	if cfg.JWTSecret == "" || cfg.AdminSecret == "" {
-		log.Fatal("JWTSecret or AdminSecret missing in config/local.yaml or environment variables")
+	if (cfg.JWTSecret == "" && len(cfg.JWTKeys) == 0) || cfg.AdminSecret == "" {
+		log.Fatal("JWTSecret (or jwt_keys) or AdminSecret missing in config/local.yaml or environment variables")
 	}
 
 	return &cfg
@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/lockout"
+	"github.com/shubhamjaiswar43/restify/internal/mailer"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountHandler serves the password reset and email verification flows.
+// Both mail the user a single-use token that expires after ResetTTL or
+// VerifyTTL respectively.
+type AccountHandler struct {
+	Users       storage.UserStore
+	OneTime     storage.OneTimeTokenStore
+	Sessions    storage.TokenStore
+	Mailer      mailer.Mailer
+	LinkBaseURL string
+	ResetTTL    time.Duration
+	VerifyTTL   time.Duration
+	// IPGuard and ResetGuard throttle password reset requests per client IP
+	// and per email, since each one mails the address and replaces its
+	// outstanding reset token.
+	IPGuard    *lockout.Guard
+	ResetGuard *lockout.Guard
+}
+
+func NewAccountHandler(users storage.UserStore, oneTime storage.OneTimeTokenStore, sessions storage.TokenStore, m mailer.Mailer, linkBaseURL string, resetTTL, verifyTTL time.Duration, ipGuard, resetGuard *lockout.Guard) *AccountHandler {
+	return &AccountHandler{
+		Users:       users,
+		OneTime:     oneTime,
+		Sessions:    sessions,
+		Mailer:      m,
+		LinkBaseURL: linkBaseURL,
+		ResetTTL:    resetTTL,
+		VerifyTTL:   verifyTTL,
+		IPGuard:     ipGuard,
+		ResetGuard:  resetGuard,
+	}
+}
+
+// issueToken replaces any outstanding token of the user for purpose with a new one.
+func (h *AccountHandler) issueToken(ctx context.Context, user *types.User, purpose string, ttl time.Duration) (string, error) {
+	if err := h.OneTime.InvalidateOneTimeTokens(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	err = h.OneTime.CreateOneTimeToken(ctx, &types.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: hash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// link points the user at path on the frontend, or just shows the token when
+// no frontend is configured.
+func (h *AccountHandler) link(path, token string) string {
+	if h.LinkBaseURL == "" {
+		return "Your code: " + token
+	}
+	return h.LinkBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail mails the user a token proving they own their email.
+func (h *AccountHandler) sendVerificationEmail(ctx context.Context, user *types.User) error {
+	token, err := h.issueToken(ctx, user, types.TokenPurposeEmailVerification, h.VerifyTTL)
+	if err != nil {
+		return err
+	}
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address:\n\n%s\n\nThis expires in %s.\n",
+			user.Name, h.link("/verify-email", token), h.VerifyTTL),
+	})
+}
+
+// sendPasswordResetEmail mails the user a token allowing them to set a new password.
+func (h *AccountHandler) sendPasswordResetEmail(ctx context.Context, user *types.User) error {
+	token, err := h.issueToken(ctx, user, types.TokenPurposePasswordReset, h.ResetTTL)
+	if err != nil {
+		return err
+	}
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset your password. If it was you, continue here:\n\n%s\n\nThis expires in %s. If you did not ask for it, ignore this email.\n",
+			user.Name, h.link("/reset-password", token), h.ResetTTL),
+	})
+}
+
+// POST /password/forgot - mails a password reset token. Requests are
+// throttled per IP and per email, and the email goes out in the background,
+// so neither the response nor its timing tells whether the email is registered.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ForgotPassword API called", slog.Time("timestamp", time.Now()))
+
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Forgot password validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	email := types.NormalizeEmail(req.Email)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Every request counts, whether or not the email is registered
+	ip := clientIP(r)
+	if !checkThrottle(ctx, w, h.IPGuard, passwordResetIPKey(ip)) || !checkThrottle(ctx, w, h.ResetGuard, passwordResetKey(email)) {
+		return
+	}
+	if _, _, err := h.IPGuard.Fail(ctx, passwordResetIPKey(ip)); err != nil {
+		slog.Error("Failed to record password reset request", slog.String("error", err.Error()))
+	}
+	if _, _, err := h.ResetGuard.Fail(ctx, passwordResetKey(email)); err != nil {
+		slog.Error("Failed to record password reset request", slog.String("error", err.Error()))
+	}
+
+	go h.mailPasswordReset(email)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// mailPasswordReset sends the reset email to the user with email, if there is
+// one. It runs after the response is written, so failures are only logged.
+func (h *AccountHandler) mailPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := h.Users.GetUserByEmail(ctx, email)
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		return
+	}
+	if user == nil {
+		slog.Warn("Password reset requested for unknown email", slog.String("email", email))
+		return
+	}
+	if err := h.sendPasswordResetEmail(ctx, user); err != nil {
+		slog.Error("Failed to send password reset email",
+			slog.String("user_id", user.ID.Hex()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	slog.Info("Password reset email sent",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+}
+
+// POST /password/reset - sets a new password using a mailed token and signs
+// the user out everywhere.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ResetPassword API called", slog.Time("timestamp", time.Now()))
+
+	var req struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required,min=8"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Reset password validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := h.OneTime.ConsumeOneTimeToken(ctx, types.TokenPurposePasswordReset, auth.HashToken(req.Token), time.Now())
+	if err != nil {
+		slog.Error("Failed to redeem reset token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if token == nil {
+		slog.Warn("Invalid or expired password reset token")
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		slog.Error("Failed to hash password", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to hash password: "+err.Error())
+		return
+	}
+	if err := h.Users.SetPassword(ctx, token.UserID, string(hashed)); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		slog.Error("Failed to update password", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update password: "+err.Error())
+		return
+	}
+
+	// Whoever knew the old password must not stay signed in
+	if err := h.Sessions.RevokeUserTokens(ctx, token.UserID, time.Now()); err != nil {
+		slog.Error("Failed to revoke sessions after password reset", slog.String("error", err.Error()))
+	}
+	if err := h.OneTime.InvalidateOneTimeTokens(ctx, token.UserID, types.TokenPurposePasswordReset); err != nil {
+		slog.Error("Failed to invalidate reset tokens", slog.String("error", err.Error()))
+	}
+
+	slog.Info("Password reset successfully",
+		slog.String("user_id", token.UserID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password reset successfully",
+	})
+}
+
+// POST /email/verify - marks the user's email as verified using a mailed token
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	slog.Info("VerifyEmail API called", slog.Time("timestamp", time.Now()))
+
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Verify email validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	token, err := h.OneTime.ConsumeOneTimeToken(ctx, types.TokenPurposeEmailVerification, auth.HashToken(req.Token), now)
+	if err != nil {
+		slog.Error("Failed to redeem verification token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if token == nil {
+		slog.Warn("Invalid or expired email verification token")
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	if err := h.Users.MarkEmailVerified(ctx, token.UserID, token.Email, now); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			slog.Warn("Verification token for an email the user no longer has", slog.String("user_id", token.UserID.Hex()))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		slog.Error("Failed to mark email verified", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to verify email: "+err.Error())
+		return
+	}
+
+	slog.Info("Email verified successfully",
+		slog.String("user_id", token.UserID.Hex()),
+		slog.String("email", token.Email),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Email verified successfully",
+		"email":   token.Email,
+	})
+}
+
+// POST /email/verify/resend - mails the caller a new verification token
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ResendVerification API called", slog.Time("timestamp", time.Now()))
+
+	user, err := auth.CurrentUser(r.Context())
+	if err != nil {
+		slog.Error("Failed to load current user", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Cannot load user: "+err.Error())
+		return
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "User no longer exists")
+		return
+	}
+	if user.EmailVerified {
+		helper.WriteSimpleError(w, http.StatusConflict, "Email is already verified")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.sendVerificationEmail(ctx, user); err != nil {
+		slog.Error("Failed to send verification email", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to send verification email: "+err.Error())
+		return
+	}
+
+	slog.Info("Verification email sent",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Verification email sent",
+	})
+}
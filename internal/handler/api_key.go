@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyHandler struct {
+	Keys      storage.APIKeyStore
+	UserStore storage.UserStore
+}
+
+func NewAPIKeyHandler(keys storage.APIKeyStore, userStore storage.UserStore) *APIKeyHandler {
+	return &APIKeyHandler{
+		Keys:      keys,
+		UserStore: userStore,
+	}
+}
+
+// POST /api-keys - mints a key acting as user_id (the caller by default) with
+// the given permission scopes. The key is only ever shown in this response.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateAPIKey API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      string     `json:"name" validate:"required,min=2,max=100"`
+		UserID    string     `json:"user_id"`
+		Scopes    []string   `json:"scopes" validate:"required,min=1"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("API key validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !authz.ValidGrant(scope) {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid scope "+scope)
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = claims.UserID
+	}
+	if _, err := primitive.ObjectIDFromHex(req.UserID); err != nil {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user_id format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.UserStore.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		slog.Error("Failed to generate API key", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate API key: "+err.Error())
+		return
+	}
+	apiKey := &types.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		UserID:    user.ID,
+		Scopes:    req.Scopes,
+		CreatedBy: actorID(claims),
+		CreatedAt: time.Now(),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.Keys.CreateAPIKey(ctx, apiKey); err != nil {
+		slog.Error("Failed to store API key", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create API key: "+err.Error())
+		return
+	}
+
+	slog.Info("API key created",
+		slog.String("api_key_id", apiKey.ID.Hex()),
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("created_by", claims.UserID),
+		slog.Any("scopes", apiKey.Scopes),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "API key created, store it now as it cannot be shown again",
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// GET /api-keys?user_id=&active=&limit=&cursor=&sort=
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ListAPIKeys API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	listOpts, err := parseListOptions(q)
+	if err != nil {
+		slog.Warn("Invalid API key query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := storage.APIKeyFilter{ListOptions: listOpts}
+	if v := q.Get("user_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user_id format")
+			return
+		}
+		filter.UserID = id
+	}
+	if v := q.Get("active"); v != "" {
+		if v != "true" && v != "false" {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "active must be true or false")
+			return
+		}
+		if v == "true" {
+			filter.ActiveAt = time.Now()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys, next, err := h.Keys.ListAPIKeys(ctx, filter)
+	if err != nil {
+		writeListError(w, "API keys", err)
+		return
+	}
+
+	slog.Info("Fetched API keys successfully",
+		slog.String("requested_by", claims.UserID),
+		slog.Int("count", len(keys)),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(keys),
+		"api_keys":    keys,
+		"next_cursor": next,
+	})
+}
+
+// DELETE /api-keys/{id} - revokes a key; the record is kept for auditing
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RevokeAPIKey API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid API key ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid API key ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.Keys.RevokeAPIKey(ctx, id, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			helper.WriteSimpleError(w, http.StatusNotFound, "API key not found")
+		case errors.Is(err, storage.ErrConflict):
+			helper.WriteSimpleError(w, http.StatusConflict, "API key is already revoked")
+		default:
+			slog.Error("Failed to revoke API key", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to revoke API key: "+err.Error())
+		}
+		return
+	}
+
+	slog.Info("API key revoked",
+		slog.String("api_key_id", idStr),
+		slog.String("revoked_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":    "API key revoked successfully",
+		"api_key_id": idStr,
+	})
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPIKeyAccess(t *testing.T) {
+	o := newOrderTest(t)
+	orderID := o.placeOrder()
+	customerID, _ := field(o.expect(http.StatusOK, http.MethodGet, "/me", o.customer, nil), "id").(string)
+
+	createKey := func(token string, body map[string]any) (key, id string) {
+		t.Helper()
+		out := o.expect(http.StatusCreated, http.MethodPost, "/api-keys", token, body)
+		key, _ = out["key"].(string)
+		id, _ = field(out, "api_key", "id").(string)
+		return key, id
+	}
+	withKey := func(status int, method, path, key string) {
+		t.Helper()
+		o.expect(status, method, path, "", nil, "X-API-Key", key)
+	}
+
+	// An admin's key can do only what its scopes allow
+	reporting, reportingID := createKey(o.admin, map[string]any{"name": "reporting", "scopes": []string{"orders:read"}})
+	withKey(http.StatusOK, http.MethodGet, "/orders", reporting)
+	withKey(http.StatusOK, http.MethodGet, "/orders/"+orderID, reporting)
+	withKey(http.StatusForbidden, http.MethodGet, "/users", reporting)
+	o.expect(http.StatusForbidden, http.MethodPost, "/api-keys", "", map[string]any{"name": "escalate", "scopes": []string{"*"}}, "X-API-Key", reporting)
+
+	// A key acting as a customer never exceeds the customer's role
+	kiosk, _ := createKey(o.admin, map[string]any{"name": "kiosk", "user_id": customerID, "scopes": []string{"orders:*"}})
+	withKey(http.StatusOK, http.MethodGet, "/orders/"+orderID, kiosk)
+	withKey(http.StatusForbidden, http.MethodGet, "/orders", kiosk)
+	o.expect(http.StatusForbidden, http.MethodPatch, "/orders/"+orderID+"/status", "", map[string]any{"status": "preparing"}, "X-API-Key", kiosk)
+
+	// Unknown, malformed and revoked keys are turned away by the middleware
+	withKey(http.StatusUnauthorized, http.MethodGet, "/orders", "not-a-key")
+	withKey(http.StatusUnauthorized, http.MethodGet, "/orders", reporting+"x")
+	o.expect(http.StatusOK, http.MethodDelete, "/api-keys/"+reportingID, o.admin, nil)
+	withKey(http.StatusUnauthorized, http.MethodGet, "/orders", reporting)
+
+	// Minting keys needs api-keys:write and well-formed scopes
+	o.expect(http.StatusForbidden, http.MethodPost, "/api-keys", o.customer, map[string]any{"name": "mine", "scopes": []string{"orders:read"}})
+	o.expect(http.StatusBadRequest, http.MethodPost, "/api-keys", o.admin, map[string]any{"name": "bad", "scopes": []string{"orders:*:read"}})
+	o.expect(http.StatusBadRequest, http.MethodPost, "/api-keys", o.admin, map[string]any{"name": "none", "scopes": []string{}})
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+)
+
+// requestClaims returns the caller's claims set by the auth middleware. A
+// missing value means the route was registered without that middleware, which
+// is answered with 500.
+func requestClaims(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	claims, ok := auth.ClaimsFrom(r.Context())
+	if !ok {
+		slog.Error("Missing claims in context", slog.String("path", r.URL.Path))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,429 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/lockout"
+	"github.com/shubhamjaiswar43/restify/internal/mailer"
+	"github.com/shubhamjaiswar43/restify/internal/server"
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testAdminSecret = "admin-secret"
+
+// captureMailer keeps the messages it is asked to send.
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// apiTest serves the API on the memory backend, wired like cmd/app with the
+// default authorization policy.
+type apiTest struct {
+	t           *testing.T
+	srv         *httptest.Server
+	mail        *captureMailer
+	invitations *InvitationHandler
+}
+
+func newAPITest(t *testing.T) *apiTest {
+	store := memory.New()
+	policy, err := authz.LoadPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizer := authz.New(policy, store.Memberships())
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute, "restify")
+	attempts := lockout.NewMemoryStore(time.Hour)
+	accountGuard := lockout.NewGuard(attempts, lockout.Policy{MaxFailures: 5, LockoutDuration: 15 * time.Minute})
+	ipGuard := lockout.NewGuard(attempts, lockout.Policy{MaxFailures: 50, LockoutDuration: 15 * time.Minute})
+	resetGuard := lockout.NewGuard(attempts, lockout.Policy{MaxFailures: 5, LockoutDuration: time.Hour})
+	mail := &captureMailer{}
+
+	accountHandler := NewAccountHandler(store.Users(), store.OneTimeTokens(), store.Tokens(), mail, "https://restify.test", time.Hour, time.Hour, ipGuard, resetGuard)
+	invitationHandler := NewInvitationHandler(store.Invitations(), store.Users(), store.Memberships(), store.Restaurants(), authorizer, accountHandler, time.Hour)
+	userHandler := NewUserHandler(store.Users(), store.Tokens(), jwtManager, time.Hour, testAdminSecret, accountGuard, ipGuard, accountHandler, MFAPolicy{Issuer: "Restify", ChallengeTTL: 5 * time.Minute}, invitationHandler, store.Memberships(), authorizer)
+	restaurantHandler := NewRestaurantHandler(store.Restaurants(), authorizer, "USD")
+	menuHandler := NewMenuHandler(store.Menu(), store.Restaurants(), authorizer)
+	orderHandler := NewOrderHandler(store.Orders(), store.Menu(), store.Restaurants(), store.Promotions(), authorizer, false)
+	staffHandler := NewStaffHandler(store.Memberships(), store.Users(), store.Restaurants(), authorizer)
+	apiKeyHandler := NewAPIKeyHandler(store.APIKeys(), store.Users())
+
+	authenticated := auth.NewAuthMiddleware(jwtManager, auth.TokenRevocations{Store: store.Tokens()}, auth.APIKeys{
+		Keys:   store.APIKeys(),
+		Users:  store.Users(),
+		Tokens: store.Tokens(),
+	})()
+	require := authorizer.Require
+
+	router := server.NewRouter()
+	router.Use(server.Recoverer, auth.ProvideUsers(store.Users()))
+	router.Post("/signup", userHandler.Signup)
+	router.Post("/login", userHandler.Login)
+	router.Get("/me", userHandler.GetMe, authenticated)
+	router.Get("/me/memberships", staffHandler.GetMyMemberships, authenticated)
+	router.Get("/users", userHandler.GetAllUsers, authenticated, require("users:read"))
+	router.Post("/restaurants", restaurantHandler.CreateRestaurant, authenticated, require("restaurants:create"))
+	router.Post("/menu-items", menuHandler.CreateMenuItem, authenticated)
+	router.Post("/orders", orderHandler.CreateOrder, authenticated)
+	router.Get("/orders", orderHandler.GetAllOrders, authenticated, require("orders:read"))
+	router.Get("/orders/{id}", orderHandler.GetOrderByID, authenticated)
+	router.Patch("/orders/{id}/status", orderHandler.UpdateOrderStatus, authenticated)
+	router.Post("/api-keys", apiKeyHandler.CreateAPIKey, authenticated, require("api-keys:write"))
+	router.Delete("/api-keys/{id}", apiKeyHandler.RevokeAPIKey, authenticated, require("api-keys:write"))
+	router.Post("/invitations", invitationHandler.CreateInvitation, authenticated)
+	router.Post("/invitations/accept", invitationHandler.AcceptInvitation, authenticated)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return &apiTest{t: t, srv: srv, mail: mail, invitations: invitationHandler}
+}
+
+// do sends body as JSON and decodes the JSON response into a map.
+func (a *apiTest) do(method, path, token string, body any, header ...string) (int, map[string]any) {
+	a.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, a.srv.URL+path, &buf)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := a.srv.Client().Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// expect fails the test unless the request is answered with status.
+func (a *apiTest) expect(status int, method, path, token string, body any, header ...string) map[string]any {
+	a.t.Helper()
+	got, out := a.do(method, path, token, body, header...)
+	if got != status {
+		a.t.Fatalf("%s %s: status %d, want %d: %v", method, path, got, status, out)
+	}
+	return out
+}
+
+func (a *apiTest) signup(email string, header ...string) {
+	a.t.Helper()
+	body := map[string]any{"name": "Test", "email": email, "password": "password1"}
+	if len(header) > 0 {
+		body["role"] = "admin"
+	}
+	a.expect(http.StatusCreated, http.MethodPost, "/signup", "", body, header...)
+}
+
+func (a *apiTest) login(email string) string {
+	a.t.Helper()
+	out := a.expect(http.StatusOK, http.MethodPost, "/login", "", map[string]any{"email": email, "password": "password1"})
+	token, _ := out["token"].(string)
+	if token == "" {
+		a.t.Fatalf("login returned no token: %v", out)
+	}
+	return token
+}
+
+// mailedToken returns the token of the last email sent to address.
+func (a *apiTest) mailedToken(address string) string {
+	a.t.Helper()
+	a.mail.mu.Lock()
+	defer a.mail.mu.Unlock()
+	for i := len(a.mail.sent) - 1; i >= 0; i-- {
+		if msg := a.mail.sent[i]; msg.To == address {
+			_, rest, ok := strings.Cut(msg.Body, "?token=")
+			if !ok {
+				break
+			}
+			token, _, _ := strings.Cut(rest, "\n")
+			token, err := url.QueryUnescape(token)
+			if err != nil {
+				a.t.Fatal(err)
+			}
+			return token
+		}
+	}
+	a.t.Fatalf("no email with a token was sent to %s", address)
+	return ""
+}
+
+// field walks nested JSON objects along keys.
+func field(v any, keys ...string) any {
+	for _, k := range keys {
+		m, _ := v.(map[string]any)
+		v = m[k]
+	}
+	return v
+}
+
+// orderTest has an admin, a customer and a restaurant with one menu item.
+type orderTest struct {
+	*apiTest
+	admin, customer string
+	restaurantID    string
+	menuItemID      string
+}
+
+func newOrderTest(t *testing.T) *orderTest {
+	a := newAPITest(t)
+	a.signup("admin@example.com", "Admin-Secret", testAdminSecret)
+	a.signup("customer@example.com")
+	o := &orderTest{apiTest: a, admin: a.login("admin@example.com"), customer: a.login("customer@example.com")}
+
+	out := a.expect(http.StatusCreated, http.MethodPost, "/restaurants", o.admin, map[string]any{"name": "Luigi's", "address": "1 Main St", "phone": "+14155552671"})
+	o.restaurantID, _ = field(out, "restaurant", "id").(string)
+	out = a.expect(http.StatusCreated, http.MethodPost, "/menu-items", o.admin, map[string]any{
+		"restaurant_id": o.restaurantID, "name": "Pizza", "category": "main", "price": map[string]any{"amount": 999, "currency": "USD"},
+	})
+	o.menuItemID, _ = field(out, "menu_item", "id").(string)
+	return o
+}
+
+func (o *orderTest) placeOrder() string {
+	o.t.Helper()
+	out := o.expect(http.StatusCreated, http.MethodPost, "/orders", o.customer, map[string]any{
+		"restaurant_id": o.restaurantID,
+		"items":         []map[string]any{{"menu_item_id": o.menuItemID, "quantity": 2}},
+	})
+	id, _ := field(out, "order", "id").(string)
+	return id
+}
+
+func (o *orderTest) setStatus(status int, orderID, token, to string) map[string]any {
+	o.t.Helper()
+	return o.expect(status, http.MethodPatch, "/orders/"+orderID+"/status", token, map[string]any{"status": to})
+}
+
+func TestSignupAndLogin(t *testing.T) {
+	a := newAPITest(t)
+	a.signup("Alice@Example.com")
+
+	a.expect(http.StatusConflict, http.MethodPost, "/signup", "", map[string]any{"name": "Alice", "email": "alice@example.COM", "password": "password1"})
+	a.expect(http.StatusBadRequest, http.MethodPost, "/signup", "", map[string]any{"name": "Bob", "email": "bob@example.com", "password": "short"})
+	a.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]any{"email": "alice@example.com", "password": "wrong-password"})
+
+	token := a.login("ALICE@example.com")
+	me := a.expect(http.StatusOK, http.MethodGet, "/me", token, nil)
+	if email := field(me, "email"); email != "alice@example.com" {
+		t.Errorf("GET /me email = %v, want the normalized address", email)
+	}
+	if role := field(me, "role"); role != "customer" {
+		t.Errorf("GET /me role = %v, want customer", role)
+	}
+	if _, ok := me["password"]; ok {
+		t.Error("GET /me returned the password hash")
+	}
+
+	a.expect(http.StatusUnauthorized, http.MethodGet, "/me", "", nil)
+	a.expect(http.StatusUnauthorized, http.MethodGet, "/me", "not-a-token", nil)
+	a.expect(http.StatusForbidden, http.MethodGet, "/users", token, nil)
+
+	a.mail.mu.Lock()
+	defer a.mail.mu.Unlock()
+	if len(a.mail.sent) != 1 || a.mail.sent[0].To != "alice@example.com" {
+		t.Errorf("sent %+v, want one verification email to alice@example.com", a.mail.sent)
+	}
+}
+
+func TestAdminBootstrap(t *testing.T) {
+	a := newAPITest(t)
+	admin := map[string]any{"name": "Admin", "email": "admin@example.com", "password": "password1", "role": "admin"}
+
+	a.expect(http.StatusUnauthorized, http.MethodPost, "/signup", "", admin)
+	a.expect(http.StatusUnauthorized, http.MethodPost, "/signup", "", admin, "Admin-Secret", "wrong")
+	a.signup("admin@example.com", "Admin-Secret", testAdminSecret)
+
+	token := a.login("admin@example.com")
+	a.expect(http.StatusOK, http.MethodGet, "/users", token, nil)
+
+	// The secret only creates the first admin
+	admin["email"] = "second@example.com"
+	a.expect(http.StatusForbidden, http.MethodPost, "/signup", "", admin, "Admin-Secret", testAdminSecret)
+}
+
+func TestConcurrentAdminBootstrap(t *testing.T) {
+	a := newAPITest(t)
+	const signups = 8
+
+	// Each signup passes the no-admin-yet check; the store lets one through
+	statuses := make(chan int, signups)
+	var wg sync.WaitGroup
+	for i := range signups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, _ := json.Marshal(map[string]any{"name": "Admin", "email": fmt.Sprintf("admin%d@example.com", i), "password": "password1", "role": "admin"})
+			req, _ := http.NewRequest(http.MethodPost, a.srv.URL+"/signup", bytes.NewReader(body))
+			req.Header.Set("Admin-Secret", testAdminSecret)
+			resp, err := a.srv.Client().Do(req)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	won := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			won++
+		case http.StatusForbidden:
+		default:
+			t.Errorf("signup answered %d", status)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("%d admins bootstrapped, want 1", won)
+	}
+
+	// Whoever won is the only admin
+	for i := range signups {
+		status, out := a.do(http.MethodPost, "/login", "", map[string]any{"email": fmt.Sprintf("admin%d@example.com", i), "password": "password1"})
+		if status != http.StatusOK {
+			continue
+		}
+		token, _ := out["token"].(string)
+		out = a.expect(http.StatusOK, http.MethodGet, "/users?role=admin", token, nil)
+		if users, _ := out["users"].([]any); len(users) != 1 {
+			t.Errorf("admins = %v, want 1", users)
+		}
+	}
+}
+
+func TestOrderFlow(t *testing.T) {
+	o := newOrderTest(t)
+
+	orderID := o.placeOrder()
+	order := o.expect(http.StatusOK, http.MethodGet, "/orders/"+orderID, o.customer, nil)
+	if status := field(order, "order", "status"); status != "pending" {
+		t.Errorf("new order status = %v, want pending", status)
+	}
+	if total := field(order, "order", "total_price", "amount"); total != 1998.0 {
+		t.Errorf("order total = %v, want 1998", total)
+	}
+
+	// Customers may only cancel their own orders
+	o.setStatus(http.StatusForbidden, orderID, o.customer, "preparing")
+	o.setStatus(http.StatusConflict, orderID, o.admin, "completed")
+	o.setStatus(http.StatusOK, orderID, o.admin, "preparing")
+	o.setStatus(http.StatusOK, orderID, o.admin, "ready")
+	o.setStatus(http.StatusConflict, orderID, o.admin, "pending")
+	out := o.setStatus(http.StatusOK, orderID, o.admin, "completed")
+	if history, _ := field(out, "order", "status_history").([]any); len(history) != 4 {
+		t.Errorf("status history %v, want 4 entries", history)
+	}
+
+	cancelled := o.placeOrder()
+	o.setStatus(http.StatusOK, cancelled, o.customer, "cancelled")
+	o.setStatus(http.StatusConflict, cancelled, o.admin, "preparing")
+
+	// Other customers cannot tell someone else's order from a missing one
+	o.signup("other@example.com")
+	other := o.login("other@example.com")
+	missing := primitive.NewObjectID().Hex()
+	o.expect(http.StatusNotFound, http.MethodGet, "/orders/"+missing, other, nil)
+	o.expect(http.StatusNotFound, http.MethodGet, "/orders/"+orderID, other, nil)
+	o.setStatus(http.StatusNotFound, missing, other, "cancelled")
+	o.setStatus(http.StatusNotFound, o.placeOrder(), other, "cancelled")
+}
+
+func TestOrderAmountLimits(t *testing.T) {
+	o := newOrderTest(t)
+	order := func(menuItemID string, quantity int) map[string]any {
+		return map[string]any{
+			"restaurant_id": o.restaurantID,
+			"items":         []map[string]any{{"menu_item_id": menuItemID, "quantity": quantity}},
+		}
+	}
+
+	o.expect(http.StatusBadRequest, http.MethodPost, "/orders", o.customer, order(o.menuItemID, 1001))
+	o.expect(http.StatusCreated, http.MethodPost, "/orders", o.customer, order(o.menuItemID, 1000))
+
+	// A line total past int64 must not wrap around to a small or negative price
+	out := o.expect(http.StatusCreated, http.MethodPost, "/menu-items", o.admin, map[string]any{
+		"restaurant_id": o.restaurantID, "name": "Caviar", "category": "main", "price": map[string]any{"amount": int64(1) << 60, "currency": "USD"},
+	})
+	caviar, _ := field(out, "menu_item", "id").(string)
+	o.expect(http.StatusBadRequest, http.MethodPost, "/orders", o.customer, order(caviar, 16))
+	o.expect(http.StatusBadRequest, http.MethodPost, "/orders", o.customer, order(caviar, 1))
+}
+
+func TestListPaging(t *testing.T) {
+	o := newOrderTest(t)
+	placed := map[string]bool{}
+	for i := 0; i < 7; i++ {
+		placed[o.placeOrder()] = true
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	for pages := 1; ; pages++ {
+		q := url.Values{"limit": {"3"}, "sort": {"-created_at"}}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		out := o.expect(http.StatusOK, http.MethodGet, "/orders?"+q.Encode(), o.admin, nil)
+		orders, _ := out["orders"].([]any)
+		if len(orders) > 3 {
+			t.Fatalf("page %d has %d orders, want at most 3", pages, len(orders))
+		}
+		for _, order := range orders {
+			id, _ := field(order, "id").(string)
+			if seen[id] || !placed[id] {
+				t.Fatalf("page %d returned order %s again or unexpectedly", pages, id)
+			}
+			seen[id] = true
+		}
+		cursor, _ = out["next_cursor"].(string)
+		if cursor == "" {
+			if pages != 3 {
+				t.Errorf("got %d pages, want 3", pages)
+			}
+			break
+		}
+	}
+	if len(seen) != len(placed) {
+		t.Errorf("paging returned %d orders, want %d", len(seen), len(placed))
+	}
+
+	o.expect(http.StatusBadRequest, http.MethodGet, "/orders?sort=password", o.admin, nil)
+	o.expect(http.StatusBadRequest, http.MethodGet, "/orders?cursor=garbage", o.admin, nil)
+}
@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/mailer"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errInvitationUnusable is returned by redeem when the invitation was
+// accepted, revoked or expired in the meantime.
+var errInvitationUnusable = errors.New("invitation is no longer valid")
+
+type InvitationHandler struct {
+	Invitations     storage.InvitationStore
+	UserStore       storage.UserStore
+	Members         storage.MembershipStore
+	RestaurantStore storage.RestaurantStore
+	Authz           *authz.Authorizer
+	// Accounts mails the invitations.
+	Accounts *AccountHandler
+	TTL      time.Duration
+}
+
+func NewInvitationHandler(invitations storage.InvitationStore, userStore storage.UserStore, members storage.MembershipStore, restaurantStore storage.RestaurantStore, az *authz.Authorizer, accounts *AccountHandler, ttl time.Duration) *InvitationHandler {
+	return &InvitationHandler{
+		Invitations:     invitations,
+		UserStore:       userStore,
+		Members:         members,
+		RestaurantStore: restaurantStore,
+		Authz:           az,
+		Accounts:        accounts,
+		TTL:             ttl,
+	}
+}
+
+// authorizeInvitation checks that the caller may hand out role at
+// restaurantID: admins need users:invite-admin, staff roles need staff:write
+// at the restaurant, and managers staff:appoint-manager as well.
+func (h *InvitationHandler) authorizeInvitation(w http.ResponseWriter, r *http.Request, role string, restaurantID *primitive.ObjectID) bool {
+	var err error
+	switch {
+	case role == "admin":
+		err = h.Authz.Authorize(r.Context(), "users:invite-admin", authz.Resource{})
+	case restaurantID == nil:
+		helper.WriteSimpleError(w, http.StatusBadRequest, "restaurant_id is required for staff roles")
+		return false
+	default:
+		res := authz.Resource{RestaurantID: *restaurantID}
+		err = h.Authz.Authorize(r.Context(), "staff:write", res)
+		if err == nil && role == types.StaffRoleManager {
+			err = h.Authz.Authorize(r.Context(), "staff:appoint-manager", res)
+		}
+	}
+	if err != nil {
+		authz.WriteError(w, err)
+		return false
+	}
+	return true
+}
+
+// lookupInvitation finds the pending invitation of token meant for email.
+func (h *InvitationHandler) lookupInvitation(ctx context.Context, token, email string) (*types.Invitation, error) {
+	inv, err := h.Invitations.GetInvitationByToken(ctx, auth.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || !inv.Pending(time.Now()) || !strings.EqualFold(inv.Email, email) {
+		return nil, nil
+	}
+	return inv, nil
+}
+
+// redeem accepts inv on behalf of user and grants its role. The token was
+// mailed to the user's address, so their email counts as verified too.
+func (h *InvitationHandler) redeem(ctx context.Context, inv *types.Invitation, user *types.User) error {
+	now := time.Now()
+	if err := h.Invitations.AcceptInvitation(ctx, inv.ID, user.ID, now); err != nil {
+		if errors.Is(err, storage.ErrConflict) || errors.Is(err, storage.ErrNotFound) {
+			return errInvitationUnusable
+		}
+		return err
+	}
+
+	var err error
+	if inv.Role == "admin" {
+		err = h.UserStore.SetRole(ctx, user.ID, "admin")
+	} else {
+		_, err = h.Members.SetMembership(ctx, &types.Membership{
+			UserID:       user.ID,
+			RestaurantID: *inv.RestaurantID,
+			Role:         inv.Role,
+			CreatedBy:    inv.InvitedBy,
+		})
+	}
+	if err != nil {
+		if reopenErr := h.Invitations.ReopenInvitation(ctx, inv.ID); reopenErr != nil {
+			slog.Error("Failed to reopen invitation", slog.String("error", reopenErr.Error()))
+		}
+		return err
+	}
+
+	if !user.EmailVerified {
+		if err := h.UserStore.MarkEmailVerified(ctx, user.ID, user.Email, now); err != nil {
+			slog.Error("Failed to mark email verified", slog.String("error", err.Error()))
+		}
+	}
+
+	slog.Info("Invitation accepted",
+		slog.String("invitation_id", inv.ID.Hex()),
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("role", inv.Role),
+		slog.Time("timestamp", time.Now()),
+	)
+	return nil
+}
+
+// POST /invitations - invites an email to a role, mailing them a signup token
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreateInvitation API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email        string              `json:"email" validate:"required,email"`
+		Role         string              `json:"role" validate:"required,oneof=admin manager cashier kitchen waiter"`
+		RestaurantID *primitive.ObjectID `json:"restaurant_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Invitation validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+	if req.Role == "admin" {
+		req.RestaurantID = nil
+	}
+
+	if !h.authorizeInvitation(w, r, req.Role, req.RestaurantID) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	restaurantName := ""
+	if req.RestaurantID != nil {
+		restaurant, err := h.RestaurantStore.GetByID(ctx, req.RestaurantID.Hex())
+		if err != nil {
+			slog.Error("Failed to fetch restaurant", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch restaurant: "+err.Error())
+			return
+		}
+		if restaurant == nil {
+			helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+			return
+		}
+		restaurantName = restaurant.Name
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		slog.Error("Failed to generate invitation token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+		return
+	}
+	now := time.Now()
+	inv := &types.Invitation{
+		Email:        types.NormalizeEmail(req.Email),
+		Role:         req.Role,
+		RestaurantID: req.RestaurantID,
+		TokenHash:    hash,
+		InvitedBy:    actorID(claims),
+		ExpiresAt:    now.Add(h.TTL),
+		CreatedAt:    now,
+	}
+	if err := h.Invitations.CreateInvitation(ctx, inv); err != nil {
+		slog.Error("Failed to create invitation", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create invitation: "+err.Error())
+		return
+	}
+
+	where := ""
+	if restaurantName != "" {
+		where = " at " + restaurantName
+	}
+	err = h.Accounts.Mailer.Send(ctx, mailer.Message{
+		To:      inv.Email,
+		Subject: "You have been invited to Restify",
+		Body: fmt.Sprintf("Hello,\n\nYou have been invited to join Restify as %s%s. Sign up, or accept while logged in, with:\n\n%s\n\nThis invitation expires in %s.\n",
+			inv.Role, where, h.Accounts.link("/accept-invite", token), h.TTL),
+	})
+	if err != nil {
+		slog.Error("Failed to send invitation email",
+			slog.String("invitation_id", inv.ID.Hex()),
+			slog.String("error", err.Error()),
+		)
+		helper.WriteSimpleError(w, http.StatusBadGateway, "Invitation created but the email could not be sent: "+err.Error())
+		return
+	}
+
+	slog.Info("Invitation created successfully",
+		slog.String("invitation_id", inv.ID.Hex()),
+		slog.String("email", inv.Email),
+		slog.String("role", inv.Role),
+		slog.String("invited_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Invitation sent successfully",
+		"invitation": inv,
+	})
+}
+
+// GET /invitations?restaurant_id=&email=&pending=&limit=&cursor=&sort= -
+// staff:read at the restaurant, or users:read for every invitation
+func (h *InvitationHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ListInvitations API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	listOpts, err := parseListOptions(q)
+	if err != nil {
+		slog.Warn("Invalid invitation query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := storage.InvitationFilter{ListOptions: listOpts, Email: types.NormalizeEmail(q.Get("email"))}
+	if v := q.Get("pending"); v != "" {
+		if v != "true" && v != "false" {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "pending must be true or false")
+			return
+		}
+		if v == "true" {
+			filter.PendingAt = time.Now()
+		}
+	}
+
+	permission, res := "users:read", authz.Resource{}
+	if v := q.Get("restaurant_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid restaurant_id format")
+			return
+		}
+		filter.RestaurantID = id
+		permission, res = "staff:read", authz.Resource{RestaurantID: id}
+	}
+	if err := h.Authz.Authorize(r.Context(), permission, res); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	invitations, next, err := h.Invitations.ListInvitations(ctx, filter)
+	if err != nil {
+		writeListError(w, "invitations", err)
+		return
+	}
+
+	slog.Info("Fetched invitations successfully",
+		slog.String("requested_by", claims.UserID),
+		slog.Int("count", len(invitations)),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(invitations),
+		"invitations": invitations,
+		"next_cursor": next,
+	})
+}
+
+// POST /invitations/{id}/revoke - revokes a pending invitation
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RevokeInvitation API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid invitation ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid invitation ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	inv, err := h.Invitations.GetInvitation(ctx, id)
+	if err != nil {
+		slog.Error("Failed to fetch invitation", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if inv == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "Invitation not found")
+		return
+	}
+	if !h.authorizeInvitation(w, r, inv.Role, inv.RestaurantID) {
+		return
+	}
+
+	if err := h.Invitations.RevokeInvitation(ctx, id, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			helper.WriteSimpleError(w, http.StatusNotFound, "Invitation not found")
+		case errors.Is(err, storage.ErrConflict):
+			helper.WriteSimpleError(w, http.StatusConflict, "Invitation was already accepted or revoked")
+		default:
+			slog.Error("Failed to revoke invitation", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to revoke invitation: "+err.Error())
+		}
+		return
+	}
+
+	slog.Info("Invitation revoked",
+		slog.String("invitation_id", idStr),
+		slog.String("revoked_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":       "Invitation revoked successfully",
+		"invitation_id": idStr,
+	})
+}
+
+// POST /invitations/accept - grants an invitation to the logged-in user it was sent to
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	slog.Info("AcceptInvitation API called", slog.Time("timestamp", time.Now()))
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Accept invitation validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	inv, err := h.lookupInvitation(ctx, req.Token, user.Email)
+	if err != nil {
+		slog.Error("Failed to fetch invitation", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if inv == nil {
+		slog.Warn("Invalid invitation token", slog.String("user_id", user.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired invitation")
+		return
+	}
+	if user.Role == "admin" {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Admins already have access to every restaurant")
+		return
+	}
+
+	if err := h.redeem(ctx, inv, user); err != nil {
+		if errors.Is(err, errInvitationUnusable) {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired invitation")
+			return
+		}
+		slog.Error("Failed to accept invitation", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to accept invitation: "+err.Error())
+		return
+	}
+
+	message := "Invitation accepted"
+	if inv.Role == "admin" {
+		message += ", log in again to use the admin role"
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":       message,
+		"role":          inv.Role,
+		"restaurant_id": inv.RestaurantID,
+	})
+}
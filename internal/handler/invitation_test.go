@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInvitation(t *testing.T) {
+	o := newOrderTest(t)
+	invite := func(email, role, restaurantID string) string {
+		t.Helper()
+		body := map[string]any{"email": email, "role": role}
+		if restaurantID != "" {
+			body["restaurant_id"] = restaurantID
+		}
+		o.expect(http.StatusCreated, http.MethodPost, "/invitations", o.admin, body)
+		return o.mailedToken(email)
+	}
+	signup := func(status int, email, token string) {
+		t.Helper()
+		o.expect(status, http.MethodPost, "/signup", "", map[string]any{"name": "Invitee", "email": email, "password": "password1", "invite_token": token})
+	}
+
+	token := invite("cashier@example.com", "cashier", o.restaurantID)
+
+	// Only the invited address can use the token
+	signup(http.StatusBadRequest, "someone@example.com", token)
+	signup(http.StatusCreated, "Cashier@Example.com", token)
+	cashier := o.login("cashier@example.com")
+
+	// The invitation grants its staff role at its restaurant and nothing else
+	me := o.expect(http.StatusOK, http.MethodGet, "/me", cashier, nil)
+	if role := field(me, "role"); role != "customer" {
+		t.Errorf("global role = %v, want customer", role)
+	}
+	if verified := field(me, "email_verified"); verified != true {
+		t.Errorf("email_verified = %v, want true after accepting a mailed invitation", verified)
+	}
+	out := o.expect(http.StatusOK, http.MethodGet, "/me/memberships", cashier, nil)
+	memberships, _ := out["memberships"].([]any)
+	if len(memberships) != 1 || field(memberships[0], "restaurant_id") != o.restaurantID || field(memberships[0], "role") != "cashier" {
+		t.Fatalf("memberships = %v, want cashier at %s", memberships, o.restaurantID)
+	}
+	o.expect(http.StatusOK, http.MethodGet, "/orders/"+o.placeOrder(), cashier, nil)
+	o.expect(http.StatusForbidden, http.MethodPost, "/menu-items", cashier, map[string]any{
+		"restaurant_id": o.restaurantID, "name": "Soup", "category": "main", "price": map[string]any{"amount": 500, "currency": "USD"},
+	})
+	o.expect(http.StatusForbidden, http.MethodGet, "/users", cashier, nil)
+
+	// It is single-use
+	o.expect(http.StatusBadRequest, http.MethodPost, "/invitations/accept", cashier, map[string]any{"token": token})
+
+	// An admin invitation makes an admin, without a restaurant
+	token = invite("admin2@example.com", "admin", "")
+	signup(http.StatusCreated, "admin2@example.com", token)
+	admin2 := o.login("admin2@example.com")
+	if role := field(o.expect(http.StatusOK, http.MethodGet, "/me", admin2, nil), "role"); role != "admin" {
+		t.Errorf("invited admin has role %v", role)
+	}
+	if out := o.expect(http.StatusOK, http.MethodGet, "/me/memberships", admin2, nil); out["count"] != 0.0 {
+		t.Errorf("invited admin has memberships %v", out["memberships"])
+	}
+
+	// Expired invitations are refused, at signup and when accepted later
+	o.invitations.TTL = -time.Minute
+	token = invite("late@example.com", "kitchen", o.restaurantID)
+	signup(http.StatusBadRequest, "late@example.com", token)
+	o.signup("late@example.com")
+	late := o.login("late@example.com")
+	o.expect(http.StatusBadRequest, http.MethodPost, "/invitations/accept", late, map[string]any{"token": token})
+	if out := o.expect(http.StatusOK, http.MethodGet, "/me/memberships", late, nil); out["count"] != 0.0 {
+		t.Errorf("expired invitation granted %v", out["memberships"])
+	}
+}
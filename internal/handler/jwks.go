@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GET /.well-known/jwks.json - public keys other services verify our access
+// tokens with. Empty while tokens are signed with the shared HS256 secret.
+func (h *UserHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Short enough for verifiers to pick up a rotated key quickly
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.JWT.Keys.JWKS())
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+)
+
+func TestJWKS(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet("2026", &auth.Key{ID: "2026", Algorithm: auth.AlgEdDSA, Private: priv, Public: pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtManager := auth.NewKeyedJWTManager(keys, 15*time.Minute, "restify")
+	h := &UserHandler{JWT: jwtManager}
+
+	rec := httptest.NewRecorder()
+	h.JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("Cache-Control") != "public, max-age=300" {
+		t.Fatalf("status %d, headers %v", rec.Code, rec.Header())
+	}
+	var jwks auth.JWKSet
+	if err := json.NewDecoder(rec.Body).Decode(&jwks); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another service verifies our tokens with nothing but the published keys
+	token, err := jwtManager.Generate("user-1", "customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		for _, k := range jwks.Keys {
+			if k.KeyID == token.Header["kid"] && k.Algorithm == token.Method.Alg() {
+				return k.PublicKey()
+			}
+		}
+		return nil, jwt.ErrTokenUnverifiable
+	})
+	if err != nil {
+		t.Errorf("token does not verify with the JWKS: %v", err)
+	}
+
+	// With the shared HS256 secret there is nothing to publish
+	rec = httptest.NewRecorder()
+	(&UserHandler{JWT: auth.NewJWTManager("secret", time.Minute, "restify")}).JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if body := rec.Body.String(); body != "{\"keys\":[]}\n" {
+		t.Errorf("JWKS without keys = %s", body)
+	}
+}
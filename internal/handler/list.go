@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parseListOptions reads the limit, cursor and sort query parameters shared by all list endpoints.
+func parseListOptions(q url.Values) (storage.ListOptions, error) {
+	opts := storage.ListOptions{
+		Cursor: q.Get("cursor"),
+		Sort:   q.Get("sort"),
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("limit must be a positive integer")
+		}
+		opts.Limit = limit
+	}
+	return opts, nil
+}
+
+// parseObjectIDParam reads an optional ObjectID query parameter.
+func parseObjectIDParam(q url.Values, name string) (primitive.ObjectID, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return primitive.NilObjectID, nil
+	}
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid %s format", name)
+	}
+	return id, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp or YYYY-MM-DD date.
+// With endOfDay set, a plain date is moved to the start of the following day
+// so it can be used as an exclusive upper bound.
+func parseTimeParam(q url.Values, name string, endOfDay bool) (time.Time, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseBoolParam reads an optional boolean query parameter.
+func parseBoolParam(q url.Values, name string) (*bool, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+	return &v, nil
+}
+
+// writeListError answers a failed list query, telling bad sort and cursor
+// parameters apart from storage failures.
+func writeListError(w http.ResponseWriter, what string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidSort):
+		slog.Warn("Unsupported sort requested", slog.String("resource", what))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Unsupported sort field")
+	case errors.Is(err, storage.ErrInvalidCursor):
+		slog.Warn("Invalid cursor", slog.String("resource", what))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired cursor")
+	default:
+		slog.Error("Failed to fetch "+what, slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch "+what+": "+err.Error())
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/lockout"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Lockout keys; accounts are keyed by email so unknown emails are throttled too.
+func accountKey(email string) string       { return "login:" + types.NormalizeEmail(email) }
+func loginIPKey(ip string) string          { return "login-ip:" + ip }
+func adminSecretKey(ip string) string      { return "admin-secret:" + ip }
+func passwordResetKey(email string) string { return "password-reset:" + types.NormalizeEmail(email) }
+func passwordResetIPKey(ip string) string  { return "password-reset-ip:" + ip }
+
+// clientIP returns the address the request came from, which server.RealIP
+// takes from X-Forwarded-For behind a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkThrottle answers 429 with a Retry-After header when guard blocks key.
+func checkThrottle(ctx context.Context, w http.ResponseWriter, guard *lockout.Guard, key string) bool {
+	wait, err := guard.Check(ctx, key)
+	if err != nil {
+		slog.Error("Failed to check attempt throttle", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to check login attempts: "+err.Error())
+		return false
+	}
+	if wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		slog.Warn("Throttled attempt", slog.String("key", key), slog.Int("retry_after", seconds))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		helper.WriteSimpleError(w, http.StatusTooManyRequests, "Too many failed attempts, retry in "+strconv.Itoa(seconds)+"s")
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed login against the account and the client
+// IP. When the account gets locked the event is stored on the user document.
+func (h *UserHandler) recordLoginFailure(ctx context.Context, email, ip string, user *types.User) {
+	if _, _, err := h.IPGuard.Fail(ctx, loginIPKey(ip)); err != nil {
+		slog.Error("Failed to record login failure", slog.String("error", err.Error()))
+	}
+	rec, locked, err := h.AccountGuard.Fail(ctx, accountKey(email))
+	if err != nil {
+		slog.Error("Failed to record login failure", slog.String("error", err.Error()))
+		return
+	}
+	if !locked {
+		return
+	}
+	slog.Warn("Account locked after repeated failed logins",
+		slog.String("email", email),
+		slog.String("ip", ip),
+		slog.Time("locked_until", rec.LockedUntil),
+	)
+	if user == nil {
+		return
+	}
+	err = h.Store.AddLockoutEvent(ctx, user.ID, types.LockoutEvent{
+		Type:        types.LockoutEventLocked,
+		IP:          ip,
+		LockedUntil: &rec.LockedUntil,
+		At:          time.Now(),
+	})
+	if err != nil {
+		slog.Error("Failed to record lockout event", slog.String("error", err.Error()))
+	}
+}
+
+// POST /users/{id}/unlock - ends a login lockout before it expires
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UnlockUser API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	userID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid user ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.Store.GetUserByID(ctx, idStr)
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := h.AccountGuard.Reset(ctx, accountKey(user.Email)); err != nil {
+		slog.Error("Failed to reset lockout", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to unlock user: "+err.Error())
+		return
+	}
+	actor := actorID(claims)
+	err = h.Store.AddLockoutEvent(ctx, userID, types.LockoutEvent{
+		Type:    types.LockoutEventUnlocked,
+		ActorID: &actor,
+		At:      time.Now(),
+	})
+	if err != nil {
+		slog.Error("Failed to record lockout event", slog.String("error", err.Error()))
+	}
+
+	slog.Info("User unlocked",
+		slog.String("user_id", idStr),
+		slog.String("unlocked_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "User unlocked successfully",
+		"user_id": idStr,
+	})
+}
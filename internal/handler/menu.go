@@ -3,34 +3,36 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
 	"github.com/shubhamjaiswar43/restify/internal/helper"
-	"github.com/shubhamjaiswar43/restify/internal/storage/mongodb"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MenuHandler struct {
-	MenuStore       *mongodb.MenuStore
-	RestaurantStore *mongodb.RestaurantStore
+	MenuStore       storage.MenuStore
+	RestaurantStore storage.RestaurantStore
+	Authz           *authz.Authorizer
 }
 
-func NewMenuHandler(menuStore *mongodb.MenuStore, restaurantStore *mongodb.RestaurantStore) *MenuHandler {
-	return &MenuHandler{MenuStore: menuStore, RestaurantStore: restaurantStore}
+func NewMenuHandler(menuStore storage.MenuStore, restaurantStore storage.RestaurantStore, az *authz.Authorizer) *MenuHandler {
+	return &MenuHandler{MenuStore: menuStore, RestaurantStore: restaurantStore, Authz: az}
 }
 
 // POST /menu-items
 func (h *MenuHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
 	slog.Info("CreateMenuItem API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
@@ -40,12 +42,6 @@ func (h *MenuHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
 		slog.Time("timestamp", time.Now()),
 	)
 
-	if claims.Role != "admin" {
-		slog.Warn("Unauthorized menu creation attempt", slog.String("user_id", claims.UserID))
-		helper.WriteSimpleError(w, http.StatusForbidden, "Only admin can create menu items")
-		return
-	}
-
 	var item types.MenuItem
 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
 		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
@@ -62,6 +58,11 @@ func (h *MenuHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if err := h.Authz.Authorize(r.Context(), "menu:write", authz.Resource{RestaurantID: item.Restaurant}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
 	// Check if the restaurant exists
 	restaurant, err := h.RestaurantStore.GetByID(ctx, item.Restaurant.Hex())
 	if err != nil {
@@ -74,28 +75,35 @@ func (h *MenuHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
 		helper.WriteSimpleError(w, http.StatusBadRequest, "Restaurant does not exist")
 		return
 	}
+	if !restaurant.IsActive {
+		slog.Warn("Menu creation failed: restaurant is deactivated", slog.String("restaurant_id", item.Restaurant.Hex()))
+		helper.WriteSimpleError(w, http.StatusConflict, "Restaurant is deactivated")
+		return
+	}
 
-	// Check if item already exists in this restaurant
-	exists, err := h.MenuStore.GetByNameAndRestaurant(ctx, item.Name, item.Restaurant)
-	if err != nil {
-		slog.Error("Failed to check menu item existence", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Error checking existing menu item: "+err.Error())
+	if item.Price, ok = priceIn(w, item.Price, restaurant.Currency); !ok {
 		return
 	}
-	if exists != nil {
-		slog.Warn("Duplicate menu item creation attempt",
-			slog.String("menu_name", item.Name),
-			slog.String("restaurant_id", item.Restaurant.Hex()),
-		)
-		helper.WriteSimpleError(w, http.StatusConflict, "Menu item with this name already exists in this restaurant")
+	if !prepareOptionGroups(w, &item, restaurant.Currency) {
 		return
 	}
 
+	// New items can be ordered right away
+	item.Available = true
 	item.CreatedAt = time.Now()
 	item.UpdatedAt = time.Now()
 
+	// Name uniqueness per restaurant is enforced by the store
 	created, err := h.MenuStore.CreateMenuItem(ctx, &item)
 	if err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			slog.Warn("Duplicate menu item creation attempt",
+				slog.String("menu_name", item.Name),
+				slog.String("restaurant_id", item.Restaurant.Hex()),
+			)
+			helper.WriteSimpleError(w, http.StatusConflict, "Menu item with this name already exists in this restaurant")
+			return
+		}
 		slog.Error("Failed to create menu item in DB", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create menu item: "+err.Error())
 		return
@@ -119,14 +127,12 @@ func (h *MenuHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GET /menu-items?restaurant_id=<id>
+// GET /menu-items?restaurant_id=<id>&category=&available=&limit=&cursor=&sort=
 func (h *MenuHandler) GetMenuItems(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetMenuItems API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
@@ -143,20 +149,30 @@ func (h *MenuHandler) GetMenuItems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := primitive.ObjectIDFromHex(restaurantIDStr)
+	restaurantID, err := primitive.ObjectIDFromHex(restaurantIDStr)
 	if err != nil {
 		slog.Warn("Invalid restaurant_id format", slog.String("restaurant_id", restaurantIDStr))
 		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid restaurant_id format")
 		return
 	}
 
+	q := r.URL.Query()
+	filter := storage.MenuItemFilter{RestaurantID: restaurantID, Category: q.Get("category")}
+	if filter.ListOptions, err = parseListOptions(q); err == nil {
+		filter.Available, err = parseBoolParam(q, "available")
+	}
+	if err != nil {
+		slog.Warn("Invalid menu item query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	items, err := h.MenuStore.GetByRestaurant(ctx, restaurantIDStr)
+	items, next, err := h.MenuStore.ListMenuItems(ctx, filter)
 	if err != nil {
-		slog.Error("Failed to fetch menu items", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch menu items: "+err.Error())
+		writeListError(w, "menu items", err)
 		return
 	}
 
@@ -170,7 +186,378 @@ func (h *MenuHandler) GetMenuItems(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]any{
 		"count":         len(items),
 		"menu_items":    items,
+		"next_cursor":   next,
 		"restaurant_id": restaurantIDStr,
 		"requested_by":  claims.UserID,
 	})
 }
+
+// GET /menu-items/{id}
+func (h *MenuHandler) GetMenuItemByID(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetMenuItemByID API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, ok := h.loadMenuItem(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"menu_item":    item,
+		"requested_by": claims.UserID,
+	})
+}
+
+// PUT /menu-items/{id} - replaces name, category, price, availability and
+// option groups. An item cannot be moved to another restaurant.
+func (h *MenuHandler) UpdateMenuItem(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateMenuItem API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.MenuItem
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStructExcept(req, "Restaurant"); err != nil {
+		slog.Warn("Menu item validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, ok := h.loadMenuItem(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.Authz.Authorize(r.Context(), "menu:write", authz.Resource{RestaurantID: item.Restaurant}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	if !req.Restaurant.IsZero() && req.Restaurant != item.Restaurant {
+		slog.Warn("Attempt to move menu item to another restaurant", slog.String("menu_id", item.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Menu item cannot be moved to another restaurant")
+		return
+	}
+	item.Name = req.Name
+	item.Category = req.Category
+	item.Price = req.Price
+	item.Available = req.Available
+	item.OptionGroups = req.OptionGroups
+
+	h.saveMenuItem(ctx, w, item, claims)
+}
+
+// PATCH /menu-items/{id} - updates only the fields present in the body
+func (h *MenuHandler) PatchMenuItem(w http.ResponseWriter, r *http.Request) {
+	slog.Info("PatchMenuItem API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name         *string              `json:"name"`
+		Category     *string              `json:"category"`
+		Price        *types.Money         `json:"price"`
+		Available    *bool                `json:"available"`
+		OptionGroups *[]types.OptionGroup `json:"option_groups"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, ok := h.loadMenuItem(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.Authz.Authorize(r.Context(), "menu:write", authz.Resource{RestaurantID: item.Restaurant}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.Category != nil {
+		item.Category = *req.Category
+	}
+	if req.Price != nil {
+		item.Price = *req.Price
+	}
+	if req.Available != nil {
+		item.Available = *req.Available
+	}
+	if req.OptionGroups != nil {
+		item.OptionGroups = *req.OptionGroups
+	}
+	if err := helper.ValidateStruct(item); err != nil {
+		slog.Warn("Menu item validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	h.saveMenuItem(ctx, w, item, claims)
+}
+
+// PATCH /menu-items/{id}/availability - quickly 86 an item during service or bring it back
+func (h *MenuHandler) SetAvailability(w http.ResponseWriter, r *http.Request) {
+	slog.Info("SetAvailability API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Available *bool `json:"available" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Availability validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, ok := h.loadMenuItem(ctx, w, r)
+	if !ok {
+		return
+	}
+	// A separate permission, so the kitchen can 86 items itself when it runs out
+	if err := h.Authz.Authorize(r.Context(), "menu:availability", authz.Resource{RestaurantID: item.Restaurant}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	item, err := h.MenuStore.SetAvailability(ctx, item.ID, *req.Available)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			slog.Warn("Menu item not found", slog.String("menu_id", r.PathValue("id")))
+			helper.WriteSimpleError(w, http.StatusNotFound, "Menu item not found")
+			return
+		}
+		slog.Error("Failed to update availability", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update availability: "+err.Error())
+		return
+	}
+
+	slog.Info("Menu item availability changed",
+		slog.String("menu_id", item.ID.Hex()),
+		slog.Bool("available", item.Available),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":   "Menu item availability updated successfully",
+		"menu_item": item,
+	})
+}
+
+// DELETE /menu-items/{id}
+func (h *MenuHandler) DeleteMenuItem(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteMenuItem API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	item, ok := h.loadMenuItem(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.Authz.Authorize(r.Context(), "menu:write", authz.Resource{RestaurantID: item.Restaurant}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	idStr := item.ID.Hex()
+	if err := h.MenuStore.DeleteMenuItem(ctx, item.ID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			slog.Warn("Menu item not found", slog.String("menu_id", idStr))
+			helper.WriteSimpleError(w, http.StatusNotFound, "Menu item not found")
+			return
+		}
+		slog.Error("Failed to delete menu item", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to delete menu item: "+err.Error())
+		return
+	}
+
+	slog.Info("Menu item deleted successfully",
+		slog.String("menu_id", idStr),
+		slog.String("deleted_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Menu item deleted successfully",
+		"menu_id": idStr,
+	})
+}
+
+// loadMenuItem fetches the menu item named by the request path and writes the
+// error response itself when it cannot.
+func (h *MenuHandler) loadMenuItem(ctx context.Context, w http.ResponseWriter, r *http.Request) (*types.MenuItem, bool) {
+	idStr := r.PathValue("id")
+	itemID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid menu item ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid menu item ID format")
+		return nil, false
+	}
+
+	item, err := h.MenuStore.GetMenuItemByID(ctx, itemID)
+	if err != nil {
+		slog.Error("Failed to fetch menu item", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch menu item: "+err.Error())
+		return nil, false
+	}
+	if item == nil {
+		slog.Warn("Menu item not found", slog.String("menu_id", idStr))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Menu item not found")
+		return nil, false
+	}
+	return item, true
+}
+
+// priceIn puts price in the restaurant's currency and writes the error
+// response itself when it is in another one.
+func priceIn(w http.ResponseWriter, price types.Money, currency string) (types.Money, bool) {
+	converted, err := price.In(currency)
+	if err != nil {
+		slog.Warn("Menu item price in wrong currency", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Price must be in the restaurant's currency "+currency)
+		return types.Money{}, false
+	}
+	return converted, true
+}
+
+// prepareOptionGroups checks the selection rules of the item's option groups,
+// gives new groups and options their IDs and puts the price deltas in the
+// restaurant's currency. It writes the error response itself when it cannot.
+func prepareOptionGroups(w http.ResponseWriter, item *types.MenuItem, currency string) bool {
+	ids := make(map[primitive.ObjectID]bool)
+	// IDs sent back from an earlier read are kept, so orders keep matching
+	assignID := func(id *primitive.ObjectID) bool {
+		if id.IsZero() {
+			*id = primitive.NewObjectID()
+		} else if ids[*id] {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Option group and option IDs must be unique")
+			return false
+		}
+		ids[*id] = true
+		return true
+	}
+
+	for gi := range item.OptionGroups {
+		group := &item.OptionGroups[gi]
+		if group.MinRequired() > len(group.Options) {
+			helper.WriteSimpleError(w, http.StatusBadRequest, fmt.Sprintf("Option group %q requires more selections than it has options", group.Name))
+			return false
+		}
+		if group.MaxSelections > 0 && group.MaxSelections < group.MinRequired() {
+			helper.WriteSimpleError(w, http.StatusBadRequest, fmt.Sprintf("Option group %q allows fewer selections than it requires", group.Name))
+			return false
+		}
+		if !assignID(&group.ID) {
+			return false
+		}
+		for oi := range group.Options {
+			option := &group.Options[oi]
+			if !assignID(&option.ID) {
+				return false
+			}
+			delta, err := option.PriceDelta.In(currency)
+			if err != nil {
+				slog.Warn("Option price delta in wrong currency", slog.String("error", err.Error()))
+				helper.WriteSimpleError(w, http.StatusBadRequest, "Option prices must be in the restaurant's currency "+currency)
+				return false
+			}
+			option.PriceDelta = delta
+		}
+	}
+	return true
+}
+
+// saveMenuItem persists an edited menu item and writes the response. Name
+// uniqueness per restaurant is enforced by the store, as on creation.
+func (h *MenuHandler) saveMenuItem(ctx context.Context, w http.ResponseWriter, item *types.MenuItem, claims *auth.Claims) {
+	restaurant, err := h.RestaurantStore.GetByID(ctx, item.Restaurant.Hex())
+	if err != nil {
+		slog.Error("Failed to fetch restaurant", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Error checking restaurant: "+err.Error())
+		return
+	}
+	if restaurant == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant of the menu item not found")
+		return
+	}
+	var ok bool
+	if item.Price, ok = priceIn(w, item.Price, restaurant.Currency); !ok {
+		return
+	}
+	if !prepareOptionGroups(w, item, restaurant.Currency) {
+		return
+	}
+
+	updated, err := h.MenuStore.UpdateMenuItem(ctx, item)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "Menu item not found")
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicate) {
+			slog.Warn("Menu item rename conflicts with existing item",
+				slog.String("menu_name", item.Name),
+				slog.String("restaurant_id", item.Restaurant.Hex()),
+			)
+			helper.WriteSimpleError(w, http.StatusConflict, "Menu item with this name already exists in this restaurant")
+			return
+		}
+		slog.Error("Failed to update menu item in DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update menu item: "+err.Error())
+		return
+	}
+
+	slog.Info("Menu item updated successfully",
+		slog.String("menu_id", updated.ID.Hex()),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Menu item updated successfully",
+		"menu_item":  updated,
+		"updated_by": claims.UserID,
+	})
+}
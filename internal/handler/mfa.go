@@ -0,0 +1,487 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/mfa"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFAPolicy configures two-factor login.
+type MFAPolicy struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// RequiredRoles cannot log in without MFA; users holding them enroll
+	// during their next login.
+	RequiredRoles []string
+	// ChallengeTTL bounds the time between the password and the second step.
+	ChallengeTTL time.Duration
+}
+
+func (p MFAPolicy) required(role string) bool {
+	return slices.Contains(p.RequiredRoles, role)
+}
+
+// secondFactorRequest carries either a TOTP code or a recovery code.
+type secondFactorRequest struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+// writeMFAChallenge answers a correct password with a challenge token instead
+// of an access token: the user must enter a code, or enroll first.
+func (h *UserHandler) writeMFAChallenge(w http.ResponseWriter, user *types.User) {
+	purpose, message := auth.PurposeMFA, "Enter the code from your authenticator app"
+	if !user.MFA.Enabled {
+		purpose, message = auth.PurposeMFAEnrollment, "Two-factor authentication is mandatory for your role, enroll to continue"
+	}
+	challenge, err := h.JWT.GenerateChallenge(user.ID.Hex(), user.Role, purpose, h.MFA.ChallengeTTL)
+	if err != nil {
+		slog.Error("Failed to generate MFA challenge", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+		return
+	}
+
+	slog.Info("Password accepted, second factor pending",
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("challenge", purpose),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"mfa_required":    true,
+		"challenge_type":  purpose,
+		"challenge_token": challenge,
+		"expires_in":      int(h.MFA.ChallengeTTL.Seconds()),
+		"message":         message,
+	})
+}
+
+// verifySecondFactor checks a TOTP code or consumes a recovery code. Each
+// code is accepted once; a replayed code counts as invalid.
+func (h *UserHandler) verifySecondFactor(ctx context.Context, user *types.User, req secondFactorRequest) (bool, error) {
+	if req.RecoveryCode != "" {
+		err := h.Store.UseRecoveryCode(ctx, user.ID, mfa.HashRecoveryCode(req.RecoveryCode))
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	step, ok := mfa.Validate(user.MFA.Secret, req.Code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	if err := h.Store.UseMFAStep(ctx, user.ID, step); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return false, nil
+		}
+		return false, err
+	}
+	user.MFA.LastUsedStep = step
+	return true, nil
+}
+
+// checkSecondFactor verifies req for the caller, writing the error response
+// and counting the failure against the account when it is wrong.
+func (h *UserHandler) checkSecondFactor(ctx context.Context, w http.ResponseWriter, r *http.Request, user *types.User, req secondFactorRequest) bool {
+	ip := clientIP(r)
+	if !checkThrottle(ctx, w, h.AccountGuard, accountKey(user.Email)) {
+		return false
+	}
+	ok, err := h.verifySecondFactor(ctx, user, req)
+	if err != nil {
+		slog.Error("Failed to verify second factor", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to verify code: "+err.Error())
+		return false
+	}
+	if !ok {
+		slog.Warn("Invalid second factor", slog.String("user_id", user.ID.Hex()), slog.String("ip", ip))
+		h.recordLoginFailure(ctx, user.Email, ip, user)
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid authentication code")
+		return false
+	}
+	if err := h.AccountGuard.Reset(ctx, accountKey(user.Email)); err != nil {
+		slog.Error("Failed to reset login failures", slog.String("error", err.Error()))
+	}
+	return true
+}
+
+// decodeSecondFactor reads and validates a secondFactorRequest body.
+func decodeSecondFactor(w http.ResponseWriter, r *http.Request) (secondFactorRequest, bool) {
+	var req secondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return req, false
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Second factor validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return req, false
+	}
+	return req, true
+}
+
+// currentUser loads the caller, answering 401 when they no longer exist.
+func currentUser(w http.ResponseWriter, r *http.Request) (*types.User, bool) {
+	user, err := auth.CurrentUser(r.Context())
+	if err != nil {
+		slog.Error("Failed to load current user", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Cannot load user: "+err.Error())
+		return nil, false
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "User no longer exists")
+		return nil, false
+	}
+	return user, true
+}
+
+// POST /login/mfa - completes a login with the challenge token from /login
+// and a TOTP or recovery code
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	slog.Info("LoginMFA API called", slog.Time("timestamp", time.Now()))
+
+	var req struct {
+		ChallengeToken string `json:"challenge_token" validate:"required"`
+		secondFactorRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("MFA login validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	claims, err := h.JWT.VerifyChallenge(req.ChallengeToken, auth.PurposeMFA)
+	if err != nil {
+		slog.Warn("Invalid MFA challenge token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.Store.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if user == nil || !user.MFA.Enabled {
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+	if !checkThrottle(ctx, w, h.IPGuard, loginIPKey(clientIP(r))) {
+		return
+	}
+	if !h.checkSecondFactor(ctx, w, r, user, req.secondFactorRequest) {
+		return
+	}
+
+	tokens, err := h.issueTokens(ctx, user, primitive.NewObjectID())
+	if err != nil {
+		slog.Error("Failed to generate tokens", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+		return
+	}
+
+	slog.Info("User logged in with MFA",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Bool("recovery_code", req.RecoveryCode != ""),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// POST /mfa/enroll - starts TOTP enrollment and returns the secret and its
+// otpauth:// URI for a QR code. Accepts an enrollment challenge token.
+func (h *UserHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	slog.Info("EnrollMFA API called", slog.Time("timestamp", time.Now()))
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.MFA.Enabled {
+		helper.WriteSimpleError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		slog.Error("Failed to generate MFA secret", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate secret: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settings := user.MFA
+	settings.PendingSecret = secret
+	if err := h.Store.SetMFA(ctx, user.ID, settings); err != nil {
+		slog.Error("Failed to store MFA secret", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to start enrollment: "+err.Error())
+		return
+	}
+
+	slog.Info("MFA enrollment started",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":     "Add the secret to your authenticator app, then confirm with a code",
+		"secret":      secret,
+		"otpauth_uri": mfa.ProvisioningURI(h.MFA.Issuer, user.Email, secret),
+	})
+}
+
+// POST /mfa/enable - confirms enrollment with a first code and returns the
+// recovery codes. Completing a mandatory enrollment also logs the user in.
+func (h *UserHandler) EnableMFA(w http.ResponseWriter, r *http.Request) {
+	slog.Info("EnableMFA API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Enable MFA validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	if user.MFA.Enabled {
+		helper.WriteSimpleError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if user.MFA.PendingSecret == "" {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Start enrollment with POST /mfa/enroll first")
+		return
+	}
+	step, valid := mfa.Validate(user.MFA.PendingSecret, req.Code, time.Now())
+	if !valid {
+		slog.Warn("Invalid code during MFA enrollment", slog.String("user_id", user.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid authentication code")
+		return
+	}
+
+	codes, hashes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		slog.Error("Failed to generate recovery codes", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate recovery codes: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	err = h.Store.SetMFA(ctx, user.ID, types.MFA{
+		Enabled:       true,
+		Secret:        user.MFA.PendingSecret,
+		RecoveryCodes: hashes,
+		LastUsedStep:  step,
+		EnabledAt:     &now,
+	})
+	if err != nil {
+		slog.Error("Failed to enable MFA", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to enable MFA: "+err.Error())
+		return
+	}
+
+	slog.Info("MFA enabled",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	resp := map[string]any{
+		"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe, they are shown only once",
+		"recovery_codes": codes,
+	}
+	if claims.Purpose == auth.PurposeMFAEnrollment {
+		tokens, err := h.issueTokens(ctx, user, primitive.NewObjectID())
+		if err != nil {
+			slog.Error("Failed to generate tokens", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+			return
+		}
+		maps.Copy(resp, tokens)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// POST /mfa/disable - turns MFA off after checking a current code
+func (h *UserHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DisableMFA API called", slog.Time("timestamp", time.Now()))
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	req, ok := decodeSecondFactor(w, r)
+	if !ok {
+		return
+	}
+	if !user.MFA.Enabled {
+		helper.WriteSimpleError(w, http.StatusConflict, "Two-factor authentication is not enabled")
+		return
+	}
+	if h.MFA.required(user.Role) {
+		helper.WriteSimpleError(w, http.StatusForbidden, "Two-factor authentication is mandatory for your role")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !h.checkSecondFactor(ctx, w, r, user, req) {
+		return
+	}
+	if err := h.Store.SetMFA(ctx, user.ID, types.MFA{}); err != nil {
+		slog.Error("Failed to disable MFA", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to disable MFA: "+err.Error())
+		return
+	}
+
+	slog.Info("MFA disabled",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// POST /mfa/recovery-codes - replaces the recovery codes after checking a current TOTP code
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RegenerateRecoveryCodes API called", slog.Time("timestamp", time.Now()))
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Recovery code validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+	if !user.MFA.Enabled {
+		helper.WriteSimpleError(w, http.StatusConflict, "Two-factor authentication is not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !h.checkSecondFactor(ctx, w, r, user, secondFactorRequest{Code: req.Code}) {
+		return
+	}
+	codes, hashes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		slog.Error("Failed to generate recovery codes", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate recovery codes: "+err.Error())
+		return
+	}
+	settings := user.MFA
+	settings.RecoveryCodes = hashes
+	if err := h.Store.SetMFA(ctx, user.ID, settings); err != nil {
+		slog.Error("Failed to store recovery codes", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to store recovery codes: "+err.Error())
+		return
+	}
+
+	slog.Info("Recovery codes regenerated",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":        "New recovery codes generated, the old ones no longer work",
+		"recovery_codes": codes,
+	})
+}
+
+// DELETE /users/{id}/mfa - clears a user's MFA, e.g. after they lost their
+// device and recovery codes. Users of a mandatory role re-enroll on next login.
+func (h *UserHandler) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ResetUserMFA API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	userID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid user ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.Store.SetMFA(ctx, userID, types.MFA{}); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		slog.Error("Failed to reset MFA", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to reset MFA: "+err.Error())
+		return
+	}
+
+	slog.Info("MFA reset by admin",
+		slog.String("user_id", idStr),
+		slog.String("reset_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication reset",
+		"user_id": idStr,
+	})
+}
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/oidc"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errSSOEmailMissing    = errors.New("the identity provider did not share an email address")
+	errSSOEmailUnverified = errors.New("an account with this email exists but the identity provider has not verified the email")
+)
+
+// GroupRole grants Role to members of an identity provider group: a global
+// role when RestaurantID is zero, a staff role at RestaurantID otherwise.
+type GroupRole struct {
+	Group        string
+	Role         string
+	RestaurantID primitive.ObjectID
+}
+
+type OIDCHandler struct {
+	Provider   *oidc.Provider
+	States     oidc.StateStore
+	StateTTL   time.Duration
+	GroupRoles []GroupRole
+	UserStore  storage.UserStore
+	Members    storage.MembershipStore
+	// Sessions issues the tokens, or the MFA challenge its policy asks for.
+	Sessions *UserHandler
+}
+
+func NewOIDCHandler(provider *oidc.Provider, states oidc.StateStore, stateTTL time.Duration, groupRoles []GroupRole, userStore storage.UserStore, members storage.MembershipStore, sessions *UserHandler) *OIDCHandler {
+	return &OIDCHandler{
+		Provider:   provider,
+		States:     states,
+		StateTTL:   stateTTL,
+		GroupRoles: groupRoles,
+		UserStore:  userStore,
+		Members:    members,
+		Sessions:   sessions,
+	}
+}
+
+// mapGroups works out the roles groups grant: the global role, admin winning
+// over customer, and per restaurant the staff role of the first matching
+// mapping. matched is false when no mapping applies at all.
+func (h *OIDCHandler) mapGroups(groups []string) (role string, staff map[primitive.ObjectID]string, matched bool) {
+	role, staff = "customer", make(map[primitive.ObjectID]string)
+	for _, gr := range h.GroupRoles {
+		if !slices.Contains(groups, gr.Group) {
+			continue
+		}
+		matched = true
+		if gr.RestaurantID.IsZero() {
+			if gr.Role == "admin" {
+				role = "admin"
+			}
+			continue
+		}
+		if _, ok := staff[gr.RestaurantID]; !ok {
+			staff[gr.RestaurantID] = gr.Role
+		}
+	}
+	return role, staff, matched
+}
+
+// provision finds the user of tok, linking an existing account with the same
+// verified email or creating one on first login, and gives it role.
+// roleChanged reports whether an existing user's role had to change.
+func (h *OIDCHandler) provision(ctx context.Context, tok *oidc.IDToken, role string) (user *types.User, roleChanged bool, err error) {
+	user, err = h.UserStore.GetUserByIdentity(ctx, tok.Issuer, tok.Subject)
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	identity := types.Identity{Issuer: tok.Issuer, Subject: tok.Subject, LinkedAt: now}
+
+	if user == nil {
+		email := types.NormalizeEmail(tok.Email)
+		if email == "" {
+			return nil, false, errSSOEmailMissing
+		}
+		existing, err := h.UserStore.GetUserByEmail(ctx, email)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			// Whoever controls an unverified address must not take over the account
+			if !tok.EmailVerified {
+				return nil, false, errSSOEmailUnverified
+			}
+			if err := h.UserStore.LinkIdentity(ctx, existing.ID, identity); err != nil {
+				return nil, false, err
+			}
+			existing.Identities = append(existing.Identities, identity)
+			user = existing
+			slog.Info("Linked identity to existing user",
+				slog.String("user_id", user.ID.Hex()),
+				slog.String("issuer", tok.Issuer),
+				slog.String("subject", tok.Subject),
+			)
+		} else {
+			user = &types.User{
+				ID:            primitive.NewObjectID(),
+				Name:          tok.Name,
+				Email:         email,
+				Role:          role,
+				EmailVerified: tok.EmailVerified,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+				Identities:    []types.Identity{identity},
+			}
+			if user.Name == "" {
+				user.Name = email
+			}
+			if user.EmailVerified {
+				user.EmailVerifiedAt = &now
+			}
+			// No password is set, so the account can only log in through the provider
+			if err := h.UserStore.CreateUser(ctx, user); err != nil {
+				return nil, false, err
+			}
+			slog.Info("Provisioned user from identity provider",
+				slog.String("user_id", user.ID.Hex()),
+				slog.String("email", user.Email),
+				slog.String("role", role),
+			)
+		}
+	}
+
+	// The provider's groups are the source of truth for linked accounts
+	if user.Role != role {
+		if err := h.UserStore.SetRole(ctx, user.ID, role); err != nil {
+			return nil, false, err
+		}
+		slog.Info("Role synced from identity provider",
+			slog.String("user_id", user.ID.Hex()),
+			slog.String("from", user.Role),
+			slog.String("to", role),
+		)
+		user.Role = role
+		roleChanged = true
+	}
+	return user, roleChanged, nil
+}
+
+// syncStaffRoles makes the user's memberships at every restaurant named by a
+// mapping match staff, removing those the groups no longer grant. Memberships
+// at other restaurants are left alone. taken reports whether a membership was
+// removed or had its role changed.
+func (h *OIDCHandler) syncStaffRoles(ctx context.Context, user *types.User, staff map[primitive.ObjectID]string) (taken bool, err error) {
+	seen := make(map[primitive.ObjectID]bool)
+	for _, gr := range h.GroupRoles {
+		if gr.RestaurantID.IsZero() || seen[gr.RestaurantID] {
+			continue
+		}
+		seen[gr.RestaurantID] = true
+
+		current, err := h.Members.GetMembership(ctx, user.ID, gr.RestaurantID)
+		if err != nil {
+			return taken, err
+		}
+		want := staff[gr.RestaurantID]
+		switch {
+		case want != "" && (current == nil || current.Role != want):
+			_, err = h.Members.SetMembership(ctx, &types.Membership{
+				UserID:       user.ID,
+				RestaurantID: gr.RestaurantID,
+				Role:         want,
+				CreatedBy:    user.ID,
+			})
+			taken = taken || current != nil
+		case want == "" && current != nil:
+			err = h.Members.DeleteMembership(ctx, user.ID, gr.RestaurantID)
+			taken = true
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return taken, err
+		}
+	}
+	return taken, nil
+}
+
+// GET /oidc/login - redirects to the identity provider to log in
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	slog.Info("OIDC Login API called", slog.Time("timestamp", time.Now()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	state, challenge, s, err := oidc.NewLogin(h.StateTTL)
+	if err != nil {
+		slog.Error("Failed to start OIDC login", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to start login: "+err.Error())
+		return
+	}
+	if err := h.States.Save(ctx, state, s); err != nil {
+		slog.Error("Failed to save OIDC state", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to start login: "+err.Error())
+		return
+	}
+	authURL, err := h.Provider.AuthCodeURL(ctx, state, s.Nonce, challenge)
+	if err != nil {
+		slog.Error("Identity provider unavailable", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET /oidc/callback?code=&state= - finishes the login the provider redirected
+// back from and answers like POST /login
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	slog.Info("OIDC Callback API called", slog.Time("timestamp", time.Now()))
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		slog.Warn("Identity provider refused the login", slog.String("error", e), slog.String("description", q.Get("error_description")))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Login failed at the identity provider: "+e)
+		return
+	}
+	code, state := q.Get("code"), q.Get("state")
+	if code == "" || state == "" {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "code and state are required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	s, err := h.States.Take(ctx, state)
+	if err != nil {
+		slog.Error("Failed to load OIDC state", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to finish login: "+err.Error())
+		return
+	}
+	if s == nil {
+		slog.Warn("Unknown or expired OIDC state")
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired login, start again")
+		return
+	}
+
+	rawIDToken, err := h.Provider.Exchange(ctx, code, s.Verifier)
+	if err != nil {
+		slog.Error("Failed to exchange authorization code", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadGateway, "Failed to redeem the authorization code")
+		return
+	}
+	tok, err := h.Provider.Verify(ctx, rawIDToken, s.Nonce)
+	if err != nil {
+		slog.Error("Rejected ID token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid ID token")
+		return
+	}
+
+	role, staff, matched := h.mapGroups(tok.Groups)
+	if !matched {
+		slog.Warn("Identity provider user has no mapped group",
+			slog.String("subject", tok.Subject),
+			slog.Any("groups", tok.Groups),
+		)
+		helper.WriteSimpleError(w, http.StatusForbidden, "Your account is not allowed to use Restify")
+		return
+	}
+
+	user, roleChanged, err := h.provision(ctx, tok, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, errSSOEmailMissing):
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Cannot log in: "+err.Error())
+		case errors.Is(err, errSSOEmailUnverified), errors.Is(err, storage.ErrDuplicate):
+			slog.Warn("Cannot link identity", slog.String("subject", tok.Subject), slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusConflict, "Cannot log in: "+err.Error())
+		default:
+			slog.Error("Failed to provision user", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to provision user: "+err.Error())
+		}
+		return
+	}
+	staffChanged, err := h.syncStaffRoles(ctx, user, staff)
+	if err != nil {
+		slog.Error("Failed to sync staff roles", slog.String("user_id", user.ID.Hex()), slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to sync staff roles: "+err.Error())
+		return
+	}
+
+	// As on an admin's role change, sessions opened under the old roles
+	// must not outlive them
+	if roleChanged || staffChanged {
+		cutoff := time.Now()
+		if err := h.Sessions.Tokens.RevokeUserTokens(ctx, user.ID, cutoff); err != nil {
+			slog.Error("Failed to revoke sessions after role sync", slog.String("error", err.Error()))
+		}
+		// A token from the cutoff's millisecond counts as revoked, so the
+		// tokens issued below must come from a later one
+		time.Sleep(time.Until(cutoff.Truncate(time.Millisecond).Add(time.Millisecond)))
+	}
+
+	if user.MFA.Enabled || h.Sessions.MFA.required(user.Role) {
+		h.Sessions.writeMFAChallenge(w, user)
+		return
+	}
+
+	tokens, err := h.Sessions.issueTokens(ctx, user, primitive.NewObjectID())
+	if err != nil {
+		slog.Error("Failed to generate tokens", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+		return
+	}
+
+	slog.Info("User logged in through identity provider",
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("email", user.Email),
+		slog.String("role", user.Role),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(tokens)
+}
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/lockout"
+	"github.com/shubhamjaiswar43/restify/internal/oidc"
+	"github.com/shubhamjaiswar43/restify/internal/oidc/oidctest"
+	"github.com/shubhamjaiswar43/restify/internal/storage/memory"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oidcTest wires an OIDCHandler on the memory backend to a mock provider.
+type oidcTest struct {
+	t          *testing.T
+	idp        *oidctest.Provider
+	h          *OIDCHandler
+	store      *memory.Storage
+	jwt        *auth.JWTManager
+	restaurant primitive.ObjectID
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	idp := oidctest.NewProvider(t, "restify")
+	store := memory.New()
+	jwtManager := auth.NewJWTManager("test-secret", 15*time.Minute, "restify")
+	restaurant := primitive.NewObjectID()
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:   idp.URL,
+		ClientID:    "restify",
+		RedirectURL: "https://restify.test/oidc/callback",
+		Scopes:      []string{"openid", "email", "groups"},
+		GroupsClaim: "groups",
+		HTTPClient:  idp.Client(),
+	})
+	sessions := &UserHandler{Store: store.Users(), Tokens: store.Tokens(), JWT: jwtManager, RefreshTTL: time.Hour}
+	groupRoles := []GroupRole{
+		{Group: "admins", Role: "admin"},
+		{Group: "everyone", Role: "customer"},
+		{Group: "kitchen", Role: types.StaffRoleKitchen, RestaurantID: restaurant},
+		{Group: "managers", Role: types.StaffRoleManager, RestaurantID: restaurant},
+	}
+	h := NewOIDCHandler(provider, oidc.NewMemoryStateStore(), time.Minute, groupRoles, store.Users(), store.Memberships(), sessions)
+	return &oidcTest{t: t, idp: idp, h: h, store: store, jwt: jwtManager, restaurant: restaurant}
+}
+
+// start runs GET /oidc/login and returns the provider URL it redirects to.
+func (o *oidcTest) start() string {
+	o.t.Helper()
+	rec := httptest.NewRecorder()
+	o.h.Login(rec, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		o.t.Fatalf("login: status %d: %s", rec.Code, rec.Body)
+	}
+	return rec.Header().Get("Location")
+}
+
+// callback runs GET /oidc/callback as the provider's redirect would.
+func (o *oidcTest) callback(code, state string) *httptest.ResponseRecorder {
+	o.t.Helper()
+	q := url.Values{"code": {code}, "state": {state}}
+	rec := httptest.NewRecorder()
+	o.h.Callback(rec, httptest.NewRequest(http.MethodGet, "/oidc/callback?"+q.Encode(), nil))
+	return rec
+}
+
+// login signs subject in with claims and returns the callback response.
+func (o *oidcTest) login(subject string, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	o.t.Helper()
+	code, state := o.idp.Authorize(o.t, o.start(), subject, claims)
+	return o.callback(code, state)
+}
+
+// mustLogin is login expecting tokens, returning the access token's claims.
+func (o *oidcTest) mustLogin(subject string, claims jwt.MapClaims) *auth.Claims {
+	o.t.Helper()
+	rec := o.login(subject, claims)
+	if rec.Code != http.StatusOK {
+		o.t.Fatalf("callback: status %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	c, err := o.jwt.Verify(body.Token)
+	if err != nil {
+		o.t.Fatalf("access token: %v", err)
+	}
+	return c
+}
+
+func (o *oidcTest) revoked(c *auth.Claims) bool {
+	o.t.Helper()
+	revoked, err := auth.TokenRevocations{Store: o.store.Tokens()}.IsRevoked(context.Background(), c)
+	if err != nil {
+		o.t.Fatalf("IsRevoked: %v", err)
+	}
+	return revoked
+}
+
+func (o *oidcTest) user(c *auth.Claims) *types.User {
+	o.t.Helper()
+	u, err := o.store.Users().GetUserByID(context.Background(), c.UserID)
+	if err != nil || u == nil {
+		o.t.Fatalf("GetUserByID(%s) = %v, %v", c.UserID, u, err)
+	}
+	return u
+}
+
+func (o *oidcTest) membership(c *auth.Claims) *types.Membership {
+	o.t.Helper()
+	m, err := o.store.Memberships().GetMembership(context.Background(), o.user(c).ID, o.restaurant)
+	if err != nil {
+		o.t.Fatalf("GetMembership: %v", err)
+	}
+	return m
+}
+
+func TestOIDCProvisionsNewUser(t *testing.T) {
+	o := newOIDCTest(t)
+	c := o.mustLogin("alice", jwt.MapClaims{
+		"email":          "Alice@Example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"groups":         []string{"everyone"},
+	})
+
+	u := o.user(c)
+	if u.Email != "alice@example.com" || u.Name != "Alice" || u.Role != "customer" || !u.EmailVerified {
+		t.Errorf("provisioned user = %+v", u)
+	}
+	if len(u.Identities) != 1 || u.Identities[0].Issuer != o.idp.URL || u.Identities[0].Subject != "alice" {
+		t.Errorf("identities = %+v", u.Identities)
+	}
+	if u.Password != "" {
+		t.Error("provisioned user has a password")
+	}
+
+	// The next login finds the user by its identity, even with another email
+	again := o.mustLogin("alice", jwt.MapClaims{"email": "new@example.com", "groups": []string{"everyone"}})
+	if again.UserID != c.UserID {
+		t.Errorf("second login is user %s, want %s", again.UserID, c.UserID)
+	}
+}
+
+func TestOIDCLinksExistingAccount(t *testing.T) {
+	ctx := context.Background()
+	o := newOIDCTest(t)
+	existing := &types.User{ID: primitive.NewObjectID(), Name: "Bob", Email: "bob@example.com", Role: "customer", Password: "hash"}
+	if err := o.store.Users().CreateUser(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := o.login("bob-unverified", jwt.MapClaims{"email": "bob@example.com", "groups": []string{"everyone"}})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("unverified email: status %d, want 409", rec.Code)
+	}
+	if u, _ := o.store.Users().GetUserByIdentity(ctx, o.idp.URL, "bob-unverified"); u != nil {
+		t.Fatal("identity with an unverified email was linked")
+	}
+
+	c := o.mustLogin("bob", jwt.MapClaims{"email": "BOB@example.com", "email_verified": true, "groups": []string{"everyone"}})
+	if c.UserID != existing.ID.Hex() {
+		t.Fatalf("logged in as %s, want the existing user %s", c.UserID, existing.ID.Hex())
+	}
+	if u := o.user(c); len(u.Identities) != 1 || u.Identities[0].Subject != "bob" || u.Password != "hash" {
+		t.Errorf("linked user = %+v", u)
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	o := newOIDCTest(t)
+	code, state := o.idp.Authorize(t, o.start(), "carol", jwt.MapClaims{"email": "carol@example.com", "groups": []string{"everyone"}})
+	if rec := o.callback(code, state); rec.Code != http.StatusOK {
+		t.Fatalf("callback: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := o.callback(code, state); rec.Code != http.StatusBadRequest {
+		t.Fatalf("replayed callback: status %d, want 400", rec.Code)
+	}
+	if rec := o.callback(code, "made-up-state"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown state: status %d, want 400", rec.Code)
+	}
+}
+
+func TestOIDCRequiresPKCE(t *testing.T) {
+	o := newOIDCTest(t)
+	// An attacker swapping in their own challenge cannot redeem the code
+	// with the verifier the handler kept
+	u, _ := url.Parse(o.start())
+	q := u.Query()
+	q.Set("code_challenge", oidc.CodeChallenge("attacker-verifier"))
+	u.RawQuery = q.Encode()
+
+	code, state := o.idp.Authorize(t, u.String(), "dave", jwt.MapClaims{"email": "dave@example.com", "groups": []string{"everyone"}})
+	if rec := o.callback(code, state); rec.Code != http.StatusBadGateway {
+		t.Fatalf("callback: status %d, want 502", rec.Code)
+	}
+}
+
+func TestOIDCRejectsBadIDTokens(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"bad nonce", jwt.MapClaims{"nonce": "stolen"}, http.StatusUnauthorized},
+		{"bad audience", jwt.MapClaims{"aud": "someone-else"}, http.StatusUnauthorized},
+		{"bad azp", jwt.MapClaims{"aud": []string{"restify", "other"}, "azp": "other"}, http.StatusUnauthorized},
+		{"no mapped group", jwt.MapClaims{"groups": []string{"contractors"}}, http.StatusForbidden},
+		{"no email", jwt.MapClaims{"email": ""}, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newOIDCTest(t)
+			claims := jwt.MapClaims{"email": "eve@example.com", "groups": []string{"everyone"}}
+			for k, v := range tc.claims {
+				claims[k] = v
+			}
+			if rec := o.login("eve", claims); rec.Code != tc.want {
+				t.Fatalf("callback: status %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	o := newOIDCTest(t)
+	o.mustLogin("frank", jwt.MapClaims{"email": "frank@example.com", "groups": []string{"everyone"}})
+
+	// The handler's provider has the old key cached and may not refetch for
+	// a minute, so a fresh handler stands in for a later login
+	o.idp.RotateKey(t)
+	o.h.Provider = oidc.NewProvider(oidc.Config{
+		IssuerURL:   o.idp.URL,
+		ClientID:    "restify",
+		RedirectURL: "https://restify.test/oidc/callback",
+		GroupsClaim: "groups",
+		HTTPClient:  o.idp.Client(),
+	})
+	o.mustLogin("frank", jwt.MapClaims{"email": "frank@example.com", "groups": []string{"everyone"}})
+}
+
+func TestOIDCGroupRoles(t *testing.T) {
+	o := newOIDCTest(t)
+	login := func(groups ...string) *auth.Claims {
+		t.Helper()
+		return o.mustLogin("grace", jwt.MapClaims{"email": "grace@example.com", "email_verified": true, "groups": groups})
+	}
+
+	admin := login("admins", "everyone", "kitchen")
+	if admin.Role != "admin" || o.user(admin).Role != "admin" {
+		t.Fatalf("role = %s, want admin", admin.Role)
+	}
+	if m := o.membership(admin); m == nil || m.Role != types.StaffRoleKitchen {
+		t.Fatalf("membership = %+v, want kitchen", m)
+	}
+
+	// Same groups: nothing is taken away, so earlier sessions stay valid
+	same := login("admins", "everyone", "kitchen")
+	if o.revoked(admin) {
+		t.Fatal("session revoked although no role changed")
+	}
+
+	// The first mapping of a restaurant wins
+	promoted := login("admins", "managers", "kitchen")
+	if m := o.membership(promoted); m == nil || m.Role != types.StaffRoleKitchen {
+		t.Fatalf("membership = %+v, want kitchen", m)
+	}
+	promoted = login("admins", "managers")
+	if m := o.membership(promoted); m == nil || m.Role != types.StaffRoleManager {
+		t.Fatalf("membership = %+v, want manager", m)
+	}
+	if !o.revoked(same) {
+		t.Error("session opened as kitchen staff survived the change to manager")
+	}
+
+	demoted := login("everyone")
+	if demoted.Role != "customer" || o.user(demoted).Role != "customer" {
+		t.Fatalf("role = %s, want customer", demoted.Role)
+	}
+	if m := o.membership(demoted); m != nil {
+		t.Fatalf("membership = %+v, want removed", m)
+	}
+	if !o.revoked(promoted) {
+		t.Error("admin session survived the demotion")
+	}
+	if o.revoked(demoted) {
+		t.Error("session issued after the demotion is revoked")
+	}
+
+	// Memberships at restaurants no mapping names are not the provider's
+	other := primitive.NewObjectID()
+	o.store.Memberships().SetMembership(context.Background(), &types.Membership{UserID: o.user(demoted).ID, RestaurantID: other, Role: types.StaffRoleWaiter})
+	login("everyone")
+	if m, _ := o.store.Memberships().GetMembership(context.Background(), o.user(demoted).ID, other); m == nil {
+		t.Error("membership at an unmapped restaurant was removed")
+	}
+}
+
+func TestPasswordLoginOfLinkedAccount(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	attempts := lockout.NewMemoryStore(time.Hour)
+	h := &UserHandler{
+		Store:        store.Users(),
+		Tokens:       store.Tokens(),
+		JWT:          auth.NewJWTManager("test-secret", 15*time.Minute, "restify"),
+		RefreshTTL:   time.Hour,
+		AccountGuard: lockout.NewGuard(attempts, lockout.Policy{MaxFailures: 3, LockoutDuration: time.Hour}),
+		IPGuard:      lockout.NewGuard(attempts, lockout.Policy{MaxFailures: 50, LockoutDuration: time.Hour}),
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked := &types.User{
+		ID: primitive.NewObjectID(), Name: "Carol", Email: "carol@example.com", Role: "customer", Password: string(hash),
+		Identities: []types.Identity{{Issuer: "https://idp.example.com", Subject: "carol"}},
+	}
+	if err := store.Users().CreateUser(ctx, linked); err != nil {
+		t.Fatal(err)
+	}
+
+	// Right or wrong, the password is not checked, so guesses neither reveal
+	// it nor lock the account
+	for _, password := range []string{"guess-1", "guess-2", "guess-3", "guess-4", "password1"} {
+		body, _ := json.Marshal(map[string]string{"email": "carol@example.com", "password": password})
+		rec := httptest.NewRecorder()
+		h.Login(rec, httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body)))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("password %q: status %d, want 403: %s", password, rec.Code, rec.Body)
+		}
+	}
+	if wait, err := h.AccountGuard.Check(ctx, accountKey("carol@example.com")); err != nil || wait != 0 {
+		t.Errorf("account throttled for %v, %v", wait, err)
+	}
+}
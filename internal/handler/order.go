@@ -3,34 +3,45 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"log/slog"
 
 	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
 	"github.com/shubhamjaiswar43/restify/internal/helper"
-	"github.com/shubhamjaiswar43/restify/internal/storage/mongodb"
+	"github.com/shubhamjaiswar43/restify/internal/pricing"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type OrderHandler struct {
-	Store *mongodb.OrderStore
+	Store           storage.OrderStore
+	MenuStore       storage.MenuStore
+	RestaurantStore storage.RestaurantStore
+	Promotions      storage.PromotionStore
+	Authz           *authz.Authorizer
+	// RequireVerifiedEmail refuses orders from users who have not verified their email.
+	RequireVerifiedEmail bool
 }
 
-func NewOrderHandler(store *mongodb.OrderStore) *OrderHandler {
-	return &OrderHandler{Store: store}
+func NewOrderHandler(store storage.OrderStore, menuStore storage.MenuStore, restaurantStore storage.RestaurantStore, promotions storage.PromotionStore, az *authz.Authorizer, requireVerifiedEmail bool) *OrderHandler {
+	return &OrderHandler{Store: store, MenuStore: menuStore, RestaurantStore: restaurantStore, Promotions: promotions, Authz: az, RequireVerifiedEmail: requireVerifiedEmail}
 }
 
 // POST /orders
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	slog.Info("CreateOrder API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
@@ -47,13 +58,30 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if claims.Role == "admin" {
-		if order.UserID.IsZero() {
-			slog.Warn("Admin missing user_id in order creation")
-			helper.WriteSimpleError(w, http.StatusBadRequest, "Admin must specify user_id for the order")
+	if h.RequireVerifiedEmail {
+		user, err := auth.CurrentUser(r.Context())
+		if err != nil {
+			slog.Error("Failed to load current user", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusUnauthorized, "Cannot load user: "+err.Error())
+			return
+		}
+		if user == nil {
+			helper.WriteSimpleError(w, http.StatusUnauthorized, "User no longer exists")
 			return
 		}
-	} else {
+		if !user.EmailVerified {
+			slog.Warn("Order rejected: email not verified", slog.String("user_id", claims.UserID))
+			helper.WriteSimpleError(w, http.StatusForbidden, "Verify your email address before placing orders")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Orders are placed for the caller unless they name a customer, which
+	// only staff may do (orders:create:any)
+	if order.UserID.IsZero() {
 		userObjID, err := primitive.ObjectIDFromHex(claims.UserID)
 		if err != nil {
 			slog.Error("Invalid user_id in token", slog.String("error", err.Error()))
@@ -62,15 +90,110 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		}
 		order.UserID = userObjID
 	}
+	res := authz.Resource{RestaurantID: order.Restaurant, OwnerID: order.UserID}
+	if err := h.Authz.Authorize(r.Context(), "orders:create", res); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	actorRole, err := h.Authz.ActingRole(r.Context(), order.Restaurant)
+	if err != nil {
+		authz.WriteError(w, err)
+		return
+	}
 
+	// Every order starts out pending, whatever the client asked for
+	order.Status = types.OrderStatusPending
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = time.Now()
+	order.StatusHistory = []types.StatusChange{{
+		To:        types.OrderStatusPending,
+		ActorID:   actorID(claims),
+		ActorRole: actorRole,
+		At:        order.CreatedAt,
+	}}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	restaurant, err := h.RestaurantStore.GetByID(ctx, order.Restaurant.Hex())
+	if err != nil {
+		slog.Error("Failed to check restaurant existence", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Error checking restaurant: "+err.Error())
+		return
+	}
+	if restaurant == nil || !restaurant.IsActive {
+		slog.Warn("Order rejected: restaurant unavailable", slog.String("restaurant_id", order.Restaurant.Hex()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Restaurant does not exist or is not accepting orders")
+		return
+	}
+
+	var promo *types.Promotion
+	order.PromoCode = strings.ToUpper(strings.TrimSpace(order.PromoCode))
+	if order.PromoCode != "" {
+		promo, err = h.Promotions.GetPromotionByCode(ctx, order.PromoCode)
+		if err != nil {
+			slog.Error("Failed to look up promo code", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Error checking promo code: "+err.Error())
+			return
+		}
+		if promo == nil {
+			slog.Warn("Order rejected: unknown promo code", slog.String("promo_code", order.PromoCode))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Unknown promo code")
+			return
+		}
+	}
+
+	// Prices always come from the menu, never from the client
+	if err := pricing.PriceOrder(ctx, h.MenuStore, &order, restaurant, promo); err != nil {
+		var itemErr *pricing.ItemError
+		if errors.As(err, &itemErr) {
+			slog.Warn("Order rejected during pricing", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Cannot place order: "+err.Error())
+			return
+		}
+		if errors.Is(err, pricing.ErrOrderTooLarge) {
+			slog.Warn("Order rejected during pricing", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Cannot place order: "+err.Error())
+			return
+		}
+		var promoErr *pricing.PromoError
+		if errors.As(err, &promoErr) {
+			slog.Warn("Order rejected: promo code not applicable", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Cannot apply promo code: "+promoErr.Reason)
+			return
+		}
+		slog.Error("Failed to price order", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to price order: "+err.Error())
+		return
+	}
+
+	// The redemption is counted before the order is saved so that the limits
+	// hold under concurrent orders, and given back if saving fails
+	if promo != nil {
+		if err := h.Promotions.Redeem(ctx, promo.ID, order.UserID); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrRedemptionLimit):
+				helper.WriteSimpleError(w, http.StatusConflict, "Promo code has been fully redeemed")
+			case errors.Is(err, storage.ErrUserRedemptionLimit):
+				helper.WriteSimpleError(w, http.StatusConflict, "You have already used this promo code as often as allowed")
+			case errors.Is(err, storage.ErrNotFound):
+				helper.WriteSimpleError(w, http.StatusBadRequest, "Unknown promo code")
+			default:
+				slog.Error("Failed to redeem promo code", slog.String("error", err.Error()))
+				helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to redeem promo code: "+err.Error())
+			}
+			return
+		}
+	}
 
 	created, err := h.Store.CreateOrder(ctx, &order)
 	if err != nil {
+		if promo != nil {
+			if relErr := h.Promotions.Release(ctx, promo.ID, order.UserID); relErr != nil {
+				slog.Error("Failed to release promo redemption",
+					slog.String("promotion_id", promo.ID.Hex()),
+					slog.String("user_id", order.UserID.Hex()),
+					slog.String("error", relErr.Error()),
+				)
+			}
+		}
 		slog.Error("Failed to create order", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create order: "+err.Error())
 		return
@@ -90,30 +213,28 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GET /orders - only admin
+// GET /orders?status=&restaurant_id=&user_id=&created_from=&created_to=&limit=&cursor=&sort= - requires orders:read on every order
 func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetAllOrders API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
-	if claims.Role != "admin" {
-		slog.Warn("Unauthorized access attempt to GetAllOrders", slog.String("user_id", claims.UserID))
-		helper.WriteSimpleError(w, http.StatusForbidden, "Only admin can view all orders")
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		slog.Warn("Invalid order query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	orders, err := h.Store.GetAllOrders(ctx)
+	orders, next, err := h.Store.ListOrders(ctx, filter)
 	if err != nil {
-		slog.Error("Failed to fetch orders", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch orders: "+err.Error())
+		writeListError(w, "orders", err)
 		return
 	}
 
@@ -124,9 +245,127 @@ func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	)
 
 	json.NewEncoder(w).Encode(map[string]any{
-		"count":   len(orders),
-		"orders":  orders,
-		"fetched": time.Now().Format(time.RFC3339),
+		"count":       len(orders),
+		"orders":      orders,
+		"next_cursor": next,
+		"fetched":     time.Now().Format(time.RFC3339),
+	})
+}
+
+// GET /me/orders?status=&restaurant_id=&created_from=&created_to=&limit=&cursor=&sort=
+func (h *OrderHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetMyOrders API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		slog.Error("Invalid user_id in token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid user ID in token: "+err.Error())
+		return
+	}
+
+	if err := h.Authz.Authorize(r.Context(), "orders:read", authz.Resource{OwnerID: userID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		slog.Warn("Invalid order query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	orders, next, err := h.Store.ListUserOrders(ctx, userID, filter)
+	if err != nil {
+		writeListError(w, "orders", err)
+		return
+	}
+
+	slog.Info("Fetched user orders successfully",
+		slog.Int("count", len(orders)),
+		slog.String("user_id", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(orders),
+		"orders":      orders,
+		"next_cursor": next,
+		"fetched":     time.Now().Format(time.RFC3339),
+	})
+}
+
+// GET /restaurants/{id}/orders?status=&user_id=&created_from=&created_to=&limit=&cursor=&sort=
+func (h *OrderHandler) GetRestaurantOrders(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetRestaurantOrders API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	restaurantID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid restaurant ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid restaurant ID format")
+		return
+	}
+
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		slog.Warn("Invalid order query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.Authz.Authorize(r.Context(), "orders:read", authz.Resource{RestaurantID: restaurantID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	restaurant, err := h.RestaurantStore.GetByID(ctx, idStr)
+	if err != nil {
+		slog.Error("Failed to fetch restaurant", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch restaurant: "+err.Error())
+		return
+	}
+	if restaurant == nil {
+		slog.Warn("Restaurant not found", slog.String("restaurant_id", idStr))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+
+	orders, next, err := h.Store.ListRestaurantOrders(ctx, restaurantID, filter)
+	if err != nil {
+		writeListError(w, "orders", err)
+		return
+	}
+
+	slog.Info("Fetched restaurant orders successfully",
+		slog.Int("count", len(orders)),
+		slog.String("restaurant_id", idStr),
+		slog.String("requested_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":         len(orders),
+		"orders":        orders,
+		"next_cursor":   next,
+		"restaurant_id": idStr,
+		"fetched":       time.Now().Format(time.RFC3339),
 	})
 }
 
@@ -134,14 +373,12 @@ func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetOrderByID API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
-	idStr := r.URL.Path[len("/orders/"):]
+	idStr := r.PathValue("id")
 	orderID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
 		slog.Warn("Invalid order ID", slog.String("id", idStr))
@@ -164,9 +401,7 @@ func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if claims.Role != "admin" && order.UserID.Hex() != claims.UserID {
-		slog.Warn("Forbidden order access", slog.String("order_id", order.ID.Hex()), slog.String("user_id", claims.UserID))
-		helper.WriteSimpleError(w, http.StatusForbidden, "You can only access your own orders")
+	if !h.authorizeOrder(w, r, "orders:read", order) {
 		return
 	}
 
@@ -182,3 +417,176 @@ func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 		"fetchedAt":   time.Now().Format(time.RFC3339),
 	})
 }
+
+// PATCH /orders/{id}/status
+func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateOrderStatus API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	orderID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid order ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid order ID format")
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" validate:"required,oneof=pending preparing ready completed cancelled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Order status validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	order, err := h.Store.GetOrderByID(ctx, orderID)
+	if err != nil {
+		slog.Error("Failed to fetch order", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch order: "+err.Error())
+		return
+	}
+	if order == nil {
+		slog.Warn("Order not found", slog.String("order_id", idStr))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	// Each target status is its own permission, e.g. the kitchen may set
+	// orders:status:ready and customers orders:status:cancelled on their own orders
+	if !h.authorizeOrder(w, r, "orders:status:"+req.Status, order) {
+		return
+	}
+	actorRole, err := h.Authz.ActingRole(r.Context(), order.Restaurant)
+	if err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	if !types.CanTransitionOrder(order.Status, req.Status) {
+		slog.Warn("Illegal order status transition",
+			slog.String("order_id", order.ID.Hex()),
+			slog.String("from", order.Status),
+			slog.String("to", req.Status),
+		)
+		helper.WriteErrorResponse(w, http.StatusConflict, map[string]string{
+			"message": "Cannot change order status from " + order.Status + " to " + req.Status,
+			"allowed": strings.Join(types.NextOrderStatuses(order.Status), ","),
+		})
+		return
+	}
+
+	updated, err := h.Store.UpdateOrderStatus(ctx, orderID, types.StatusChange{
+		From:      order.Status,
+		To:        req.Status,
+		ActorID:   actorID(claims),
+		ActorRole: actorRole,
+		At:        time.Now(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrConflict):
+			slog.Warn("Order status changed concurrently", slog.String("order_id", idStr))
+			helper.WriteSimpleError(w, http.StatusConflict, "Order status was changed by someone else, reload and retry")
+		case errors.Is(err, storage.ErrNotFound):
+			helper.WriteSimpleError(w, http.StatusNotFound, "Order not found")
+		default:
+			slog.Error("Failed to update order status", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update order status: "+err.Error())
+		}
+		return
+	}
+
+	// The conditional update lets only one request cancel the order, so each
+	// redemption is given back once
+	if updated.Status == types.OrderStatusCancelled {
+		for _, d := range updated.Discounts {
+			if err := h.Promotions.Release(ctx, d.PromotionID, updated.UserID); err != nil {
+				slog.Error("Failed to release promo redemption",
+					slog.String("order_id", updated.ID.Hex()),
+					slog.String("promotion_id", d.PromotionID.Hex()),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+
+	slog.Info("Order status updated",
+		slog.String("order_id", updated.ID.Hex()),
+		slog.String("from", order.Status),
+		slog.String("to", updated.Status),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Order status updated successfully",
+		"order":   updated,
+	})
+}
+
+// actorID converts the authenticated user's ID for audit records.
+// A malformed ID yields the zero ObjectID rather than failing the request.
+func actorID(claims *auth.Claims) primitive.ObjectID {
+	id, _ := primitive.ObjectIDFromHex(claims.UserID)
+	return id
+}
+
+// parseOrderFilter reads the order list query parameters.
+func parseOrderFilter(q url.Values) (storage.OrderFilter, error) {
+	var (
+		f   storage.OrderFilter
+		err error
+	)
+	if f.ListOptions, err = parseListOptions(q); err != nil {
+		return f, err
+	}
+	f.Status = q.Get("status")
+	if f.Status != "" && !slices.Contains(types.OrderStatuses, f.Status) {
+		return f, fmt.Errorf("status must be one of: %s", strings.Join(types.OrderStatuses, " "))
+	}
+	if f.RestaurantID, err = parseObjectIDParam(q, "restaurant_id"); err != nil {
+		return f, err
+	}
+	if f.UserID, err = parseObjectIDParam(q, "user_id"); err != nil {
+		return f, err
+	}
+	if f.CreatedFrom, err = parseTimeParam(q, "created_from", false); err != nil {
+		return f, err
+	}
+	if f.CreatedTo, err = parseTimeParam(q, "created_to", true); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// authorizeOrder checks permission on order and writes the error response
+// when it is missing. Callers who may not even read the order get the same
+// 404 as for a missing one, so order IDs cannot be probed for existence.
+func (h *OrderHandler) authorizeOrder(w http.ResponseWriter, r *http.Request, permission string, order *types.Order) bool {
+	res := authz.Resource{RestaurantID: order.Restaurant, OwnerID: order.UserID}
+	err := h.Authz.Authorize(r.Context(), permission, res)
+	if err == nil {
+		return true
+	}
+	var denied *authz.DeniedError
+	if errors.As(err, &denied) && (permission == "orders:read" || h.Authz.Authorize(r.Context(), "orders:read", res) != nil) {
+		slog.Warn("Order hidden from caller without read access", slog.String("order_id", order.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Order not found")
+		return false
+	}
+	authz.WriteError(w, err)
+	return false
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// saveUser stores the edited profile of user and writes the error response on
+// failure. A changed email starts out unverified: outstanding mailed tokens
+// are invalidated and a new verification email goes to the new address.
+func (h *UserHandler) saveUser(ctx context.Context, w http.ResponseWriter, user *types.User, emailChanged bool) (*types.User, bool) {
+	if emailChanged {
+		user.EmailVerified = false
+		user.EmailVerifiedAt = nil
+	}
+	updated, err := h.Store.UpdateUser(ctx, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+		case errors.Is(err, storage.ErrDuplicate):
+			slog.Warn("Email already registered", slog.String("email", user.Email))
+			helper.WriteSimpleError(w, http.StatusConflict, "Email already registered")
+		default:
+			slog.Error("Failed to update user", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update user: "+err.Error())
+		}
+		return nil, false
+	}
+
+	if emailChanged {
+		for _, purpose := range []string{types.TokenPurposeEmailVerification, types.TokenPurposePasswordReset} {
+			if err := h.Accounts.OneTime.InvalidateOneTimeTokens(ctx, updated.ID, purpose); err != nil {
+				slog.Error("Failed to invalidate mailed tokens", slog.String("purpose", purpose), slog.String("error", err.Error()))
+			}
+		}
+		if err := h.Accounts.sendVerificationEmail(ctx, updated); err != nil {
+			slog.Error("Failed to send verification email",
+				slog.String("user_id", updated.ID.Hex()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return updated, true
+}
+
+// GET /me - the caller's own profile
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetMe API called", slog.Time("timestamp", time.Now()))
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// PATCH /me - edits the caller's name, email or password. Changing the email
+// or password requires the current password; a new password signs the user
+// out everywhere.
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateMe API called", slog.Time("timestamp", time.Now()))
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name            *string `json:"name" validate:"omitempty,min=2,max=100"`
+		Email           *string `json:"email" validate:"omitempty,email"`
+		Password        *string `json:"password" validate:"omitempty,min=8"`
+		CurrentPassword string  `json:"current_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Profile validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	emailChanged := req.Email != nil && types.NormalizeEmail(*req.Email) != user.Email
+	if emailChanged || req.Password != nil {
+		if req.CurrentPassword == "" {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "current_password is required to change the email or password")
+			return
+		}
+		// Guessing the current password here counts like a failed login
+		ip := clientIP(r)
+		if !checkThrottle(ctx, w, h.AccountGuard, accountKey(user.Email)) {
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			slog.Warn("Invalid current password", slog.String("user_id", user.ID.Hex()), slog.String("ip", ip))
+			h.recordLoginFailure(ctx, user.Email, ip, user)
+			helper.WriteSimpleError(w, http.StatusUnauthorized, "Current password is incorrect")
+			return
+		}
+		if err := h.AccountGuard.Reset(ctx, accountKey(user.Email)); err != nil {
+			slog.Error("Failed to reset login failures", slog.String("error", err.Error()))
+		}
+	}
+
+	// Hash first so a failure leaves the profile untouched, then save
+	// everything in one write
+	if req.Password != nil {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			slog.Error("Failed to hash password", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to hash password: "+err.Error())
+			return
+		}
+		user.Password = string(hashed)
+	}
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if emailChanged {
+		user.Email = types.NormalizeEmail(*req.Email)
+	}
+	updated, ok := h.saveUser(ctx, w, user, emailChanged)
+	if !ok {
+		return
+	}
+
+	resp := map[string]any{
+		"message": "Profile updated successfully",
+		"user":    updated,
+	}
+	if req.Password != nil {
+		// Every session, this one included, was opened with the old password
+		if err := h.Tokens.RevokeUserTokens(ctx, user.ID, time.Now()); err != nil {
+			slog.Error("Failed to revoke sessions after password change", slog.String("error", err.Error()))
+		}
+		resp["message"] = "Profile updated, log in again with the new password"
+	}
+
+	slog.Info("Profile updated",
+		slog.String("user_id", user.ID.Hex()),
+		slog.Bool("email_changed", emailChanged),
+		slog.Bool("password_changed", req.Password != nil),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
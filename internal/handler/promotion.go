@@ -0,0 +1,391 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PromotionHandler struct {
+	Store           storage.PromotionStore
+	RestaurantStore storage.RestaurantStore
+	Authz           *authz.Authorizer
+}
+
+func NewPromotionHandler(store storage.PromotionStore, restaurantStore storage.RestaurantStore, az *authz.Authorizer) *PromotionHandler {
+	return &PromotionHandler{Store: store, RestaurantStore: restaurantStore, Authz: az}
+}
+
+// POST /promotions - creates a promo code for one restaurant, or without
+// restaurant_id for every restaurant (admins only)
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	slog.Info("CreatePromotion API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var p types.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	p.Code = strings.ToUpper(strings.TrimSpace(p.Code))
+	if err := helper.ValidateStruct(p); err != nil {
+		slog.Warn("Promotion validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+	if err := checkPromotionRules(&p); err != nil {
+		slog.Warn("Promotion validation failed", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := authz.Resource{}
+	if p.RestaurantID != nil {
+		res.RestaurantID = *p.RestaurantID
+	}
+	if err := h.Authz.Authorize(r.Context(), "promotions:write", res); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !h.checkPromotionMoney(ctx, w, &p) {
+		return
+	}
+
+	// Usage is counted by the server only
+	p.ID = primitive.NewObjectID()
+	p.Redemptions = 0
+	p.Active = true
+	p.CreatedBy = actorID(claims)
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+
+	if err := h.Store.CreatePromotion(ctx, &p); err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			slog.Warn("Duplicate promo code", slog.String("code", p.Code))
+			helper.WriteSimpleError(w, http.StatusConflict, "A promotion with this code already exists")
+			return
+		}
+		slog.Error("Failed to create promotion", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create promotion: "+err.Error())
+		return
+	}
+
+	slog.Info("Promotion created successfully",
+		slog.String("promotion_id", p.ID.Hex()),
+		slog.String("code", p.Code),
+		slog.String("type", p.Type),
+		slog.String("created_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":   "Promotion created successfully",
+		"promotion": p,
+	})
+}
+
+// GET /promotions?restaurant_id=&global=&active=&limit=&cursor=&sort= -
+// without restaurant_id requires promotions:read everywhere
+func (h *PromotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetPromotions API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var (
+		filter storage.PromotionFilter
+		global *bool
+		err    error
+	)
+	if filter.ListOptions, err = parseListOptions(q); err == nil {
+		if filter.RestaurantID, err = parseObjectIDParam(q, "restaurant_id"); err == nil {
+			if filter.Active, err = parseBoolParam(q, "active"); err == nil {
+				global, err = parseBoolParam(q, "global")
+			}
+		}
+	}
+	if err == nil && global != nil && *global && !filter.RestaurantID.IsZero() {
+		err = fmt.Errorf("global and restaurant_id cannot be combined")
+	}
+	if err != nil {
+		slog.Warn("Invalid promotion query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.GlobalOnly = global != nil && *global
+
+	if err := h.Authz.Authorize(r.Context(), "promotions:read", authz.Resource{RestaurantID: filter.RestaurantID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	promotions, next, err := h.Store.ListPromotions(ctx, filter)
+	if err != nil {
+		writeListError(w, "promotions", err)
+		return
+	}
+
+	slog.Info("Promotions fetched successfully",
+		slog.Int("count", len(promotions)),
+		slog.String("requested_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(promotions),
+		"promotions":  promotions,
+		"next_cursor": next,
+	})
+}
+
+// GET /promotions/{id}
+func (h *PromotionHandler) GetPromotionByID(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetPromotionByID API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, ok := h.loadPromotion(ctx, w, r, "promotions:read")
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"promotion":    p,
+		"requested_by": claims.UserID,
+	})
+}
+
+// PATCH /promotions/{id} - updates only the fields present in the body. The
+// code, type and discount cannot change once customers may have used them.
+func (h *PromotionHandler) PatchPromotion(w http.ResponseWriter, r *http.Request) {
+	slog.Info("PatchPromotion API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Description    *string      `json:"description"`
+		StartsAt       *time.Time   `json:"starts_at"`
+		EndsAt         *time.Time   `json:"ends_at"`
+		MaxRedemptions *int         `json:"max_redemptions"`
+		MaxPerUser     *int         `json:"max_per_user"`
+		MinOrder       *types.Money `json:"min_order"`
+		Active         *bool        `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, ok := h.loadPromotion(ctx, w, r, "promotions:write")
+	if !ok {
+		return
+	}
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.StartsAt != nil {
+		p.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		p.EndsAt = req.EndsAt
+	}
+	if req.MaxRedemptions != nil {
+		p.MaxRedemptions = *req.MaxRedemptions
+	}
+	if req.MaxPerUser != nil {
+		p.MaxPerUser = *req.MaxPerUser
+	}
+	if req.MinOrder != nil {
+		p.MinOrder = *req.MinOrder
+	}
+	if req.Active != nil {
+		p.Active = *req.Active
+	}
+	if err := helper.ValidateStruct(p); err != nil {
+		slog.Warn("Promotion validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+	if err := checkPromotionRules(p); err != nil {
+		slog.Warn("Promotion validation failed", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkPromotionMoney(ctx, w, p) {
+		return
+	}
+
+	updated, err := h.Store.UpdatePromotion(ctx, p)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "Promotion not found")
+			return
+		}
+		slog.Error("Failed to update promotion", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update promotion: "+err.Error())
+		return
+	}
+
+	slog.Info("Promotion updated successfully",
+		slog.String("promotion_id", updated.ID.Hex()),
+		slog.Bool("active", updated.Active),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Promotion updated successfully",
+		"promotion":  updated,
+		"updated_by": claims.UserID,
+	})
+}
+
+// loadPromotion fetches the promotion named by the request path, checks the
+// caller holds permission on it and writes the error response itself when
+// either fails.
+func (h *PromotionHandler) loadPromotion(ctx context.Context, w http.ResponseWriter, r *http.Request, permission string) (*types.Promotion, bool) {
+	idStr := r.PathValue("id")
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid promotion ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid promotion ID format")
+		return nil, false
+	}
+
+	p, err := h.Store.GetPromotion(ctx, id)
+	if err != nil {
+		slog.Error("Failed to fetch promotion", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch promotion: "+err.Error())
+		return nil, false
+	}
+	if p == nil {
+		slog.Warn("Promotion not found", slog.String("promotion_id", idStr))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Promotion not found")
+		return nil, false
+	}
+
+	res := authz.Resource{}
+	if p.RestaurantID != nil {
+		res.RestaurantID = *p.RestaurantID
+	}
+	if err := h.Authz.Authorize(r.Context(), permission, res); err != nil {
+		authz.WriteError(w, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// checkPromotionRules checks what the struct tags cannot: the fields each
+// promotion type needs and a validity window that ends after it starts.
+func checkPromotionRules(p *types.Promotion) error {
+	switch p.Type {
+	case types.PromoPercentage, types.PromoCategory:
+		if p.Percent <= 0 || p.Percent > types.Hundred {
+			return fmt.Errorf("percent must be greater than 0 and at most 100")
+		}
+		if p.Type == types.PromoCategory && len(p.Categories) == 0 && len(p.MenuItemIDs) == 0 {
+			return fmt.Errorf("a category promotion needs categories or menu_item_ids")
+		}
+	case types.PromoFixedAmount:
+		if p.Amount.Amount <= 0 {
+			return fmt.Errorf("amount must be greater than 0")
+		}
+	case types.PromoBuyXGetY:
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 {
+			return fmt.Errorf("buy_quantity and get_quantity must be at least 1")
+		}
+	}
+	if p.MinOrder.Amount < 0 {
+		return fmt.Errorf("min_order cannot be negative")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// checkPromotionMoney puts the promotion's amounts in the currency of its
+// restaurant, or for a promotion valid everywhere makes sure they name one
+// currency, and writes the error response itself when it cannot.
+func (h *PromotionHandler) checkPromotionMoney(ctx context.Context, w http.ResponseWriter, p *types.Promotion) bool {
+	if p.RestaurantID == nil {
+		currency := ""
+		for _, m := range []types.Money{p.Amount, p.MinOrder} {
+			if m.IsZero() {
+				continue
+			}
+			if err := helper.ValidateVar(m.Currency, "required,iso4217"); err != nil {
+				helper.WriteSimpleError(w, http.StatusBadRequest, "Amounts of a promotion for every restaurant need a valid currency")
+				return false
+			}
+			if currency != "" && m.Currency != currency {
+				helper.WriteSimpleError(w, http.StatusBadRequest, "amount and min_order must be in the same currency")
+				return false
+			}
+			currency = m.Currency
+		}
+		return true
+	}
+
+	restaurant, err := h.RestaurantStore.GetByID(ctx, p.RestaurantID.Hex())
+	if err != nil {
+		slog.Error("Failed to check restaurant existence", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Error checking restaurant: "+err.Error())
+		return false
+	}
+	if restaurant == nil {
+		slog.Warn("Promotion for unknown restaurant", slog.String("restaurant_id", p.RestaurantID.Hex()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Restaurant does not exist")
+		return false
+	}
+	for _, m := range []*types.Money{&p.Amount, &p.MinOrder} {
+		converted, err := m.In(restaurant.Currency)
+		if err != nil {
+			slog.Warn("Promotion amount in wrong currency", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Amounts must be in the restaurant's currency "+restaurant.Currency)
+			return false
+		}
+		*m = converted
+	}
+	return true
+}
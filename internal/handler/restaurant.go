@@ -3,33 +3,37 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"log/slog"
 
 	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
 	"github.com/shubhamjaiswar43/restify/internal/helper"
-	"github.com/shubhamjaiswar43/restify/internal/storage/mongodb"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type RestaurantHandler struct {
-	Store *mongodb.RestaurantStore
+	Store storage.RestaurantStore
+	Authz *authz.Authorizer
+	// DefaultCurrency is used for restaurants created without a currency.
+	DefaultCurrency string
 }
 
-func NewRestaurantHandler(store *mongodb.RestaurantStore) *RestaurantHandler {
-	return &RestaurantHandler{Store: store}
+func NewRestaurantHandler(store storage.RestaurantStore, az *authz.Authorizer, defaultCurrency string) *RestaurantHandler {
+	return &RestaurantHandler{Store: store, Authz: az, DefaultCurrency: defaultCurrency}
 }
 
 // POST /restaurants
 func (h *RestaurantHandler) CreateRestaurant(w http.ResponseWriter, r *http.Request) {
 	slog.Info("CreateRestaurant API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
@@ -52,6 +56,9 @@ func (h *RestaurantHandler) CreateRestaurant(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Taxes are set through PUT /restaurants/{id}/tax
+	restaurant.Tax = types.TaxPolicy{}
+
 	if err := helper.ValidateStruct(restaurant); err != nil {
 		slog.Warn("Restaurant validation failed", slog.String("error", err.Error()))
 		helper.WriteValidationError(w, err)
@@ -59,27 +66,23 @@ func (h *RestaurantHandler) CreateRestaurant(w http.ResponseWriter, r *http.Requ
 	}
 
 	restaurant.IsActive = true
+	if restaurant.Currency == "" {
+		restaurant.Currency = h.DefaultCurrency
+	}
 	restaurant.CreatedAt = time.Now()
 	restaurant.UpdatedAt = time.Now()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Check if a restaurant with the same name already exists
-	exists, err := h.Store.GetByName(ctx, restaurant.Name)
-	if err != nil {
-		slog.Error("Failed to check restaurant existence", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Error checking existing restaurant: "+err.Error())
-		return
-	}
-	if exists != nil {
-		slog.Warn("Duplicate restaurant creation attempt", slog.String("restaurant_name", restaurant.Name))
-		helper.WriteSimpleError(w, http.StatusConflict, "Restaurant with this name already exists")
-		return
-	}
-
+	// Name uniqueness is enforced by the store
 	created, err := h.Store.CreateRestaurant(ctx, &restaurant)
 	if err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			slog.Warn("Duplicate restaurant creation attempt", slog.String("restaurant_name", restaurant.Name))
+			helper.WriteSimpleError(w, http.StatusConflict, "Restaurant with this name already exists")
+			return
+		}
 		slog.Error("Failed to create restaurant in DB", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create restaurant: "+err.Error())
 		return
@@ -101,14 +104,12 @@ func (h *RestaurantHandler) CreateRestaurant(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-// GET /restaurants
+// GET /restaurants?name=&active=&limit=&cursor=&sort=
 func (h *RestaurantHandler) GetRestaurants(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetRestaurants API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Missing claims in context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
@@ -118,13 +119,33 @@ func (h *RestaurantHandler) GetRestaurants(w http.ResponseWriter, r *http.Reques
 		slog.Time("timestamp", time.Now()),
 	)
 
+	q := r.URL.Query()
+	filter := storage.RestaurantFilter{Name: q.Get("name")}
+	listOpts, err := parseListOptions(q)
+	var activeOnly *bool
+	if err == nil {
+		activeOnly, err = parseBoolParam(q, "active")
+	}
+	if err != nil {
+		slog.Warn("Invalid restaurant query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.ListOptions = listOpts
+	// Deactivated restaurants are hidden unless the policy says otherwise
+	viewInactive, err := h.Authz.Can(r.Context(), "restaurants:view-inactive", authz.Resource{})
+	if err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	filter.ActiveOnly = !viewInactive || (activeOnly != nil && *activeOnly)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	restaurants, err := h.Store.GetAllRestaurants(ctx)
+	restaurants, next, err := h.Store.ListRestaurants(ctx, filter)
 	if err != nil {
-		slog.Error("Failed to fetch restaurants", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch restaurants: "+err.Error())
+		writeListError(w, "restaurants", err)
 		return
 	}
 
@@ -136,6 +157,247 @@ func (h *RestaurantHandler) GetRestaurants(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(map[string]any{
 		"count":        len(restaurants),
 		"restaurants":  restaurants,
+		"next_cursor":  next,
+		"requested_by": claims.UserID,
+	})
+}
+
+// GET /restaurants/{id}
+func (h *RestaurantHandler) GetRestaurantByID(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetRestaurantByID API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restaurant, ok := h.loadRestaurant(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !h.checkVisible(w, r, restaurant) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"restaurant":   restaurant,
 		"requested_by": claims.UserID,
 	})
 }
+
+// PUT /restaurants/{id} - replaces name, address, phone and description.
+// Activation is changed through PATCH or DELETE only, and the currency never.
+func (h *RestaurantHandler) UpdateRestaurant(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateRestaurant API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req types.Restaurant
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Restaurant validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restaurant, ok := h.loadRestaurant(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.Authz.Authorize(r.Context(), "restaurants:write", authz.Resource{RestaurantID: restaurant.ID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	// Existing prices are in the restaurant's currency, so it is fixed
+	if req.Currency != "" && req.Currency != restaurant.Currency {
+		slog.Warn("Attempt to change restaurant currency", slog.String("restaurant_id", restaurant.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Restaurant currency cannot be changed")
+		return
+	}
+	restaurant.Name = req.Name
+	restaurant.Address = req.Address
+	restaurant.Phone = req.Phone
+	restaurant.Description = req.Description
+
+	h.saveRestaurant(ctx, w, restaurant, claims)
+}
+
+// PATCH /restaurants/{id} - updates only the fields present in the body,
+// including is_active to deactivate or reactivate a restaurant.
+func (h *RestaurantHandler) PatchRestaurant(w http.ResponseWriter, r *http.Request) {
+	slog.Info("PatchRestaurant API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        *string `json:"name"`
+		Address     *string `json:"address"`
+		Phone       *string `json:"phone"`
+		Description *string `json:"description"`
+		IsActive    *bool   `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restaurant, ok := h.loadRestaurant(ctx, w, r)
+	if !ok {
+		return
+	}
+	res := authz.Resource{RestaurantID: restaurant.ID}
+	if err := h.Authz.Authorize(r.Context(), "restaurants:write", res); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	// Running a restaurant and opening or closing it are separate permissions
+	if req.IsActive != nil && *req.IsActive != restaurant.IsActive {
+		if err := h.Authz.Authorize(r.Context(), "restaurants:activate", res); err != nil {
+			authz.WriteError(w, err)
+			return
+		}
+	}
+	if req.Name != nil {
+		restaurant.Name = *req.Name
+	}
+	if req.Address != nil {
+		restaurant.Address = *req.Address
+	}
+	if req.Phone != nil {
+		restaurant.Phone = *req.Phone
+	}
+	if req.Description != nil {
+		restaurant.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		restaurant.IsActive = *req.IsActive
+	}
+	if err := helper.ValidateStruct(restaurant); err != nil {
+		slog.Warn("Restaurant validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	h.saveRestaurant(ctx, w, restaurant, claims)
+}
+
+// DELETE /restaurants/{id} - soft delete, the restaurant is only deactivated
+func (h *RestaurantHandler) DeleteRestaurant(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteRestaurant API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restaurant, ok := h.loadRestaurant(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.Authz.Authorize(r.Context(), "restaurants:activate", authz.Resource{RestaurantID: restaurant.ID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+	restaurant.IsActive = false
+
+	h.saveRestaurant(ctx, w, restaurant, claims)
+}
+
+// loadRestaurant fetches the restaurant named by the {id} path wildcard and
+// writes the error response itself when it cannot.
+func (h *RestaurantHandler) loadRestaurant(ctx context.Context, w http.ResponseWriter, r *http.Request) (*types.Restaurant, bool) {
+	idStr := r.PathValue("id")
+	if _, err := primitive.ObjectIDFromHex(idStr); err != nil {
+		slog.Warn("Invalid restaurant ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid restaurant ID format")
+		return nil, false
+	}
+
+	restaurant, err := h.Store.GetByID(ctx, idStr)
+	if err != nil {
+		slog.Error("Failed to fetch restaurant", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch restaurant: "+err.Error())
+		return nil, false
+	}
+	if restaurant == nil {
+		slog.Warn("Restaurant not found", slog.String("restaurant_id", idStr))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+		return nil, false
+	}
+	return restaurant, true
+}
+
+// checkVisible answers 404 for a deactivated restaurant unless the caller is
+// allowed to see it, as its own staff is.
+func (h *RestaurantHandler) checkVisible(w http.ResponseWriter, r *http.Request, restaurant *types.Restaurant) bool {
+	if restaurant.IsActive {
+		return true
+	}
+	visible, err := h.Authz.Can(r.Context(), "restaurants:view-inactive", authz.Resource{RestaurantID: restaurant.ID})
+	if err != nil {
+		authz.WriteError(w, err)
+		return false
+	}
+	if !visible {
+		slog.Warn("Customer requested deactivated restaurant", slog.String("restaurant_id", restaurant.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+		return false
+	}
+	return true
+}
+
+// saveRestaurant persists an edited restaurant and writes the response. A
+// rename colliding with another restaurant is answered with 409.
+func (h *RestaurantHandler) saveRestaurant(ctx context.Context, w http.ResponseWriter, restaurant *types.Restaurant, claims *auth.Claims) {
+	updated, err := h.Store.UpdateRestaurant(ctx, restaurant)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicate) {
+			slog.Warn("Restaurant rename conflicts with existing name", slog.String("restaurant_name", restaurant.Name))
+			helper.WriteSimpleError(w, http.StatusConflict, "Restaurant with this name already exists")
+			return
+		}
+		slog.Error("Failed to update restaurant in DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to update restaurant: "+err.Error())
+		return
+	}
+
+	slog.Info("Restaurant updated successfully",
+		slog.String("restaurant_id", updated.ID.Hex()),
+		slog.Bool("is_active", updated.IsActive),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Restaurant updated successfully",
+		"restaurant": updated,
+		"updated_by": claims.UserID,
+	})
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// issueTokens mints an access token and a refresh token belonging to the
+// given token family, and returns the login response body.
+func (h *UserHandler) issueTokens(ctx context.Context, user *types.User, familyID primitive.ObjectID) (map[string]any, error) {
+	access, err := h.JWT.Generate(user.ID.Hex(), user.Role)
+	if err != nil {
+		return nil, err
+	}
+	refresh, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = h.Tokens.CreateRefreshToken(ctx, &types.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(h.RefreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokenResponse(access, refresh, h.JWT.TokenDuration, user.Role), nil
+}
+
+func tokenResponse(access, refresh string, ttl time.Duration, role string) map[string]any {
+	return map[string]any{
+		"token":         access,
+		"refresh_token": refresh,
+		"token_type":    "Bearer",
+		"expires_in":    int(ttl.Seconds()),
+		"role":          role,
+	}
+}
+
+// POST /token/refresh - exchanges a refresh token for a new token pair.
+// Each refresh token works once; replaying a used one revokes its whole family.
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RefreshToken API called", slog.Time("timestamp", time.Now()))
+
+	var req struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Refresh validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stored, err := h.Tokens.GetRefreshToken(ctx, auth.HashToken(req.RefreshToken))
+	if err != nil {
+		slog.Error("Failed to fetch refresh token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if stored == nil || time.Now().After(stored.ExpiresAt) {
+		slog.Warn("Unknown or expired refresh token")
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+	if stored.RevokedAt != nil {
+		h.revokeReplayedFamily(ctx, stored)
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+
+	user, err := h.Store.GetUserByID(ctx, stored.UserID.Hex())
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if user == nil {
+		slog.Warn("Refresh token of deleted user", slog.String("user_id", stored.UserID.Hex()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+	// Sessions from before MFA became mandatory end here
+	if h.MFA.required(user.Role) && !user.MFA.Enabled {
+		slog.Warn("Refresh refused until MFA enrollment", slog.String("user_id", user.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Two-factor enrollment required, log in again")
+		return
+	}
+
+	access, err := h.JWT.Generate(user.ID.Hex(), user.Role)
+	if err != nil {
+		slog.Error("Failed to generate JWT", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+		return
+	}
+	refresh, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		slog.Error("Failed to generate refresh token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	err = h.Tokens.RotateRefreshToken(ctx, stored.ID, &types.RefreshToken{
+		UserID:    stored.UserID,
+		FamilyID:  stored.FamilyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(h.RefreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			// Someone used the same token a moment ago
+			h.revokeReplayedFamily(ctx, stored)
+			helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+			return
+		}
+		slog.Error("Failed to rotate refresh token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to rotate refresh token: "+err.Error())
+		return
+	}
+
+	slog.Info("Tokens refreshed",
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("family_id", stored.FamilyID.Hex()),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(tokenResponse(access, refresh, h.JWT.TokenDuration, user.Role))
+}
+
+// revokeReplayedFamily handles reuse of a rotated refresh token, a sign that it
+// was stolen: every token of that login is revoked so both parties must log in again.
+func (h *UserHandler) revokeReplayedFamily(ctx context.Context, stored *types.RefreshToken) {
+	slog.Warn("Refresh token reuse detected, revoking token family",
+		slog.String("user_id", stored.UserID.Hex()),
+		slog.String("family_id", stored.FamilyID.Hex()),
+	)
+	if err := h.Tokens.RevokeRefreshFamily(ctx, stored.FamilyID); err != nil {
+		slog.Error("Failed to revoke token family", slog.String("error", err.Error()))
+	}
+}
+
+// POST /logout - revokes the presented access token and, when given, the
+// refresh token of the same session.
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	slog.Info("Logout API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+	if claims.APIKeyID != "" {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "API keys have no session to log out of, revoke the key instead")
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.Tokens.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		slog.Error("Failed to revoke access token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to log out: "+err.Error())
+		return
+	}
+
+	if req.RefreshToken != "" {
+		stored, err := h.Tokens.GetRefreshToken(ctx, auth.HashToken(req.RefreshToken))
+		if err != nil {
+			slog.Error("Failed to fetch refresh token", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to log out: "+err.Error())
+			return
+		}
+		// Only the owner may end a session with its refresh token
+		if stored != nil && stored.UserID.Hex() == claims.UserID {
+			if err := h.Tokens.RevokeRefreshFamily(ctx, stored.FamilyID); err != nil {
+				slog.Error("Failed to revoke refresh token", slog.String("error", err.Error()))
+				helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to log out: "+err.Error())
+				return
+			}
+		}
+	}
+
+	slog.Info("User logged out",
+		slog.String("user_id", claims.UserID),
+		slog.String("jti", claims.ID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// DELETE /users/{id}/sessions (admin only) - immediately cuts off every
+// session and API key of a user, e.g. when an employee leaves.
+func (h *UserHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RevokeUserSessions API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	userID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid user ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.Store.GetUserByID(ctx, idStr)
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := h.Tokens.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		slog.Error("Failed to revoke user sessions", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to revoke sessions: "+err.Error())
+		return
+	}
+
+	slog.Info("User sessions revoked",
+		slog.String("user_id", idStr),
+		slog.String("revoked_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "All sessions and API keys of the user have been revoked",
+		"user_id": idStr,
+	})
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StaffHandler struct {
+	Members         storage.MembershipStore
+	UserStore       storage.UserStore
+	RestaurantStore storage.RestaurantStore
+	Authz           *authz.Authorizer
+}
+
+func NewStaffHandler(members storage.MembershipStore, userStore storage.UserStore, restaurantStore storage.RestaurantStore, az *authz.Authorizer) *StaffHandler {
+	return &StaffHandler{Members: members, UserStore: userStore, RestaurantStore: restaurantStore, Authz: az}
+}
+
+// GET /restaurants/{id}/staff
+func (h *StaffHandler) ListStaff(w http.ResponseWriter, r *http.Request) {
+	slog.Info("ListStaff API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := r.PathValue("id")
+	restaurantID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		slog.Warn("Invalid restaurant ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid restaurant ID format")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.Authz.Authorize(r.Context(), "staff:read", authz.Resource{RestaurantID: restaurantID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	staff, err := h.Members.ListRestaurantMembers(ctx, restaurantID)
+	if err != nil {
+		slog.Error("Failed to fetch restaurant staff", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch staff: "+err.Error())
+		return
+	}
+
+	slog.Info("Fetched restaurant staff successfully",
+		slog.Int("count", len(staff)),
+		slog.String("restaurant_id", idStr),
+		slog.String("requested_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":         len(staff),
+		"staff":         staff,
+		"restaurant_id": idStr,
+	})
+}
+
+// PUT /restaurants/{id}/staff/{user_id} - gives a user a staff role at the
+// restaurant or changes it.
+func (h *StaffHandler) SetStaffRole(w http.ResponseWriter, r *http.Request) {
+	slog.Info("SetStaffRole API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" validate:"required,oneof=manager cashier kitchen waiter"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Staff role validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	restaurantID, userID, ok := staffPathIDs(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !h.authorizeStaffChange(ctx, w, r, restaurantID, userID, req.Role) {
+		return
+	}
+
+	restaurant, err := h.RestaurantStore.GetByID(ctx, restaurantID.Hex())
+	if err != nil {
+		slog.Error("Failed to fetch restaurant", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch restaurant: "+err.Error())
+		return
+	}
+	if restaurant == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+
+	user, err := h.UserStore.GetUserByID(ctx, userID.Hex())
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.Role == "admin" {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Admins already have access to every restaurant")
+		return
+	}
+
+	membership, err := h.Members.SetMembership(ctx, &types.Membership{
+		UserID:       userID,
+		RestaurantID: restaurantID,
+		Role:         req.Role,
+		CreatedBy:    actorID(claims),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrDuplicate) {
+			helper.WriteSimpleError(w, http.StatusConflict, "Membership was changed concurrently, retry")
+			return
+		}
+		slog.Error("Failed to save membership", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to save membership: "+err.Error())
+		return
+	}
+
+	slog.Info("Staff role set",
+		slog.String("restaurant_id", restaurantID.Hex()),
+		slog.String("user_id", userID.Hex()),
+		slog.String("staff_role", membership.Role),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "Staff role saved successfully",
+		"membership": membership,
+	})
+}
+
+// DELETE /restaurants/{id}/staff/{user_id}
+func (h *StaffHandler) RemoveStaff(w http.ResponseWriter, r *http.Request) {
+	slog.Info("RemoveStaff API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	restaurantID, userID, ok := staffPathIDs(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !h.authorizeStaffChange(ctx, w, r, restaurantID, userID, "") {
+		return
+	}
+
+	if err := h.Members.DeleteMembership(ctx, userID, restaurantID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "User is not staff of this restaurant")
+			return
+		}
+		slog.Error("Failed to delete membership", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to remove staff: "+err.Error())
+		return
+	}
+
+	slog.Info("Staff removed",
+		slog.String("restaurant_id", restaurantID.Hex()),
+		slog.String("user_id", userID.Hex()),
+		slog.String("removed_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":       "Staff removed successfully",
+		"restaurant_id": restaurantID.Hex(),
+		"user_id":       userID.Hex(),
+	})
+}
+
+// GET /me/memberships - the restaurants the caller works at
+func (h *StaffHandler) GetMyMemberships(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetMyMemberships API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		slog.Error("Invalid user_id in token", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid user ID in token: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	memberships, err := h.Members.ListUserMemberships(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to fetch memberships", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch memberships: "+err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(memberships),
+		"memberships": memberships,
+	})
+}
+
+// authorizeStaffChange checks that the caller may give the target user role
+// at the restaurant, or remove them when role is empty. Appointing or
+// removing a manager needs the extra staff:appoint-manager permission.
+func (h *StaffHandler) authorizeStaffChange(ctx context.Context, w http.ResponseWriter, r *http.Request, restaurantID, userID primitive.ObjectID, role string) bool {
+	res := authz.Resource{RestaurantID: restaurantID}
+	if err := h.Authz.Authorize(r.Context(), "staff:write", res); err != nil {
+		authz.WriteError(w, err)
+		return false
+	}
+	current, err := h.Members.GetMembership(ctx, userID, restaurantID)
+	if err != nil {
+		slog.Error("Failed to look up staff membership", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to check permissions: "+err.Error())
+		return false
+	}
+	if role == types.StaffRoleManager || (current != nil && current.Role == types.StaffRoleManager) {
+		if err := h.Authz.Authorize(r.Context(), "staff:appoint-manager", res); err != nil {
+			authz.WriteError(w, err)
+			return false
+		}
+	}
+	return true
+}
+
+// staffPathIDs parses the {id} and {user_id} path wildcards.
+func staffPathIDs(w http.ResponseWriter, r *http.Request) (restaurantID, userID primitive.ObjectID, ok bool) {
+	restaurantID, err := primitive.ObjectIDFromHex(r.PathValue("id"))
+	if err != nil {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid restaurant ID format")
+		return restaurantID, userID, false
+	}
+	userID, err = primitive.ObjectIDFromHex(r.PathValue("user_id"))
+	if err != nil {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user ID format")
+		return restaurantID, userID, false
+	}
+	return restaurantID, userID, true
+}
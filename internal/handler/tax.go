@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/authz"
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+)
+
+// GET /restaurants/{id}/tax - the restaurant's tax rules
+func (h *RestaurantHandler) GetTaxPolicy(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetTaxPolicy API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restaurant, ok := h.loadRestaurant(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !h.checkVisible(w, r, restaurant) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"restaurant_id": restaurant.ID.Hex(),
+		"currency":      restaurant.Currency,
+		"tax":           restaurant.Tax,
+		"requested_by":  claims.UserID,
+	})
+}
+
+// PUT /restaurants/{id}/tax - replaces the restaurant's tax rules. Orders
+// already placed keep the taxes they were charged.
+func (h *RestaurantHandler) SetTaxPolicy(w http.ResponseWriter, r *http.Request) {
+	slog.Info("SetTaxPolicy API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Inclusive bool            `json:"inclusive"`
+		Rules     []types.TaxRule `json:"rules" validate:"max=20,unique=Name,dive"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("Tax policy validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restaurant, ok := h.loadRestaurant(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.Authz.Authorize(r.Context(), "taxes:write", authz.Resource{RestaurantID: restaurant.ID}); err != nil {
+		authz.WriteError(w, err)
+		return
+	}
+
+	policy := types.TaxPolicy{
+		Inclusive: req.Inclusive,
+		Rules:     req.Rules,
+		UpdatedAt: time.Now(),
+		UpdatedBy: actorID(claims),
+	}
+	updated, err := h.Store.SetTaxPolicy(ctx, restaurant.ID, policy)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "Restaurant not found")
+			return
+		}
+		slog.Error("Failed to save tax policy", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to save tax policy: "+err.Error())
+		return
+	}
+
+	slog.Info("Tax policy updated",
+		slog.String("restaurant_id", updated.ID.Hex()),
+		slog.Bool("inclusive", policy.Inclusive),
+		slog.Int("rules", len(policy.Rules)),
+		slog.String("updated_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":       "Tax policy updated successfully",
+		"restaurant_id": updated.ID.Hex(),
+		"tax":           updated.Tax,
+	})
+}
@@ -2,85 +2,150 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"log/slog"
 
 	"github.com/shubhamjaiswar43/restify/internal/auth"
+	"github.com/shubhamjaiswar43/restify/internal/authz"
 	"github.com/shubhamjaiswar43/restify/internal/helper"
-	"github.com/shubhamjaiswar43/restify/internal/storage/mongodb"
+	"github.com/shubhamjaiswar43/restify/internal/lockout"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserHandler struct {
-	Store       *mongodb.UserStore
+	Store       storage.UserStore
+	Tokens      storage.TokenStore
 	JWT         *auth.JWTManager
+	RefreshTTL  time.Duration
 	AdminSecret string
+	// AccountGuard throttles failed logins per account; IPGuard throttles
+	// failed logins and Admin-Secret guesses per IP.
+	AccountGuard *lockout.Guard
+	IPGuard      *lockout.Guard
+	// Accounts mails new users their email verification token.
+	Accounts *AccountHandler
+	MFA      MFAPolicy
+	// Invitations grants invited roles at signup.
+	Invitations *InvitationHandler
+	// Members and Authz back the admin user management endpoints.
+	Members storage.MembershipStore
+	Authz   *authz.Authorizer
 }
 
-func NewUserHandler(store *mongodb.UserStore, jwt *auth.JWTManager, adminSecret string) *UserHandler {
+func NewUserHandler(store storage.UserStore, tokens storage.TokenStore, jwt *auth.JWTManager, refreshTTL time.Duration, adminSecret string, accountGuard, ipGuard *lockout.Guard, accounts *AccountHandler, mfaPolicy MFAPolicy, invitations *InvitationHandler, members storage.MembershipStore, az *authz.Authorizer) *UserHandler {
 	return &UserHandler{
-		Store:       store,
-		JWT:         jwt,
-		AdminSecret: adminSecret,
+		Store:        store,
+		Tokens:       tokens,
+		JWT:          jwt,
+		RefreshTTL:   refreshTTL,
+		AdminSecret:  adminSecret,
+		AccountGuard: accountGuard,
+		IPGuard:      ipGuard,
+		Accounts:     accounts,
+		MFA:          mfaPolicy,
+		Invitations:  invitations,
+		Members:      members,
+		Authz:        az,
 	}
 }
 
-// POST /signup
+// signupRequest is the body of POST /signup. The password is read here
+// because types.User never decodes or encodes one.
+type signupRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"required,min=8"`
+	Role        string `json:"role" validate:"omitempty,oneof=admin customer"`
+	InviteToken string `json:"invite_token"`
+}
+
+// POST /signup - creates a customer account. An invite_token grants the
+// invited role instead; the Admin-Secret header only creates the first admin.
 func (h *UserHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	slog.Info("Signup API called", slog.Time("timestamp", time.Now()))
 
-	var user types.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var req signupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
 		return
 	}
 
-	if err := helper.ValidateStruct(user); err != nil {
+	if err := helper.ValidateStruct(req); err != nil {
 		slog.Warn("User validation failed", slog.String("error", err.Error()))
 		helper.WriteValidationError(w, err)
 		return
 	}
+	user := types.User{Name: req.Name, Email: types.NormalizeEmail(req.Email), Role: req.Role}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	existing, err := h.Store.GetUserByEmail(ctx, user.Email)
-	if err != nil {
-		slog.Error("Failed to check user existence", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error while checking user: "+err.Error())
-		return
-	}
-	if existing != nil {
-		slog.Warn("User tried to register with existing email", slog.String("email", user.Email))
-		helper.WriteSimpleError(w, http.StatusConflict, "Email already registered")
-		return
+	var invitation *types.Invitation
+	if req.InviteToken != "" {
+		inv, err := h.Invitations.lookupInvitation(ctx, req.InviteToken, user.Email)
+		if err != nil {
+			slog.Error("Failed to fetch invitation", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+			return
+		}
+		if inv == nil {
+			slog.Warn("Signup with invalid invitation", slog.String("email", user.Email))
+			helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid or expired invitation")
+			return
+		}
+		// The invitation decides the role; it is granted once the account exists
+		invitation = inv
+		user.Role = "customer"
 	}
 
 	if user.Role == "admin" {
 		adminKey := r.Header.Get("Admin-Secret")
 		if adminKey == "" {
 			slog.Warn("Missing Admin-Secret header for admin creation")
-			helper.WriteSimpleError(w, http.StatusUnauthorized, "Missing Admin-Secret header")
+			helper.WriteSimpleError(w, http.StatusUnauthorized, "Admins sign up with an invitation")
+			return
+		}
+		admins, _, err := h.Store.ListUsers(ctx, storage.UserFilter{Role: "admin", ListOptions: storage.ListOptions{Limit: 1}})
+		if err != nil {
+			slog.Error("Failed to look up admins", slog.String("error", err.Error()))
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+			return
+		}
+		if len(admins) > 0 {
+			slog.Warn("Admin-Secret used after bootstrap", slog.String("email", user.Email))
+			helper.WriteSimpleError(w, http.StatusForbidden, "Admin-Secret only creates the first admin, ask an admin for an invitation")
+			return
+		}
+		ip := clientIP(r)
+		if !checkThrottle(ctx, w, h.IPGuard, adminSecretKey(ip)) {
 			return
 		}
-		if adminKey != h.AdminSecret {
-			slog.Warn("Invalid Admin-Secret key used for admin signup")
+		if subtle.ConstantTimeCompare([]byte(adminKey), []byte(h.AdminSecret)) != 1 {
+			slog.Warn("Invalid Admin-Secret key used for admin signup", slog.String("ip", ip))
+			if _, _, err := h.IPGuard.Fail(ctx, adminSecretKey(ip)); err != nil {
+				slog.Error("Failed to record Admin-Secret failure", slog.String("error", err.Error()))
+			}
 			helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid Admin-Secret key")
 			return
 		}
+		h.IPGuard.Reset(ctx, adminSecretKey(ip))
+		slog.Info("Bootstrapping first admin", slog.String("email", user.Email), slog.String("ip", ip))
 	}
 
 	if user.Role == "" {
 		user.Role = "customer"
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		slog.Error("Failed to hash password", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to hash password: "+err.Error())
@@ -89,10 +154,26 @@ func (h *UserHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	user.Password = string(hashed)
 
 	user.ID = primitive.NewObjectID()
+	user.EmailVerified = false
+	user.EmailVerifiedAt = nil
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	if err := h.Store.CreateUser(ctx, &user); err != nil {
+	create := h.Store.CreateUser
+	if user.Role == "admin" {
+		create = h.Store.CreateFirstAdmin
+	}
+	if err := create(ctx, &user); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			slog.Warn("Admin-Secret used after bootstrap", slog.String("email", user.Email))
+			helper.WriteSimpleError(w, http.StatusForbidden, "Admin-Secret only creates the first admin, ask an admin for an invitation")
+			return
+		}
+		if errors.Is(err, storage.ErrDuplicate) {
+			slog.Warn("User tried to register with existing email", slog.String("email", user.Email))
+			helper.WriteSimpleError(w, http.StatusConflict, "Email already registered")
+			return
+		}
 		slog.Error("Failed to create user in DB", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to create user: "+err.Error())
 		return
@@ -105,6 +186,33 @@ func (h *UserHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		slog.Time("timestamp", time.Now()),
 	)
 
+	if invitation != nil {
+		if err := h.Invitations.redeem(ctx, invitation, &user); err != nil {
+			slog.Error("Failed to grant invited role",
+				slog.String("user_id", user.ID.Hex()),
+				slog.String("error", err.Error()),
+			)
+			helper.WriteSimpleError(w, http.StatusInternalServerError, "User created but the invitation could not be applied, accept it after logging in: "+err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":       "User created successfully",
+			"user_id":       user.ID.Hex(),
+			"role":          invitation.Role,
+			"restaurant_id": invitation.RestaurantID,
+		})
+		return
+	}
+
+	// The account works without it, so a mail failure does not fail the signup
+	if err := h.Accounts.sendVerificationEmail(ctx, &user); err != nil {
+		slog.Error("Failed to send verification email",
+			slog.String("user_id", user.ID.Hex()),
+			slog.String("error", err.Error()),
+		)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "User created successfully",
@@ -132,10 +240,17 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		helper.WriteValidationError(w, err)
 		return
 	}
+	req.Email = types.NormalizeEmail(req.Email)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Locked or backing-off accounts and IPs are refused before any password check
+	ip := clientIP(r)
+	if !checkThrottle(ctx, w, h.IPGuard, loginIPKey(ip)) || !checkThrottle(ctx, w, h.AccountGuard, accountKey(req.Email)) {
+		return
+	}
+
 	user, err := h.Store.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
@@ -143,20 +258,38 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if user == nil {
-		slog.Warn("Invalid login attempt", slog.String("email", req.Email))
+		slog.Warn("Invalid login attempt", slog.String("email", req.Email), slog.String("ip", ip))
+		h.recordLoginFailure(ctx, req.Email, ip, nil)
 		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
+	// The identity provider decides whether linked accounts may still log in,
+	// so their password is never checked and a guess cannot lock them out
+	if len(user.Identities) > 0 {
+		slog.Warn("Password login for a linked account", slog.String("user_id", user.ID.Hex()))
+		helper.WriteSimpleError(w, http.StatusForbidden, "This account logs in through single sign-on")
+		return
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		slog.Warn("Invalid password", slog.String("email", req.Email))
+		slog.Warn("Invalid password", slog.String("email", req.Email), slog.String("ip", ip))
+		h.recordLoginFailure(ctx, req.Email, ip, user)
 		helper.WriteSimpleError(w, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
+	if err := h.AccountGuard.Reset(ctx, accountKey(req.Email)); err != nil {
+		slog.Error("Failed to reset login failures", slog.String("error", err.Error()))
+	}
+
+	if user.MFA.Enabled || h.MFA.required(user.Role) {
+		h.writeMFAChallenge(w, user)
+		return
+	}
 
-	token, err := h.JWT.Generate(user.ID.Hex(), user.Role)
+	tokens, err := h.issueTokens(ctx, user, primitive.NewObjectID())
 	if err != nil {
-		slog.Error("Failed to generate JWT", slog.String("error", err.Error()))
+		slog.Error("Failed to generate tokens", slog.String("error", err.Error()))
 		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to generate token: "+err.Error())
 		return
 	}
@@ -167,36 +300,33 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		slog.Time("timestamp", time.Now()),
 	)
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"token": token,
-		"role":  user.Role,
-	})
+	json.NewEncoder(w).Encode(tokens)
 }
 
-// GET /users (admin only)
+// GET /users?role=&email=&limit=&cursor=&sort= (requires users:read)
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetAllUsers API called", slog.Time("timestamp", time.Now()))
 
-	claims, ok := r.Context().Value("claims").(*auth.Claims)
+	claims, ok := requestClaims(w, r)
 	if !ok {
-		slog.Error("Failed to read claims from context")
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to read user info from context")
 		return
 	}
 
-	if claims.Role != "admin" {
-		slog.Warn("Unauthorized access attempt", slog.String("user_id", claims.UserID))
-		helper.WriteSimpleError(w, http.StatusForbidden, "Unauthorized access — admin only")
+	q := r.URL.Query()
+	listOpts, err := parseListOptions(q)
+	if err != nil {
+		slog.Warn("Invalid user query", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	filter := storage.UserFilter{ListOptions: listOpts, Role: q.Get("role"), Email: types.NormalizeEmail(q.Get("email"))}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	users, err := h.Store.GetAllUsers(ctx)
+	users, next, err := h.Store.ListUsers(ctx, filter)
 	if err != nil {
-		slog.Error("Failed to fetch users", slog.String("error", err.Error()))
-		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to fetch users: "+err.Error())
+		writeListError(w, "users", err)
 		return
 	}
 
@@ -206,5 +336,186 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		slog.Time("timestamp", time.Now()),
 	)
 
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(map[string]any{
+		"count":       len(users),
+		"users":       users,
+		"next_cursor": next,
+	})
+}
+
+// pathUser loads the user named by the {id} path value, writing the error
+// response when the ID is malformed or unknown.
+func (h *UserHandler) pathUser(ctx context.Context, w http.ResponseWriter, r *http.Request) (*types.User, bool) {
+	idStr := r.PathValue("id")
+	if _, err := primitive.ObjectIDFromHex(idStr); err != nil {
+		slog.Warn("Invalid user ID", slog.String("id", idStr))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid user ID format")
+		return nil, false
+	}
+	user, err := h.Store.GetUserByID(ctx, idStr)
+	if err != nil {
+		slog.Error("Failed to fetch user from DB", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Database error: "+err.Error())
+		return nil, false
+	}
+	if user == nil {
+		helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+		return nil, false
+	}
+	return user, true
+}
+
+// GET /users/{id} (requires users:read)
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	slog.Info("GetUser API called", slog.Time("timestamp", time.Now()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, ok := h.pathUser(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// PATCH /users/{id} - edits another user's name, email or role (requires
+// users:write, plus users:change-role for the role)
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	slog.Info("UpdateUser API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name  *string `json:"name" validate:"omitempty,min=2,max=100"`
+		Email *string `json:"email" validate:"omitempty,email"`
+		Role  *string `json:"role" validate:"omitempty,oneof=admin customer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Invalid JSON body", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := helper.ValidateStruct(req); err != nil {
+		slog.Warn("User validation failed", slog.String("error", err.Error()))
+		helper.WriteValidationError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, ok := h.pathUser(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	roleChanged := req.Role != nil && *req.Role != user.Role
+	if roleChanged {
+		if err := h.Authz.Authorize(r.Context(), "users:change-role", authz.Resource{}); err != nil {
+			authz.WriteError(w, err)
+			return
+		}
+		// An admin demoting themselves could leave nobody able to undo it
+		if user.ID.Hex() == claims.UserID {
+			helper.WriteSimpleError(w, http.StatusBadRequest, "You cannot change your own role")
+			return
+		}
+		user.Role = *req.Role
+	}
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	emailChanged := req.Email != nil && types.NormalizeEmail(*req.Email) != user.Email
+	if emailChanged {
+		user.Email = types.NormalizeEmail(*req.Email)
+	}
+
+	updated, ok := h.saveUser(ctx, w, user, emailChanged)
+	if !ok {
+		return
+	}
+
+	// Access tokens carry the role, so the old ones must not outlive it
+	if roleChanged {
+		if err := h.Tokens.RevokeUserTokens(ctx, user.ID, time.Now()); err != nil {
+			slog.Error("Failed to revoke sessions after role change", slog.String("error", err.Error()))
+		}
+	}
+
+	slog.Info("User updated",
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("updated_by", claims.UserID),
+		slog.Bool("role_changed", roleChanged),
+		slog.Bool("email_changed", emailChanged),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DELETE /users/{id} - removes the account, its sessions and its staff
+// memberships (requires users:delete)
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	slog.Info("DeleteUser API called", slog.Time("timestamp", time.Now()))
+
+	claims, ok := requestClaims(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, ok := h.pathUser(ctx, w, r)
+	if !ok {
+		return
+	}
+	if user.ID.Hex() == claims.UserID {
+		helper.WriteSimpleError(w, http.StatusBadRequest, "You cannot delete your own account")
+		return
+	}
+
+	if err := h.Store.DeleteUser(ctx, user.ID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			helper.WriteSimpleError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		slog.Error("Failed to delete user", slog.String("error", err.Error()))
+		helper.WriteSimpleError(w, http.StatusInternalServerError, "Failed to delete user: "+err.Error())
+		return
+	}
+
+	// The account is gone either way; leftovers are logged rather than reported
+	if err := h.Tokens.RevokeUserTokens(ctx, user.ID, time.Now()); err != nil {
+		slog.Error("Failed to revoke sessions of deleted user", slog.String("error", err.Error()))
+	}
+	memberships, err := h.Members.ListUserMemberships(ctx, user.ID)
+	if err != nil {
+		slog.Error("Failed to list memberships of deleted user", slog.String("error", err.Error()))
+	}
+	for _, m := range memberships {
+		if err := h.Members.DeleteMembership(ctx, m.UserID, m.RestaurantID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			slog.Error("Failed to delete membership of deleted user",
+				slog.String("restaurant_id", m.RestaurantID.Hex()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	slog.Info("User deleted",
+		slog.String("user_id", user.ID.Hex()),
+		slog.String("email", user.Email),
+		slog.String("deleted_by", claims.UserID),
+		slog.Time("timestamp", time.Now()),
+	)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "User deleted successfully",
+		"user_id": user.ID.Hex(),
+	})
 }
@@ -5,17 +5,33 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/shubhamjaiswar43/restify/internal/types"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Money validates as its amount, so tags like gt=0 apply to the minor units
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		return field.Interface().(types.Money).Amount
+	}, types.Money{})
+	return v
+}
 
 // ValidateStruct validates a struct.
 func ValidateStruct(s interface{}) error {
 	return validate.Struct(s)
 }
 
+// ValidateVar validates a single value against tag, e.g. "iso4217".
+func ValidateVar(field any, tag string) error {
+	return validate.Var(field, tag)
+}
+
 // ValidateStructExcept validates a struct except specify keys.
 func ValidateStructExcept(s interface{}, exceptKeys ...string) error {
 	return validate.StructExcept(s, exceptKeys...)
@@ -31,6 +47,8 @@ func WriteValidationError(w http.ResponseWriter, err error) {
 			switch e.Tag() {
 			case "required":
 				errorsMap[field] = fmt.Sprintf("%s is required", field)
+			case "required_without":
+				errorsMap[field] = fmt.Sprintf("%s is required when %s is missing", field, e.Param())
 			case "email":
 				errorsMap[field] = fmt.Sprintf("%s must be a valid email address", field)
 			case "min":
@@ -39,6 +57,10 @@ func WriteValidationError(w http.ResponseWriter, err error) {
 				errorsMap[field] = fmt.Sprintf("%s must not exceed %s characters", field, e.Param())
 			case "oneof":
 				errorsMap[field] = fmt.Sprintf("%s must be one of: %s", field, e.Param())
+			case "gt":
+				errorsMap[field] = fmt.Sprintf("%s must be greater than %s", field, e.Param())
+			case "iso4217":
+				errorsMap[field] = fmt.Sprintf("%s must be an ISO 4217 currency code", field)
 			default:
 				errorsMap[field] = fmt.Sprintf("%s is invalid (%s)", field, e.Tag())
 			}
@@ -0,0 +1,104 @@
+// Package lockout throttles repeated failures, such as wrong passwords, per
+// key: a delay that doubles with every failure, then a temporary lockout.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Record is the failure state of one key, e.g. an account or a client IP.
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store keeps records. Implementations must apply Update atomically per key
+// so concurrent failures are all counted.
+type Store interface {
+	Get(ctx context.Context, key string) (Record, error)
+	// Update applies fn to the record of key, a zero Record if there is none,
+	// stores the result and returns it.
+	Update(ctx context.Context, key string, fn func(*Record)) (Record, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Policy configures a Guard.
+type Policy struct {
+	// MaxFailures locks the key out once reached.
+	MaxFailures int
+	// LockoutDuration is how long a lockout lasts. Failures older than this
+	// are forgotten as well.
+	LockoutDuration time.Duration
+	// BackoffBase is the wait after the first failure, doubling with each
+	// further one up to BackoffMax. Zero disables the backoff.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Guard applies a Policy to the records in a Store.
+type Guard struct {
+	Store  Store
+	Policy Policy
+}
+
+func NewGuard(store Store, policy Policy) *Guard {
+	return &Guard{Store: store, Policy: policy}
+}
+
+// Check returns how long the caller must wait before the next attempt for
+// key, or zero when an attempt is allowed now.
+func (g *Guard) Check(ctx context.Context, key string) (time.Duration, error) {
+	rec, err := g.Store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if now.Before(rec.LockedUntil) {
+		return rec.LockedUntil.Sub(now), nil
+	}
+	if rec.Failures > 0 {
+		if next := rec.LastFailure.Add(g.backoff(rec.Failures)); now.Before(next) {
+			return next.Sub(now), nil
+		}
+	}
+	return 0, nil
+}
+
+// Fail counts a failed attempt for key. locked reports whether this failure
+// started a lockout.
+func (g *Guard) Fail(ctx context.Context, key string) (rec Record, locked bool, err error) {
+	now := time.Now()
+	rec, err = g.Store.Update(ctx, key, func(r *Record) {
+		switch {
+		case !r.LockedUntil.IsZero() && now.After(r.LockedUntil):
+			*r = Record{} // the lockout is over, count afresh
+		case r.LockedUntil.IsZero() && now.Sub(r.LastFailure) > g.Policy.LockoutDuration:
+			*r = Record{} // old failures are forgotten
+		}
+		r.Failures++
+		r.LastFailure = now
+		if r.Failures >= g.Policy.MaxFailures && r.LockedUntil.IsZero() {
+			r.LockedUntil = now.Add(g.Policy.LockoutDuration)
+			locked = true
+		}
+	})
+	return rec, locked, err
+}
+
+// Reset forgets every failure of key, ending a lockout.
+func (g *Guard) Reset(ctx context.Context, key string) error {
+	return g.Store.Delete(ctx, key)
+}
+
+func (g *Guard) backoff(failures int) time.Duration {
+	if g.Policy.BackoffBase <= 0 {
+		return 0
+	}
+	d := g.Policy.BackoffBase
+	for i := 1; i < failures && d < g.Policy.BackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, g.Policy.BackoffMax)
+}
@@ -0,0 +1,136 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestGuard() *Guard {
+	return NewGuard(NewMemoryStore(time.Hour), Policy{
+		MaxFailures:     5,
+		LockoutDuration: 15 * time.Minute,
+		BackoffBase:     time.Second,
+		BackoffMax:      8 * time.Second,
+	})
+}
+
+// age moves the failures of key into the past, as if d had passed.
+func age(t *testing.T, g *Guard, key string, d time.Duration) {
+	t.Helper()
+	_, err := g.Store.Update(context.Background(), key, func(r *Record) {
+		r.LastFailure = r.LastFailure.Add(-d)
+		if !r.LockedUntil.IsZero() {
+			r.LockedUntil = r.LockedUntil.Add(-d)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// near reports whether the wait got is d, give or take the test's runtime.
+func near(got, d time.Duration) bool {
+	return got <= d && got > d-time.Second/2
+}
+
+func TestGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGuard()
+
+	if wait, _ := g.Check(ctx, "k"); wait != 0 {
+		t.Fatalf("Check before any failure = %v, want 0", wait)
+	}
+	for i, want := range []time.Duration{1, 2, 4, 8} {
+		if _, locked, _ := g.Fail(ctx, "k"); locked {
+			t.Fatalf("failure %d locked the key out", i+1)
+		}
+		if wait, _ := g.Check(ctx, "k"); !near(wait, want*time.Second) {
+			t.Fatalf("after %d failures Check = %v, want %v", i+1, wait, want*time.Second)
+		}
+		// Once the backoff has passed the next attempt is allowed
+		age(t, g, "k", want*time.Second)
+		if wait, _ := g.Check(ctx, "k"); wait != 0 {
+			t.Fatalf("after the backoff Check = %v, want 0", wait)
+		}
+	}
+	if wait, _ := g.Check(ctx, "other"); wait != 0 {
+		t.Fatalf("another key is throttled: %v", wait)
+	}
+}
+
+func TestGuardBackoffCap(t *testing.T) {
+	g := newTestGuard()
+	g.Policy.MaxFailures = 100
+	for failures, want := range map[int]time.Duration{1: 1, 3: 4, 4: 8, 5: 8, 50: 8} {
+		if got := g.backoff(failures); got != want*time.Second {
+			t.Errorf("backoff(%d) = %v, want %v", failures, got, want*time.Second)
+		}
+	}
+	g.Policy.BackoffBase = 0
+	if got := g.backoff(3); got != 0 {
+		t.Errorf("backoff without a base = %v, want 0", got)
+	}
+}
+
+func TestGuardLockout(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGuard()
+
+	for i := 1; i <= 5; i++ {
+		_, locked, err := g.Fail(ctx, "k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != (i == 5) {
+			t.Fatalf("failure %d: locked = %v", i, locked)
+		}
+	}
+	if wait, _ := g.Check(ctx, "k"); !near(wait, 15*time.Minute) {
+		t.Fatalf("locked out Check = %v, want 15m", wait)
+	}
+	// Failing again while locked out does not extend the lockout
+	if _, locked, _ := g.Fail(ctx, "k"); locked {
+		t.Fatal("failure during the lockout started another one")
+	}
+
+	// After the lockout failures are counted afresh
+	age(t, g, "k", 15*time.Minute+time.Second)
+	if wait, _ := g.Check(ctx, "k"); wait != 0 {
+		t.Fatalf("Check after the lockout = %v, want 0", wait)
+	}
+	rec, locked, _ := g.Fail(ctx, "k")
+	if locked || rec.Failures != 1 {
+		t.Fatalf("first failure after the lockout: %+v, locked %v", rec, locked)
+	}
+}
+
+func TestGuardForgetsOldFailures(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGuard()
+	for i := 0; i < 4; i++ {
+		g.Fail(ctx, "k")
+	}
+	age(t, g, "k", 16*time.Minute)
+	rec, locked, _ := g.Fail(ctx, "k")
+	if locked || rec.Failures != 1 {
+		t.Fatalf("failure after a quiet period: %+v, locked %v", rec, locked)
+	}
+}
+
+func TestGuardReset(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGuard()
+	for i := 0; i < 5; i++ {
+		g.Fail(ctx, "k")
+	}
+	if err := g.Reset(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := g.Check(ctx, "k"); wait != 0 {
+		t.Fatalf("Check after Reset = %v, want 0", wait)
+	}
+	if rec, _, _ := g.Fail(ctx, "k"); rec.Failures != 1 {
+		t.Fatalf("failures after Reset = %d, want 1", rec.Failures)
+	}
+}
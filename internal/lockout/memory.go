@@ -0,0 +1,62 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in process memory, so every instance of the
+// service counts failures on its own.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	maxAge  time.Duration
+	pruned  time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty store that forgets records untouched for maxAge.
+func NewMemoryStore(maxAge time.Duration) *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record), maxAge: maxAge}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(*Record)) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	rec := s.records[key]
+	fn(&rec)
+	s.records[key] = rec
+	return rec, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// prune drops stale records, at most once a minute, so random keys cannot
+// grow the map forever. The caller must hold the lock.
+func (s *MemoryStore) prune() {
+	now := time.Now()
+	if now.Sub(s.pruned) < time.Minute {
+		return
+	}
+	s.pruned = now
+	cutoff := now.Add(-s.maxAge)
+	for key, rec := range s.records {
+		if rec.LastFailure.Before(cutoff) && rec.LockedUntil.Before(cutoff) {
+			delete(s.records, key)
+		}
+	}
+}
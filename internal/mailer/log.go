@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogMailer is meant for local development: it logs every message instead of
+// sending it and, when Dir is set, also writes it there as an .eml file.
+type LogMailer struct {
+	From string
+	Dir  string
+}
+
+var _ Mailer = (*LogMailer)(nil)
+
+func NewLogMailer(from, dir string) *LogMailer {
+	return &LogMailer{
+		From: from,
+		Dir:  dir,
+	}
+}
+
+// Send logs msg and optionally stores it in Dir.
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.Info("Email logged instead of sent",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	if m.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To))
+	if err := os.WriteFile(filepath.Join(m.Dir, name), format(m.From, msg), 0o644); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	return nil
+}
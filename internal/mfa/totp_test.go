@@ -0,0 +1,112 @@
+package mfa
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, truncated to the last six digits
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	} {
+		got, err := Code(rfcSecret, Step(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tc.unix, err)
+		}
+		if got != tc.want {
+			t.Errorf("Code at %d = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+
+	lower, _ := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	upper, _ := Code(rfcSecret, 1)
+	if lower != upper {
+		t.Error("Code depends on the case of the secret")
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	for _, tc := range []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(current), current, true},
+		{"previous step", code(current - 1), current - 1, true},
+		{"next step", code(current + 1), current + 1, true},
+		{"with spaces", code(current)[:3] + " " + code(current)[3:], current, true},
+		{"two steps ago", code(current - 2), 0, false},
+		{"two steps ahead", code(current + 2), 0, false},
+		{"too short", code(current)[:5], 0, false},
+		{"wrong code", "000000", 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tc.code, now)
+			if ok != tc.wantOK || step != tc.wantStep {
+				t.Fatalf("Validate = %d, %v, want %d, %v", step, ok, tc.wantStep, tc.wantOK)
+			}
+		})
+	}
+
+	if _, ok := Validate("not base32!", "123456", now); ok {
+		t.Error("Validate accepted a code for an invalid secret")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret %q is not 160 bits of base32", secret)
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("generated secret is unusable: %v", err)
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes", len(codes), len(hashes))
+	}
+	code := codes[0]
+	for _, typed := range []string{code, " " + code[:5] + code[6:] + " ", "  " + code[:2] + " " + code[2:]} {
+		if HashRecoveryCode(typed) != hashes[0] {
+			t.Errorf("%q does not match recovery code %q", typed, code)
+		}
+	}
+	if HashRecoveryCode(codes[1]) == hashes[0] {
+		t.Error("two recovery codes share a hash")
+	}
+}
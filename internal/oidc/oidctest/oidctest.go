@@ -0,0 +1,221 @@
+// Package oidctest runs an OpenID Connect identity provider for tests. It
+// serves discovery, a JWKS and a token endpoint that enforces PKCE S256 and
+// single-use codes; logging in is done by Authorize instead of a login page.
+package oidctest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shubhamjaiswar43/restify/internal/auth"
+)
+
+// Provider is the identity provider. Discovery is served from Discovery, so
+// tests can break the document before the client first fetches it.
+type Provider struct {
+	*httptest.Server
+	ClientID  string
+	Discovery map[string]string
+
+	mu     sync.Mutex
+	keys   []signingKey
+	grants map[string]grant
+	nextID int
+}
+
+type signingKey struct {
+	id   string
+	priv ed25519.PrivateKey
+}
+
+// grant is an authorization code waiting to be redeemed.
+type grant struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+// NewProvider starts a provider for clientID, closed when the test ends.
+func NewProvider(t testing.TB, clientID string) *Provider {
+	t.Helper()
+	p := &Provider{ClientID: clientID, grants: make(map[string]grant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.serveDiscovery)
+	mux.HandleFunc("GET /jwks", p.serveJWKS)
+	mux.HandleFunc("POST /token", p.serveToken)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	p.Discovery = map[string]string{
+		"issuer":                 p.URL,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	}
+	p.RotateKey(t)
+	return p
+}
+
+// RotateKey replaces the signing key; tokens signed before are no longer
+// verifiable against the JWKS.
+func (p *Provider) RotateKey(t testing.TB) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	p.keys = []signingKey{{id: "key-" + strconv.Itoa(p.nextID), priv: priv}}
+}
+
+// Claims returns the claims of an ID token from this provider for subject,
+// valid for five minutes.
+func (p *Provider) Claims(subject, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   p.URL,
+		"aud":   p.ClientID,
+		"sub":   subject,
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+// Sign signs claims with the current key.
+func (p *Provider) Sign(t testing.TB, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := p.sign(claims)
+	if err != nil {
+		t.Fatalf("sign ID token: %v", err)
+	}
+	return signed
+}
+
+func (p *Provider) sign(claims jwt.MapClaims) (string, error) {
+	p.mu.Lock()
+	key := p.keys[len(p.keys)-1]
+	p.mu.Unlock()
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["kid"] = key.id
+	return tok.SignedString(key.priv)
+}
+
+// Authorize plays a user logging in at authURL, which must be a valid
+// authorization request with a PKCE S256 challenge. The ID token of the
+// returned code carries the default claims for subject overlaid with claims,
+// so a test can set e.g. "email" or replace "nonce".
+func (p *Provider) Authorize(t testing.TB, authURL, subject string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	q := u.Query()
+	if got := u.Scheme + "://" + u.Host + u.Path; got != p.Discovery["authorization_endpoint"] {
+		t.Fatalf("authorization request sent to %s", got)
+	}
+	if q.Get("response_type") != "code" || q.Get("client_id") != p.ClientID {
+		t.Fatalf("bad authorization request: %s", q.Encode())
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without PKCE S256: %s", q.Encode())
+	}
+	if q.Get("state") == "" || q.Get("nonce") == "" {
+		t.Fatalf("authorization request without state or nonce: %s", q.Encode())
+	}
+
+	idClaims := p.Claims(subject, q.Get("nonce"))
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+	code = rand.Text()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grants[code] = grant{
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+		claims:      idClaims,
+	}
+	return code, q.Get("state")
+}
+
+func (p *Provider) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(p.Discovery)
+}
+
+func (p *Provider) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	set := auth.JWKSet{Keys: []auth.JWK{}}
+	for _, k := range p.keys {
+		set.Keys = append(set.Keys, auth.JWK{
+			KeyType:   "OKP",
+			KeyID:     k.id,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(k.priv.Public().(ed25519.PublicKey)),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+// serveToken redeems a code once, provided the verifier matches its challenge.
+func (p *Provider) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID := r.PostForm.Get("client_id")
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(id)
+	}
+	if clientID != p.ClientID {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	p.mu.Lock()
+	g, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := p.sign(g.claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shubhamjaiswar43/restify/internal/oidc/oidctest"
+)
+
+const testClientID = "restify"
+
+func newTestProvider(idp *oidctest.Provider) *Provider {
+	return NewProvider(Config{
+		IssuerURL:   idp.URL,
+		ClientID:    testClientID,
+		RedirectURL: "https://restify.test/oidc/callback",
+		Scopes:      []string{"openid", "email"},
+		GroupsClaim: "groups",
+		HTTPClient:  idp.Client(),
+	})
+}
+
+func TestDiscovery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("authorization request", func(t *testing.T) {
+		idp := oidctest.NewProvider(t, testClientID)
+		p := newTestProvider(idp)
+
+		authURL, err := p.AuthCodeURL(ctx, "the-state", "the-nonce", CodeChallenge("the-verifier"))
+		if err != nil {
+			t.Fatalf("AuthCodeURL: %v", err)
+		}
+		u, _ := url.Parse(authURL)
+		q := u.Query()
+		if !strings.HasPrefix(authURL, idp.URL+"/authorize?") {
+			t.Errorf("authorization URL %s is not the discovered endpoint", authURL)
+		}
+		if q.Get("state") != "the-state" || q.Get("nonce") != "the-nonce" {
+			t.Errorf("state or nonce not passed on: %s", q.Encode())
+		}
+		if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") != CodeChallenge("the-verifier") {
+			t.Errorf("PKCE challenge not passed on: %s", q.Encode())
+		}
+	})
+
+	for _, tc := range []struct {
+		name  string
+		field string
+		value string
+	}{
+		{"issuer mismatch", "issuer", "https://evil.test"},
+		{"missing token endpoint", "token_endpoint", ""},
+		{"missing jwks", "jwks_uri", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			idp := oidctest.NewProvider(t, testClientID)
+			idp.Discovery[tc.field] = tc.value
+			if _, err := newTestProvider(idp).AuthCodeURL(ctx, "s", "n", "c"); err == nil {
+				t.Fatal("AuthCodeURL accepted a bad discovery document")
+			}
+		})
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Fatalf("CodeChallenge = %s, want %s", got, want)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewProvider(t, testClientID)
+	p := newTestProvider(idp)
+
+	login := func() (code string) {
+		authURL, err := p.AuthCodeURL(ctx, "state", "nonce", CodeChallenge("verifier"))
+		if err != nil {
+			t.Fatalf("AuthCodeURL: %v", err)
+		}
+		code, _ = idp.Authorize(t, authURL, "alice", nil)
+		return code
+	}
+
+	if _, err := p.Exchange(ctx, login(), "another-verifier"); err == nil {
+		t.Error("Exchange succeeded with a verifier not matching the challenge")
+	}
+
+	code := login()
+	raw, err := p.Exchange(ctx, code, "verifier")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := p.Verify(ctx, raw, "nonce"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := p.Exchange(ctx, code, "verifier"); err == nil {
+		t.Error("Exchange redeemed a code twice")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewProvider(t, testClientID)
+	p := newTestProvider(idp)
+
+	for _, tc := range []struct {
+		name   string
+		edit   func(c jwt.MapClaims)
+		wantOK bool
+	}{
+		{"valid", func(c jwt.MapClaims) {}, true},
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "replayed" }, false},
+		{"missing nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, false},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "another-client" }, false},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.test" }, false},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, false},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, false},
+		{"several audiences without azp", func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "other"} }, false},
+		{"several audiences, azp of another client", func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+			c["azp"] = "other"
+		}, false},
+		{"several audiences, azp of this client", func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+			c["azp"] = testClientID
+		}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := idp.Claims("alice", "nonce")
+			tc.edit(claims)
+			_, err := p.Verify(ctx, idp.Sign(t, claims), "nonce")
+			if (err == nil) != tc.wantOK {
+				t.Fatalf("Verify error = %v, want ok %v", err, tc.wantOK)
+			}
+		})
+	}
+
+	t.Run("claims", func(t *testing.T) {
+		claims := idp.Claims("alice", "nonce")
+		claims["email"] = "Alice@Example.com"
+		claims["email_verified"] = "true"
+		claims["groups"] = []string{"staff", "admins"}
+		tok, err := p.Verify(ctx, idp.Sign(t, claims), "nonce")
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if tok.Issuer != idp.URL || tok.Subject != "alice" || tok.Email != "Alice@Example.com" || !tok.EmailVerified {
+			t.Errorf("token = %+v", tok)
+		}
+		if len(tok.Groups) != 2 || tok.Groups[1] != "admins" {
+			t.Errorf("groups = %v", tok.Groups)
+		}
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewProvider(t, testClientID)
+	p := newTestProvider(idp)
+
+	if _, err := p.Verify(ctx, idp.Sign(t, idp.Claims("alice", "nonce")), "nonce"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	idp.RotateKey(t)
+	rotated := idp.Sign(t, idp.Claims("alice", "nonce"))
+	// The JWKS was just fetched, so the unknown kid does not trigger a refetch yet
+	if _, err := p.Verify(ctx, rotated, "nonce"); err == nil {
+		t.Fatal("Verify refetched the JWKS within keyRefreshInterval")
+	}
+
+	p.mu.Lock()
+	p.keysFetched = time.Now().Add(-keyRefreshInterval)
+	p.mu.Unlock()
+	if _, err := p.Verify(ctx, rotated, "nonce"); err != nil {
+		t.Fatalf("Verify after rotation: %v", err)
+	}
+}
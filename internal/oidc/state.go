@@ -0,0 +1,99 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// AuthState is what a login remembers until the provider redirects back.
+type AuthState struct {
+	Nonce string
+	// Verifier is the PKCE code verifier sent with the code exchange.
+	Verifier  string
+	ExpiresAt time.Time
+}
+
+// StateStore keeps pending logins keyed by their state parameter.
+type StateStore interface {
+	Save(ctx context.Context, state string, s AuthState) error
+	// Take returns and forgets the login of state, so each state works once.
+	// It returns nil for an unknown or expired state.
+	Take(ctx context.Context, state string) (*AuthState, error)
+}
+
+// NewLogin returns a fresh state parameter and the AuthState to save for it,
+// along with the PKCE S256 challenge to send to the provider.
+func NewLogin(ttl time.Duration) (state, challenge string, s AuthState, err error) {
+	if state, err = randomString(); err != nil {
+		return "", "", s, err
+	}
+	if s.Nonce, err = randomString(); err != nil {
+		return "", "", s, err
+	}
+	if s.Verifier, err = randomString(); err != nil {
+		return "", "", s, err
+	}
+	s.ExpiresAt = time.Now().Add(ttl)
+	return state, CodeChallenge(s.Verifier), s, nil
+}
+
+// CodeChallenge derives the PKCE S256 challenge of verifier (RFC 7636).
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns 256 random bits, URL-safe encoded; as a PKCE verifier
+// its 43 characters are within the allowed 43 to 128.
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// MemoryStateStore keeps pending logins in process memory. Behind several
+// instances the callback must reach the instance that started the login.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]AuthState
+}
+
+var _ StateStore = (*MemoryStateStore)(nil)
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[string]AuthState)}
+}
+
+func (m *MemoryStateStore) Save(ctx context.Context, state string, s AuthState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Abandoned logins are dropped here, so the map cannot grow without bound
+	now := time.Now()
+	for k, v := range m.states {
+		if !now.Before(v.ExpiresAt) {
+			delete(m.states, k)
+		}
+	}
+	m.states[state] = s
+	return nil
+}
+
+func (m *MemoryStateStore) Take(ctx context.Context, state string) (*AuthState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.states, state)
+	if !time.Now().Before(s.ExpiresAt) {
+		return nil, nil
+	}
+	return &s, nil
+}
@@ -0,0 +1,69 @@
+package pricing
+
+import (
+	"fmt"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applyOptions checks the options selected on an order line against the
+// item's option groups, snapshots them and returns the line's unit price
+// with their price deltas added to price.
+func applyOptions(item *types.MenuItem, line *types.OrderItem, price types.Money, currency string) (types.Money, error) {
+	fail := func(format string, args ...any) error {
+		return &ItemError{MenuItemID: item.ID, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	type choice struct {
+		group  *types.OptionGroup
+		option *types.Option
+	}
+	options := make(map[primitive.ObjectID]choice)
+	for gi := range item.OptionGroups {
+		group := &item.OptionGroups[gi]
+		for oi := range group.Options {
+			options[group.Options[oi].ID] = choice{group: group, option: &group.Options[oi]}
+		}
+	}
+
+	counts := make(map[primitive.ObjectID]int)
+	seen := make(map[primitive.ObjectID]bool)
+	for i := range line.Options {
+		sel := &line.Options[i]
+		c, ok := options[sel.OptionID]
+		if !ok || c.group.ID != sel.GroupID {
+			return types.Money{}, fail("has no option %s in group %s", sel.OptionID.Hex(), sel.GroupID.Hex())
+		}
+		if seen[sel.OptionID] {
+			return types.Money{}, fail("has option %q selected more than once", c.option.Name)
+		}
+		seen[sel.OptionID] = true
+		counts[c.group.ID]++
+
+		delta, err := c.option.PriceDelta.In(currency)
+		if err != nil {
+			return types.Money{}, fmt.Errorf("menu item %s option %s: %w", item.ID.Hex(), c.option.ID.Hex(), err)
+		}
+		sel.Group = c.group.Name
+		sel.Name = c.option.Name
+		sel.PriceDelta = delta
+		if price, err = price.Add(delta); err != nil {
+			return types.Money{}, fmt.Errorf("%w: menu item %s: %w", ErrOrderTooLarge, item.ID.Hex(), err)
+		}
+	}
+
+	for _, group := range item.OptionGroups {
+		n := counts[group.ID]
+		if least := group.MinRequired(); n < least {
+			return types.Money{}, fail("needs at least %d option(s) from %q", least, group.Name)
+		}
+		if group.MaxSelections > 0 && n > group.MaxSelections {
+			return types.Money{}, fail("allows at most %d option(s) from %q", group.MaxSelections, group.Name)
+		}
+	}
+	if price.Amount < 0 {
+		return types.Money{}, fail("has a negative price with the selected options")
+	}
+	return price, nil
+}
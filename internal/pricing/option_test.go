@@ -0,0 +1,90 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApplyOptions(t *testing.T) {
+	small := types.Option{ID: primitive.NewObjectID(), Name: "Small", PriceDelta: usd(-300)}
+	large := types.Option{ID: primitive.NewObjectID(), Name: "Large", PriceDelta: usd(200)}
+	size := types.OptionGroup{ID: primitive.NewObjectID(), Name: "Size", Required: true, MaxSelections: 1, Options: []types.Option{small, large}}
+
+	cheese := types.Option{ID: primitive.NewObjectID(), Name: "Cheese", PriceDelta: usd(100)}
+	bacon := types.Option{ID: primitive.NewObjectID(), Name: "Bacon", PriceDelta: usd(150)}
+	onion := types.Option{ID: primitive.NewObjectID(), Name: "Onion", PriceDelta: usd(0)}
+	toppings := types.OptionGroup{ID: primitive.NewObjectID(), Name: "Toppings", MinSelections: 1, MaxSelections: 2, Options: []types.Option{cheese, bacon, onion}}
+
+	sauce := types.Option{ID: primitive.NewObjectID(), Name: "Sauce", PriceDelta: usd(50)}
+	extras := types.OptionGroup{ID: primitive.NewObjectID(), Name: "Extras", Options: []types.Option{sauce}}
+
+	item := &types.MenuItem{Base: types.Base{ID: primitive.NewObjectID()}, OptionGroups: []types.OptionGroup{size, toppings, extras}}
+	pick := func(g types.OptionGroup, o types.Option) types.SelectedOption {
+		return types.SelectedOption{GroupID: g.ID, OptionID: o.ID}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		price     int64
+		selected  []types.SelectedOption
+		wantPrice int64
+		wantErr   bool
+	}{
+		{"required and minimum met", 1000, []types.SelectedOption{pick(size, large), pick(toppings, cheese)}, 1300, false},
+		{"every group", 1000, []types.SelectedOption{pick(size, small), pick(toppings, cheese), pick(toppings, bacon), pick(extras, sauce)}, 1000, false},
+		{"free option", 1000, []types.SelectedOption{pick(size, large), pick(toppings, onion)}, 1200, false},
+		{"required group missing", 1000, []types.SelectedOption{pick(toppings, cheese)}, 0, true},
+		{"below the minimum", 1000, []types.SelectedOption{pick(size, large)}, 0, true},
+		{"above the maximum", 1000, []types.SelectedOption{pick(size, small), pick(size, large), pick(toppings, cheese)}, 0, true},
+		{"three toppings", 1000, []types.SelectedOption{pick(size, large), pick(toppings, cheese), pick(toppings, bacon), pick(toppings, onion)}, 0, true},
+		{"selected twice", 1000, []types.SelectedOption{pick(size, large), pick(toppings, cheese), pick(toppings, cheese)}, 0, true},
+		{"option of another group", 1000, []types.SelectedOption{pick(size, large), pick(extras, cheese)}, 0, true},
+		{"unknown option", 1000, []types.SelectedOption{pick(size, large), pick(toppings, types.Option{ID: primitive.NewObjectID()})}, 0, true},
+		{"negative price", 200, []types.SelectedOption{pick(size, small), pick(toppings, onion)}, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			line := &types.OrderItem{MenuItemID: item.ID, Options: tc.selected}
+			price, err := applyOptions(item, line, usd(tc.price), "USD")
+			if tc.wantErr {
+				var itemErr *ItemError
+				if !errors.As(err, &itemErr) || itemErr.MenuItemID != item.ID {
+					t.Fatalf("applyOptions error = %v, want an ItemError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyOptions: %v", err)
+			}
+			if price != usd(tc.wantPrice) {
+				t.Errorf("price = %v, want %d", price, tc.wantPrice)
+			}
+		})
+	}
+
+	t.Run("snapshot", func(t *testing.T) {
+		line := &types.OrderItem{Options: []types.SelectedOption{pick(size, large), {
+			GroupID: toppings.ID, OptionID: cheese.ID, Group: "Free stuff", Name: "Gold", PriceDelta: usd(-1000),
+		}}}
+		if _, err := applyOptions(item, line, usd(1000), "USD"); err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range []types.SelectedOption{
+			{GroupID: size.ID, OptionID: large.ID, Group: "Size", Name: "Large", PriceDelta: usd(200)},
+			{GroupID: toppings.ID, OptionID: cheese.ID, Group: "Toppings", Name: "Cheese", PriceDelta: usd(100)},
+		} {
+			if line.Options[i] != want {
+				t.Errorf("option %d = %+v, want %+v", i, line.Options[i], want)
+			}
+		}
+	})
+
+	t.Run("other currency", func(t *testing.T) {
+		line := &types.OrderItem{Options: []types.SelectedOption{pick(size, large), pick(toppings, cheese)}}
+		if _, err := applyOptions(item, line, types.NewMoney(1000, "EUR"), "EUR"); err == nil {
+			t.Fatal("applyOptions added USD deltas to a EUR price")
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrOrderTooLarge is returned for an order whose amounts are too large to
+// charge. Like ItemError it is caused by the client's request.
+var ErrOrderTooLarge = errors.New("order total is too large")
+
+// maxOrderAmount bounds the pre-tax total of an order in minor units. An
+// order has at most 20 tax rules of at most 100% each, and compounding can at
+// most double the amount per rule, so its taxed total stays within an int64.
+const maxOrderAmount = math.MaxInt64 >> 21
+
+// ItemError reports an order line that cannot be priced. It is caused by the
+// client's request, so handlers should answer it with a 4xx status.
+type ItemError struct {
+	MenuItemID primitive.ObjectID
+	Reason     string
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("menu item %s %s", e.MenuItemID.Hex(), e.Reason)
+}
+
+// PriceOrder looks up every item of the order in the menu store, checks the
+// selected options against the item's option groups, snapshots its current
+// name, category and price with options and recomputes the line totals in the
+// restaurant's currency. It then takes off the discount of promo, when not
+// nil, and charges the restaurant's taxes on what is left. Any amounts sent by
+// the client are overwritten.
+func PriceOrder(ctx context.Context, menu storage.MenuStore, order *types.Order, restaurant *types.Restaurant, promo *types.Promotion) error {
+	order.Discounts = nil
+	order.DiscountTotal = types.NewMoney(0, restaurant.Currency)
+	total := types.NewMoney(0, restaurant.Currency)
+	for i := range order.Items {
+		line := &order.Items[i]
+
+		item, err := menu.GetMenuItemByID(ctx, line.MenuItemID)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return &ItemError{MenuItemID: line.MenuItemID, Reason: "does not exist"}
+		}
+		if item.Restaurant != order.Restaurant {
+			return &ItemError{MenuItemID: line.MenuItemID, Reason: "belongs to another restaurant"}
+		}
+		if !item.Available {
+			return &ItemError{MenuItemID: line.MenuItemID, Reason: "is not available"}
+		}
+
+		price, err := item.Price.In(restaurant.Currency)
+		if err != nil {
+			return fmt.Errorf("menu item %s: %w", item.ID.Hex(), err)
+		}
+		if price, err = applyOptions(item, line, price, restaurant.Currency); err != nil {
+			return err
+		}
+		line.Name = item.Name
+		line.Category = item.Category
+		line.Price = price
+		if line.LineTotal, err = price.Mul(int64(line.Quantity)); err != nil {
+			return fmt.Errorf("%w: menu item %s: %w", ErrOrderTooLarge, item.ID.Hex(), err)
+		}
+		line.Discount = types.NewMoney(0, restaurant.Currency)
+		if total, err = total.Add(line.LineTotal); err != nil || total.Amount > maxOrderAmount {
+			return ErrOrderTooLarge
+		}
+	}
+	if promo != nil {
+		if err := applyPromotion(order, promo, restaurant.Currency, time.Now()); err != nil {
+			return err
+		}
+	}
+	applyTaxes(order, restaurant.Tax, restaurant.Currency)
+	return nil
+}
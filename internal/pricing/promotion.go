@@ -0,0 +1,151 @@
+package pricing
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+)
+
+// PromoError reports a promo code that cannot be used on the order. It is
+// caused by the client's request, so handlers should answer it with a 4xx status.
+type PromoError struct {
+	Code   string
+	Reason string
+}
+
+func (e *PromoError) Error() string {
+	return fmt.Sprintf("promo code %s %s", e.Code, e.Reason)
+}
+
+// applyPromotion checks that promo can be used on the priced order at now and
+// spreads its discount over the order's lines.
+func applyPromotion(order *types.Order, promo *types.Promotion, currency string, now time.Time) error {
+	fail := func(reason string) error { return &PromoError{Code: promo.Code, Reason: reason} }
+
+	switch {
+	case !promo.Active:
+		return fail("is not active")
+	case promo.StartsAt != nil && now.Before(*promo.StartsAt):
+		return fail("is not valid yet")
+	case promo.EndsAt != nil && !now.Before(*promo.EndsAt):
+		return fail("has expired")
+	case promo.RestaurantID != nil && *promo.RestaurantID != order.Restaurant:
+		return fail("is not valid at this restaurant")
+	}
+
+	minOrder, err := promo.MinOrder.In(currency)
+	if err != nil {
+		return fail("is not valid for orders in " + currency)
+	}
+	var itemsTotal int64
+	for _, line := range order.Items {
+		itemsTotal += line.LineTotal.Amount
+	}
+	if itemsTotal < minOrder.Amount {
+		return fail("requires items worth at least " + minOrder.String())
+	}
+
+	discounts := make([]int64, len(order.Items))
+	switch promo.Type {
+	case types.PromoPercentage, types.PromoCategory:
+		rate := big.NewRat(int64(promo.Percent), int64(types.Hundred))
+		for i, line := range order.Items {
+			if promo.Type == types.PromoCategory && !promo.Qualifies(line) {
+				continue
+			}
+			discounts[i] = roundRat(new(big.Rat).Mul(big.NewRat(line.LineTotal.Amount, 1), rate))
+		}
+	case types.PromoFixedAmount:
+		amount, err := promo.Amount.In(currency)
+		if err != nil {
+			return fail("is not valid for orders in " + currency)
+		}
+		spread(discounts, order.Items, min(amount.Amount, itemsTotal))
+	case types.PromoBuyXGetY:
+		freeItems(discounts, order.Items, promo)
+	default:
+		return fmt.Errorf("promotion %s has unknown type %q", promo.ID.Hex(), promo.Type)
+	}
+
+	var total int64
+	for i := range order.Items {
+		order.Items[i].Discount = types.NewMoney(discounts[i], currency)
+		total += discounts[i]
+	}
+	if total == 0 {
+		return fail("does not apply to any item in the order")
+	}
+	order.Discounts = []types.DiscountLine{{
+		PromotionID: promo.ID,
+		Code:        promo.Code,
+		Type:        promo.Type,
+		Description: promo.Description,
+		Amount:      types.NewMoney(total, currency),
+	}}
+	order.DiscountTotal = types.NewMoney(total, currency)
+	return nil
+}
+
+// spread divides amount over the lines in proportion to their totals, giving
+// the minor units left over by rounding down to the largest remainders.
+func spread(discounts []int64, lines []types.OrderItem, amount int64) {
+	var total int64
+	for _, line := range lines {
+		total += line.LineTotal.Amount
+	}
+	if total == 0 {
+		return
+	}
+	remainders := make([]*big.Int, len(lines))
+	left := amount
+	for i, line := range lines {
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(amount), big.NewInt(line.LineTotal.Amount)), big.NewInt(total), new(big.Int))
+		discounts[i], remainders[i] = q.Int64(), r
+		left -= discounts[i]
+	}
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return remainders[b].Cmp(remainders[a]) })
+	for _, i := range order[:left] {
+		discounts[i]++
+	}
+}
+
+// freeItems makes GetQuantity of every BuyQuantity+GetQuantity qualifying
+// units free, taking the free units from the cheapest lines so customers
+// cannot pair an expensive item with a cheap one to get the expensive one
+// free. It works on line quantities, never on single units, so a huge
+// quantity costs no more than a small one.
+func freeItems(discounts []int64, lines []types.OrderItem, promo *types.Promotion) {
+	group := promo.BuyQuantity + promo.GetQuantity
+	if group == 0 {
+		return
+	}
+	var qualifying []int
+	var units int
+	for i, line := range lines {
+		if promo.Qualifies(line) && line.Quantity > 0 {
+			qualifying = append(qualifying, i)
+			units += line.Quantity
+		}
+	}
+	slices.SortStableFunc(qualifying, func(a, b int) int {
+		return cmp.Compare(lines[a].Price.Amount, lines[b].Price.Amount)
+	})
+
+	remaining := units / group * promo.GetQuantity
+	for _, i := range qualifying {
+		if remaining == 0 {
+			break
+		}
+		free := min(lines[i].Quantity, remaining)
+		discounts[i] += int64(free) * lines[i].Price.Amount
+		remaining -= free
+	}
+}
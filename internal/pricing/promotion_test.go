@@ -0,0 +1,38 @@
+package pricing
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+)
+
+func TestFreeItems(t *testing.T) {
+	line := func(category string, price int64, quantity int) types.OrderItem {
+		return types.OrderItem{Category: category, Price: usd(price), Quantity: quantity, LineTotal: usd(price * int64(quantity))}
+	}
+	buy1get1 := &types.Promotion{Type: types.PromoBuyXGetY, BuyQuantity: 1, GetQuantity: 1}
+	buy2get1 := &types.Promotion{Type: types.PromoBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Categories: []string{"pizza"}}
+
+	for _, tc := range []struct {
+		name  string
+		promo *types.Promotion
+		items []types.OrderItem
+		want  []int64
+	}{
+		{"one line", buy1get1, []types.OrderItem{line("pizza", 1000, 3)}, []int64{1000}},
+		{"cheapest lines are free", buy1get1, []types.OrderItem{line("pizza", 1000, 1), line("pizza", 900, 1), line("pizza", 200, 1), line("pizza", 100, 1)}, []int64{0, 0, 200, 100}},
+		{"free units span lines", buy1get1, []types.OrderItem{line("pizza", 1000, 3), line("pizza", 200, 1)}, []int64{1000, 200}},
+		{"incomplete group", buy2get1, []types.OrderItem{line("pizza", 1000, 2)}, []int64{0}},
+		{"only qualifying lines count", buy2get1, []types.OrderItem{line("pizza", 1000, 2), line("drinks", 100, 5), line("pizza", 800, 1)}, []int64{0, 0, 800}},
+		{"equal prices keep order", buy1get1, []types.OrderItem{line("pizza", 500, 1), line("pizza", 500, 1)}, []int64{500, 0}},
+		{"large quantity", buy2get1, []types.OrderItem{line("pizza", 100, 1_000_000_000)}, []int64{333_333_333 * 100}},
+		{"nothing to give", &types.Promotion{Type: types.PromoBuyXGetY}, []types.OrderItem{line("pizza", 1000, 4)}, []int64{0}},
+	} {
+		discounts := make([]int64, len(tc.items))
+		freeItems(discounts, tc.items, tc.promo)
+		if !slices.Equal(discounts, tc.want) {
+			t.Errorf("%s: discounts = %v, want %v", tc.name, discounts, tc.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package pricing
+
+import (
+	"math/big"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+)
+
+// applyTaxes charges the taxes of policy on the priced and discounted lines of
+// the order and sets its subtotal, tax lines and totals. Each line's taxes are rounded to
+// the minor unit on their own, so an item costs the same in every order.
+//
+// With exclusive pricing a compound tax is charged on the line plus the
+// rounded taxes before it. With inclusive pricing the line amount is split
+// exactly into its net and taxes, and the net absorbs the rounding.
+func applyTaxes(order *types.Order, policy types.TaxPolicy, currency string) {
+	rules := policy.Rules
+	taxable := make([]int64, len(rules))
+	charged := make([]int64, len(rules))
+	applied := make([]bool, len(rules))
+
+	var subtotal, taxTotal int64
+	for _, line := range order.Items {
+		rates := make([]*big.Rat, len(rules))
+		for i, rule := range rules {
+			rates[i] = big.NewRat(int64(rule.RateFor(line.Category)), int64(types.Hundred))
+		}
+
+		amount := line.LineTotal.Amount - line.Discount.Amount
+		taxes := make([]int64, len(rules))
+		if policy.Inclusive {
+			net := new(big.Rat).Quo(big.NewRat(amount, 1), taxFactor(rules, rates))
+			prior := new(big.Rat)
+			for i, rule := range rules {
+				base := new(big.Rat).Set(net)
+				if rule.Compound {
+					base.Add(base, prior)
+				}
+				tax := base.Mul(base, rates[i])
+				prior.Add(prior, tax)
+				taxes[i] = roundRat(tax)
+			}
+		} else {
+			var prior int64
+			for i, rule := range rules {
+				base := amount
+				if rule.Compound {
+					base += prior
+				}
+				taxes[i] = roundRat(new(big.Rat).Mul(big.NewRat(base, 1), rates[i]))
+				prior += taxes[i]
+			}
+		}
+
+		net := amount
+		if policy.Inclusive {
+			for _, tax := range taxes {
+				net -= tax
+			}
+		}
+		var prior int64
+		for i, rule := range rules {
+			if rates[i].Sign() != 0 {
+				applied[i] = true
+				taxable[i] += net
+				if rule.Compound {
+					taxable[i] += prior
+				}
+			}
+			charged[i] += taxes[i]
+			prior += taxes[i]
+		}
+		subtotal += net
+		taxTotal += prior
+	}
+
+	order.TaxInclusive = policy.Inclusive
+	order.Taxes = nil
+	for i, rule := range rules {
+		if !applied[i] {
+			continue
+		}
+		order.Taxes = append(order.Taxes, types.TaxLine{
+			Name:     rule.Name,
+			Rate:     rule.Rate,
+			Compound: rule.Compound,
+			Taxable:  types.NewMoney(taxable[i], currency),
+			Amount:   types.NewMoney(charged[i], currency),
+		})
+	}
+	order.Subtotal = types.NewMoney(subtotal, currency)
+	order.TaxTotal = types.NewMoney(taxTotal, currency)
+	order.TotalPrice = types.NewMoney(subtotal+taxTotal, currency)
+}
+
+// taxFactor returns what a net amount is multiplied by once every rule has
+// been charged on it, e.g. 1.1 for a single 10% tax.
+func taxFactor(rules []types.TaxRule, rates []*big.Rat) *big.Rat {
+	total := new(big.Rat)
+	for i, rule := range rules {
+		tax := new(big.Rat).Set(rates[i])
+		if rule.Compound {
+			tax.Mul(tax, new(big.Rat).Add(big.NewRat(1, 1), total))
+		}
+		total.Add(total, tax)
+	}
+	return total.Add(total, big.NewRat(1, 1))
+}
+
+// roundRat rounds r to the nearest integer, halves away from zero.
+func roundRat(r *big.Rat) int64 {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if m.Abs(m).Lsh(m, 1).Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
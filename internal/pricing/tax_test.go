@@ -0,0 +1,138 @@
+package pricing
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+)
+
+func usd(amount int64) types.Money { return types.NewMoney(amount, "USD") }
+
+func pct(t *testing.T, s string) types.Percent {
+	t.Helper()
+	p, err := types.ParsePercent(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestApplyTaxes(t *testing.T) {
+	vat := types.TaxRule{Name: "VAT", Rate: pct(t, "10")}
+	gst := types.TaxRule{Name: "GST", Rate: pct(t, "5")}
+	qst := types.TaxRule{Name: "QST", Rate: pct(t, "9.975"), Compound: true}
+	noDrinks := types.TaxRule{Name: "Food tax", Rate: pct(t, "10"), CategoryRates: []types.CategoryRate{{Category: "drinks", Rate: 0}}}
+
+	type taxLine struct{ taxable, amount int64 }
+	line := func(category string, total, discount int64) types.OrderItem {
+		return types.OrderItem{Category: category, LineTotal: usd(total), Discount: usd(discount)}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		policy   types.TaxPolicy
+		items    []types.OrderItem
+		subtotal int64
+		taxes    []taxLine
+		total    int64
+	}{
+		{
+			name:     "exclusive",
+			policy:   types.TaxPolicy{Rules: []types.TaxRule{vat}},
+			items:    []types.OrderItem{line("food", 999, 0)},
+			subtotal: 999, taxes: []taxLine{{999, 100}}, total: 1099,
+		},
+		{
+			name:     "rounded per line, half up",
+			policy:   types.TaxPolicy{Rules: []types.TaxRule{vat}},
+			items:    []types.OrderItem{line("food", 5, 0), line("food", 5, 0), line("food", 4, 0)},
+			subtotal: 14, taxes: []taxLine{{14, 2}}, total: 16,
+		},
+		{
+			name:     "compound",
+			policy:   types.TaxPolicy{Rules: []types.TaxRule{gst, qst}},
+			items:    []types.OrderItem{line("food", 1000, 0)},
+			subtotal: 1000, taxes: []taxLine{{1000, 50}, {1050, 105}}, total: 1155,
+		},
+		{
+			name:     "inclusive",
+			policy:   types.TaxPolicy{Inclusive: true, Rules: []types.TaxRule{vat}},
+			items:    []types.OrderItem{line("food", 999, 0)},
+			subtotal: 908, taxes: []taxLine{{908, 91}}, total: 999,
+		},
+		{
+			name:     "inclusive compound",
+			policy:   types.TaxPolicy{Inclusive: true, Rules: []types.TaxRule{gst, qst}},
+			items:    []types.OrderItem{line("food", 1155, 0)},
+			subtotal: 1000, taxes: []taxLine{{1000, 50}, {1050, 105}}, total: 1155,
+		},
+		{
+			name:     "after discount",
+			policy:   types.TaxPolicy{Rules: []types.TaxRule{vat}},
+			items:    []types.OrderItem{line("food", 1000, 200)},
+			subtotal: 800, taxes: []taxLine{{800, 80}}, total: 880,
+		},
+		{
+			name:     "exempt category",
+			policy:   types.TaxPolicy{Rules: []types.TaxRule{noDrinks}},
+			items:    []types.OrderItem{line("food", 1000, 0), line("drinks", 300, 0)},
+			subtotal: 1300, taxes: []taxLine{{1000, 100}}, total: 1400,
+		},
+		{
+			name:     "only exempt items",
+			policy:   types.TaxPolicy{Rules: []types.TaxRule{noDrinks, {Name: "Zero", Rate: 0}}},
+			items:    []types.OrderItem{line("drinks", 300, 0)},
+			subtotal: 300, total: 300,
+		},
+		{
+			name:     "no rules",
+			items:    []types.OrderItem{line("food", 1000, 0)},
+			subtotal: 1000, total: 1000,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			order := &types.Order{Items: tc.items}
+			applyTaxes(order, tc.policy, "USD")
+
+			if order.Subtotal != usd(tc.subtotal) || order.TotalPrice != usd(tc.total) {
+				t.Errorf("subtotal %v, total %v, want %d and %d", order.Subtotal, order.TotalPrice, tc.subtotal, tc.total)
+			}
+			if order.TaxInclusive != tc.policy.Inclusive {
+				t.Errorf("TaxInclusive = %v", order.TaxInclusive)
+			}
+			if len(order.Taxes) != len(tc.taxes) {
+				t.Fatalf("tax lines %+v, want %+v", order.Taxes, tc.taxes)
+			}
+			var taxTotal int64
+			for i, want := range tc.taxes {
+				got := order.Taxes[i]
+				if got.Taxable != usd(want.taxable) || got.Amount != usd(want.amount) {
+					t.Errorf("%s: taxable %v, amount %v, want %d and %d", got.Name, got.Taxable, got.Amount, want.taxable, want.amount)
+				}
+				taxTotal += want.amount
+			}
+			if order.TaxTotal != usd(taxTotal) {
+				t.Errorf("TaxTotal = %v, want %d", order.TaxTotal, taxTotal)
+			}
+		})
+	}
+}
+
+func TestRoundRat(t *testing.T) {
+	for _, tc := range []struct {
+		num, denom int64
+		want       int64
+	}{
+		{1, 2, 1},
+		{-1, 2, -1},
+		{149, 100, 1},
+		{151, 100, 2},
+		{-151, 100, -2},
+		{5, 1, 5},
+	} {
+		if got := roundRat(big.NewRat(tc.num, tc.denom)); got != tc.want {
+			t.Errorf("roundRat(%d/%d) = %d, want %d", tc.num, tc.denom, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies reads proxy addresses given as CIDR ranges, e.g.
+// "10.0.0.0/8", or as single IPs.
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if strings.Contains(p, "/") {
+			prefix, err := netip.ParsePrefix(p)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", p, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(p)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", p, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// RealIP sets r.RemoteAddr to the client address when the request came
+// through trusted proxies. X-Forwarded-For is read from the right, skipping
+// the hops that are trusted proxies themselves; the first other address is
+// the client. Entries left of it could be made up by the client, so they are
+// ignored, and so is the header when the peer is not a trusted proxy.
+func RealIP(trusted []netip.Prefix) Middleware {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, p := range trusted {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			peer, ok := remoteAddr(r.RemoteAddr)
+			if !ok || !isTrusted(peer) {
+				next(w, r)
+				return
+			}
+			client := peer
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					// A malformed entry ends what can be relied on
+					break
+				}
+				client = hop.Unmap()
+				if !isTrusted(client) {
+					break
+				}
+			}
+			if client != peer {
+				r.RemoteAddr = client.String()
+			}
+			next(w, r)
+		}
+	}
+}
+
+// remoteAddr parses the IP of a "host:port" RemoteAddr.
+func remoteAddr(hostport string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	addr, err := netip.ParseAddr(host)
+	return addr.Unmap(), err == nil
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	h := RealIP(trusted)(func(w http.ResponseWriter, r *http.Request) { got = r.RemoteAddr })
+
+	for _, tc := range []struct {
+		name, remote string
+		forwarded    []string
+		want         string
+	}{
+		{"no proxy", "203.0.113.7:5000", nil, "203.0.113.7:5000"},
+		{"untrusted peer sending the header", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7:5000"},
+		{"trusted proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without the header", "10.0.0.2:5000", nil, "10.0.0.2:5000"},
+		{"chain of trusted proxies", "10.0.0.2:5000", []string{"198.51.100.1, 192.168.1.1, 10.1.2.3"}, "198.51.100.1"},
+		{"spoofed entries left of the client", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"several headers", "10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"every hop trusted", "10.0.0.2:5000", []string{"10.0.0.9, 10.0.0.3"}, "10.0.0.9"},
+		{"malformed hop", "10.0.0.2:5000", []string{"1.2.3.4, garbage, 10.0.0.3"}, "10.0.0.3"},
+		{"IPv6 proxy", "[fd00::1]:5000", []string{"2001:db8::7"}, "2001:db8::7"},
+		{"IPv4-mapped proxy", "[::ffff:10.0.0.2]:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"single IP is not a range", "192.168.1.2:5000", []string{"198.51.100.1"}, "192.168.1.2:5000"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tc.remote
+		for _, v := range tc.forwarded {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		h(httptest.NewRecorder(), r)
+		if got != tc.want {
+			t.Errorf("%s: client = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		if _, err := ParseTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", bad)
+		}
+	}
+}
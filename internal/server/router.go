@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+)
+
+// Middleware wraps a handler with extra behaviour. The role middlewares returned
+// by auth.NewAuthMiddleware already have this shape.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Chain wraps h so that the first middleware is the outermost one.
+func Chain(h http.HandlerFunc, mw ...Middleware) http.HandlerFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Router declares routes on top of http.ServeMux method and wildcard patterns
+// (e.g. "GET /orders/{id}") and answers unknown paths and unsupported methods
+// with the usual JSON error body.
+type Router struct {
+	mux         *http.ServeMux
+	middlewares []Middleware
+	methods     map[string][]string // path pattern -> registered methods
+}
+
+// NewRouter creates an empty router.
+func NewRouter() *Router {
+	rt := &Router{
+		mux:     http.NewServeMux(),
+		methods: make(map[string][]string),
+	}
+	rt.mux.HandleFunc("/", notFound)
+	return rt
+}
+
+// Use appends global middleware, applied to every request including 404 and 405 answers.
+func (rt *Router) Use(mw ...Middleware) {
+	rt.middlewares = append(rt.middlewares, mw...)
+}
+
+// Handle registers h for method and path, wrapped in the given per-route middleware.
+// path uses http.ServeMux syntax, so wildcards are read with r.PathValue.
+func (rt *Router) Handle(method, path string, h http.HandlerFunc, mw ...Middleware) {
+	if _, seen := rt.methods[path]; !seen {
+		// Any method not registered below falls through to this catch-all
+		rt.mux.HandleFunc(path, rt.methodNotAllowed(path))
+	}
+	rt.methods[path] = append(rt.methods[path], method)
+	rt.mux.HandleFunc(method+" "+path, Chain(h, mw...))
+}
+
+func (rt *Router) Get(path string, h http.HandlerFunc, mw ...Middleware) {
+	rt.Handle(http.MethodGet, path, h, mw...)
+}
+
+func (rt *Router) Post(path string, h http.HandlerFunc, mw ...Middleware) {
+	rt.Handle(http.MethodPost, path, h, mw...)
+}
+
+func (rt *Router) Put(path string, h http.HandlerFunc, mw ...Middleware) {
+	rt.Handle(http.MethodPut, path, h, mw...)
+}
+
+func (rt *Router) Patch(path string, h http.HandlerFunc, mw ...Middleware) {
+	rt.Handle(http.MethodPatch, path, h, mw...)
+}
+
+func (rt *Router) Delete(path string, h http.HandlerFunc, mw ...Middleware) {
+	rt.Handle(http.MethodDelete, path, h, mw...)
+}
+
+// ServeHTTP dispatches the request through the global middleware chain.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	Chain(rt.mux.ServeHTTP, rt.middlewares...)(w, r)
+}
+
+func (rt *Router) methodNotAllowed(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := slices.Clone(rt.methods[path])
+		if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+			allowed = append(allowed, http.MethodHead)
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		helper.WriteSimpleError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" not allowed on "+r.URL.Path)
+	}
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	helper.WriteSimpleError(w, http.StatusNotFound, "No route for "+r.URL.Path)
+}
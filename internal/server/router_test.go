@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/helper"
+)
+
+// trace returns middleware that appends name to the X-Trace header before
+// calling the next handler, so tests can read the order they ran in.
+func trace(name string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next(w, r)
+		}
+	}
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func errorMessage(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body helper.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("error body is not JSON: %v", err)
+	}
+	return body.Errors["message"]
+}
+
+func TestRouterDispatch(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/items", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("list")) })
+	rt.Post("/items", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	rt.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("item " + r.PathValue("id"))) })
+	rt.Delete("/items/{id}", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{http.MethodGet, "/items", http.StatusOK, "list"},
+		{http.MethodPost, "/items", http.StatusCreated, ""},
+		{http.MethodGet, "/items/42", http.StatusOK, "item 42"},
+		{http.MethodDelete, "/items/42", http.StatusNoContent, ""},
+	} {
+		rec := serve(rt, tc.method, tc.path)
+		if rec.Code != tc.status || rec.Body.String() != tc.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tc.method, tc.path, rec.Code, rec.Body.String(), tc.status, tc.body)
+		}
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	rt := NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	rt.Get("/items", ok)
+	rt.Post("/items", ok)
+	rt.Patch("/items/{id}", ok)
+
+	for _, tc := range []struct {
+		method, path string
+		allow        string
+	}{
+		{http.MethodDelete, "/items", "GET, POST, HEAD"},
+		{http.MethodPut, "/items", "GET, POST, HEAD"},
+		{http.MethodGet, "/items/42", "PATCH"},
+	} {
+		rec := serve(rt, tc.method, tc.path)
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s = %d, want 405", tc.method, tc.path, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tc.method, tc.path, got, tc.allow)
+		}
+		if msg := errorMessage(t, rec); msg != "Method "+tc.method+" not allowed on "+tc.path {
+			t.Errorf("%s %s: message = %q", tc.method, tc.path, msg)
+		}
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	rt := NewRouter()
+	rt.Get("/items", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/", "/nothing", "/items/42"} {
+		rec := serve(rt, http.MethodGet, path)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, rec.Code)
+			continue
+		}
+		if msg := errorMessage(t, rec); msg != "No route for "+path {
+			t.Errorf("GET %s: message = %q", path, msg)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	rt := NewRouter()
+	rt.Use(trace("global-1"), trace("global-2"))
+	rt.Get("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Trace", "handler")
+	}, trace("route-1"), trace("route-2"))
+
+	rec := serve(rt, http.MethodGet, "/items")
+	if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != "global-1,global-2,route-1,route-2,handler" {
+		t.Errorf("ran in order %s", got)
+	}
+
+	// Global middleware also wraps the router's own 404 and 405 answers, but
+	// route middleware does not
+	for _, tc := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/nothing", http.StatusNotFound},
+		{http.MethodDelete, "/items", http.StatusMethodNotAllowed},
+	} {
+		rec := serve(rt, tc.method, tc.path)
+		if got := strings.Join(rec.Header().Values("X-Trace"), ","); rec.Code != tc.status || got != "global-1,global-2" {
+			t.Errorf("%s %s = %d after %s", tc.method, tc.path, rec.Code, got)
+		}
+	}
+}
+
+func TestMiddlewareStopsChain(t *testing.T) {
+	// A middleware that answers itself, like authentication, keeps the
+	// handler from running
+	deny := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}
+	called := false
+	h := Chain(func(w http.ResponseWriter, r *http.Request) { called = true }, trace("outer"), deny, trace("inner"))
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized || called {
+		t.Errorf("status %d, handler called: %v", rec.Code, called)
+	}
+	if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != "outer" {
+		t.Errorf("ran %s", got)
+	}
+}
+
+func TestRecoverer(t *testing.T) {
+	rt := NewRouter()
+	rt.Use(Recoverer, RequestLogger)
+	rt.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	rt.Get("/ok", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+
+	rec := serve(rt, http.MethodGet, "/panic")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking handler = %d, want 500", rec.Code)
+	}
+	if msg := errorMessage(t, rec); msg != "Internal server error" {
+		t.Errorf("message = %q", msg)
+	}
+
+	// The router keeps serving after a panic
+	if rec := serve(rt, http.MethodGet, "/ok"); rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("after a panic: %d %q", rec.Code, rec.Body.String())
+	}
+
+	// An aborted handler is passed on for net/http to drop the connection
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", err)
+		}
+	}()
+	Recoverer(func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) })(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
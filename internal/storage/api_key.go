@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyStore persists API keys of machine clients.
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, k *types.APIKey) error
+	GetAPIKey(ctx context.Context, id primitive.ObjectID) (*types.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*types.APIKey, error)
+	// ListAPIKeys returns one page of API keys and the cursor of the next page, if any.
+	ListAPIKeys(ctx context.Context, f APIKeyFilter) ([]*types.APIKey, string, error)
+	// TouchAPIKey records that the key was used at at.
+	TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// RevokeAPIKey disables the key for good. It returns ErrConflict when it
+	// was already revoked.
+	RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
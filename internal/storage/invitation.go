@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvitationStore persists role invitations.
+type InvitationStore interface {
+	CreateInvitation(ctx context.Context, inv *types.Invitation) error
+	GetInvitation(ctx context.Context, id primitive.ObjectID) (*types.Invitation, error)
+	GetInvitationByToken(ctx context.Context, tokenHash string) (*types.Invitation, error)
+	// ListInvitations returns one page of invitations and the cursor of the next page, if any.
+	ListInvitations(ctx context.Context, f InvitationFilter) ([]*types.Invitation, string, error)
+	// AcceptInvitation marks the invitation accepted by userID, provided it is
+	// still pending at now. It returns ErrConflict otherwise, so an invitation
+	// is accepted at most once.
+	AcceptInvitation(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error
+	// ReopenInvitation undoes AcceptInvitation when granting the role failed.
+	ReopenInvitation(ctx context.Context, id primitive.ObjectID) error
+	// RevokeInvitation withdraws a pending invitation. It returns ErrConflict
+	// when the invitation was already accepted or revoked.
+	RevokeInvitation(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
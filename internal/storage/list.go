@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// DefaultLimit is the page size used when the caller does not ask for one.
+	DefaultLimit = 20
+	// MaxLimit caps the page size so a single query stays bounded.
+	MaxLimit = 100
+)
+
+var (
+	ErrInvalidSort   = errors.New("storage: unsupported sort field")
+	ErrInvalidCursor = errors.New("storage: invalid cursor")
+)
+
+// Sortable fields per collection. A "-" prefix on ListOptions.Sort sorts descending.
+var (
+	OrderSorts      = []string{"created_at", "updated_at"}
+	RestaurantSorts = []string{"name", "created_at", "updated_at"}
+	MenuItemSorts   = []string{"name", "category", "created_at", "updated_at"}
+	UserSorts       = []string{"name", "email", "created_at"}
+	InvitationSorts = []string{"email", "created_at", "expires_at"}
+	APIKeySorts     = []string{"name", "created_at"}
+	PromotionSorts  = []string{"code", "created_at"}
+)
+
+// ListOptions controls pagination and ordering of list queries.
+type ListOptions struct {
+	Limit  int
+	Cursor string // opaque, taken from the previous page's next cursor
+	Sort   string // field name, "-" prefix for descending
+}
+
+// PageSize returns the effective limit, applying the default and the cap.
+func (o ListOptions) PageSize() int {
+	if o.Limit <= 0 {
+		return DefaultLimit
+	}
+	return min(o.Limit, MaxLimit)
+}
+
+// SortField resolves the requested sort against the allowed fields, falling
+// back to def when no sort was requested.
+func (o ListOptions) SortField(allowed []string, def string) (field string, desc bool, err error) {
+	sort := o.Sort
+	if sort == "" {
+		sort = def
+	}
+	field, desc = strings.CutPrefix(sort, "-")
+	if !slices.Contains(allowed, field) {
+		return "", false, ErrInvalidSort
+	}
+	return field, desc, nil
+}
+
+// Cursor marks the last document of a page. Documents are ordered by the
+// sort field and then by ID, so the pair identifies a unique position.
+type Cursor struct {
+	Sort string             `json:"s"`
+	ID   primitive.ObjectID `json:"id"`
+	Str  string             `json:"v,omitempty"`
+	Time *time.Time         `json:"t,omitempty"`
+}
+
+// NewCursor builds a cursor for a document whose sort field holds value,
+// which must be a string or a time.Time.
+func NewCursor(sort string, id primitive.ObjectID, value any) Cursor {
+	c := Cursor{Sort: sort, ID: id}
+	switch v := value.(type) {
+	case time.Time:
+		c.Time = &v
+	case string:
+		c.Str = v
+	}
+	return c
+}
+
+// Value returns the sort field value stored in the cursor.
+func (c Cursor) Value() any {
+	if c.Time != nil {
+		return *c.Time
+	}
+	return c.Str
+}
+
+// Encode serializes the cursor into an opaque URL-safe token.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode. The cursor must have
+// been issued for the same sort order.
+func DecodeCursor(token, sort string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID.IsZero() || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// OrderFilter selects orders. Zero values mean "any".
+type OrderFilter struct {
+	ListOptions
+	Status       string
+	RestaurantID primitive.ObjectID
+	UserID       primitive.ObjectID
+	CreatedFrom  time.Time
+	CreatedTo    time.Time
+}
+
+// RestaurantFilter selects restaurants. Name matches case-insensitively anywhere in the name.
+type RestaurantFilter struct {
+	ListOptions
+	ActiveOnly bool
+	Name       string
+}
+
+// MenuItemFilter selects menu items.
+type MenuItemFilter struct {
+	ListOptions
+	RestaurantID primitive.ObjectID
+	Category     string
+	Available    *bool
+}
+
+// UserFilter selects users.
+type UserFilter struct {
+	ListOptions
+	Role  string
+	Email string
+}
+
+// InvitationFilter selects invitations. A zero RestaurantID matches every
+// invitation; PendingAt, when set, keeps those still pending at that time.
+type InvitationFilter struct {
+	ListOptions
+	RestaurantID primitive.ObjectID
+	Email        string
+	PendingAt    time.Time
+}
+
+// APIKeyFilter selects API keys. A zero UserID matches every key; ActiveAt,
+// when set, keeps those neither revoked nor expired at that time.
+type APIKeyFilter struct {
+	ListOptions
+	UserID   primitive.ObjectID
+	ActiveAt time.Time
+}
+
+// PromotionFilter selects promotions. A zero RestaurantID matches every
+// promotion, GlobalOnly keeps those valid at every restaurant and Active,
+// when set, filters on the active flag.
+type PromotionFilter struct {
+	ListOptions
+	RestaurantID primitive.ObjectID
+	GlobalOnly   bool
+	Active       *bool
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MembershipStore persists the staff roles users hold at restaurants.
+type MembershipStore interface {
+	// SetMembership creates the membership of m.UserID at m.RestaurantID, or
+	// replaces the role of the existing one, and returns the stored document.
+	SetMembership(ctx context.Context, m *types.Membership) (*types.Membership, error)
+	GetMembership(ctx context.Context, userID, restaurantID primitive.ObjectID) (*types.Membership, error)
+	ListUserMemberships(ctx context.Context, userID primitive.ObjectID) ([]*types.Membership, error)
+	ListRestaurantMembers(ctx context.Context, restaurantID primitive.ObjectID) ([]*types.Membership, error)
+	DeleteMembership(ctx context.Context, userID, restaurantID primitive.ObjectID) error
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyStore keeps the API keys of machine clients in memory.
+type APIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[primitive.ObjectID]*types.APIKey
+}
+
+var _ storage.APIKeyStore = (*APIKeyStore)(nil)
+
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{keys: make(map[primitive.ObjectID]*types.APIKey)}
+}
+
+var apiKeySortKeys = sortKeys[types.APIKey]{
+	"name":       func(k *types.APIKey) any { return k.Name },
+	"created_at": func(k *types.APIKey) any { return k.CreatedAt },
+}
+
+// CreateAPIKey stores a new API key
+func (s *APIKeyStore) CreateAPIKey(ctx context.Context, k *types.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.keys {
+		if existing.Prefix == k.Prefix {
+			return storage.ErrDuplicate
+		}
+	}
+	if k.ID.IsZero() {
+		k.ID = primitive.NewObjectID()
+	}
+	s.keys[k.ID] = clone(k)
+	return nil
+}
+
+// GetAPIKey finds an API key by ID
+func (s *APIKeyStore) GetAPIKey(ctx context.Context, id primitive.ObjectID) (*types.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, nil
+	}
+	return clone(k), nil
+}
+
+// GetAPIKeyByPrefix finds an API key by the public prefix of the key
+func (s *APIKeyStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*types.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.Prefix == prefix {
+			return clone(k), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListAPIKeys returns one page of API keys
+func (s *APIKeyStore) ListAPIKeys(ctx context.Context, f storage.APIKeyFilter) ([]*types.APIKey, string, error) {
+	s.mu.RLock()
+	var keys []*types.APIKey
+	for _, k := range s.keys {
+		if !f.UserID.IsZero() && k.UserID != f.UserID {
+			continue
+		}
+		if !f.ActiveAt.IsZero() && !k.Active(f.ActiveAt) {
+			continue
+		}
+		keys = append(keys, clone(k))
+	}
+	s.mu.RUnlock()
+	return paginate(keys, f.ListOptions, storage.APIKeySorts, "-created_at", apiKeySortKeys, func(k *types.APIKey) primitive.ObjectID { return k.ID })
+}
+
+// TouchAPIKey records when the key was last used
+func (s *APIKeyStore) TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	k.LastUsedAt = &at
+	return nil
+}
+
+// RevokeAPIKey disables a key that is not revoked yet
+func (s *APIKeyStore) RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if k.RevokedAt != nil {
+		return storage.ErrConflict
+	}
+	k.RevokedAt = &at
+	return nil
+}
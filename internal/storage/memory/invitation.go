@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvitationStore keeps role invitations in memory.
+type InvitationStore struct {
+	mu          sync.RWMutex
+	invitations map[primitive.ObjectID]*types.Invitation
+}
+
+var _ storage.InvitationStore = (*InvitationStore)(nil)
+
+func NewInvitationStore() *InvitationStore {
+	return &InvitationStore{invitations: make(map[primitive.ObjectID]*types.Invitation)}
+}
+
+var invitationSortKeys = sortKeys[types.Invitation]{
+	"email":      func(i *types.Invitation) any { return i.Email },
+	"created_at": func(i *types.Invitation) any { return i.CreatedAt },
+	"expires_at": func(i *types.Invitation) any { return i.ExpiresAt },
+}
+
+// CreateInvitation stores a new invitation
+func (s *InvitationStore) CreateInvitation(ctx context.Context, inv *types.Invitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.invitations {
+		if existing.TokenHash == inv.TokenHash {
+			return storage.ErrDuplicate
+		}
+	}
+	if inv.ID.IsZero() {
+		inv.ID = primitive.NewObjectID()
+	}
+	s.invitations[inv.ID] = clone(inv)
+	return nil
+}
+
+// GetInvitation finds an invitation by ID
+func (s *InvitationStore) GetInvitation(ctx context.Context, id primitive.ObjectID) (*types.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	inv, ok := s.invitations[id]
+	if !ok {
+		return nil, nil
+	}
+	return clone(inv), nil
+}
+
+// GetInvitationByToken finds an invitation by the hash of its token
+func (s *InvitationStore) GetInvitationByToken(ctx context.Context, tokenHash string) (*types.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, inv := range s.invitations {
+		if inv.TokenHash == tokenHash {
+			return clone(inv), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListInvitations returns one page of invitations
+func (s *InvitationStore) ListInvitations(ctx context.Context, f storage.InvitationFilter) ([]*types.Invitation, string, error) {
+	s.mu.RLock()
+	var invitations []*types.Invitation
+	for _, inv := range s.invitations {
+		if !f.RestaurantID.IsZero() && (inv.RestaurantID == nil || *inv.RestaurantID != f.RestaurantID) {
+			continue
+		}
+		if f.Email != "" && inv.Email != f.Email {
+			continue
+		}
+		if !f.PendingAt.IsZero() && !inv.Pending(f.PendingAt) {
+			continue
+		}
+		invitations = append(invitations, clone(inv))
+	}
+	s.mu.RUnlock()
+	return paginate(invitations, f.ListOptions, storage.InvitationSorts, "-created_at", invitationSortKeys, func(i *types.Invitation) primitive.ObjectID { return i.ID })
+}
+
+// AcceptInvitation marks a pending invitation as accepted
+func (s *InvitationStore) AcceptInvitation(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invitations[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if !inv.Pending(now) {
+		return storage.ErrConflict
+	}
+	inv.AcceptedAt = &now
+	inv.AcceptedBy = &userID
+	return nil
+}
+
+// ReopenInvitation clears the acceptance of an invitation
+func (s *InvitationStore) ReopenInvitation(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invitations[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	inv.AcceptedAt = nil
+	inv.AcceptedBy = nil
+	return nil
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (s *InvitationStore) RevokeInvitation(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.invitations[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return storage.ErrConflict
+	}
+	inv.RevokedAt = &at
+	return nil
+}
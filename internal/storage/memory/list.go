@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sortKeys maps every sortable field of a document type to its value, which is
+// either a string or a time.Time.
+type sortKeys[T any] map[string]func(*T) any
+
+// paginate orders the matching documents like the MongoDB backend does (sort
+// field, then ID) and cuts out the page following the cursor.
+func paginate[T any](docs []*T, opts storage.ListOptions, allowed []string, defaultSort string, keys sortKeys[T], idOf func(*T) primitive.ObjectID) ([]*T, string, error) {
+	field, desc, err := opts.SortField(allowed, defaultSort)
+	if err != nil {
+		return nil, "", err
+	}
+	sortKey := field
+	if desc {
+		sortKey = "-" + field
+	}
+	after, err := storage.DecodeCursor(opts.Cursor, sortKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := keys[field]
+	less := func(a, b *T) bool {
+		c := compareValues(key(a), key(b))
+		if c == 0 {
+			c = compareIDs(idOf(a), idOf(b))
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.Slice(docs, func(i, j int) bool { return less(docs[i], docs[j]) })
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(docs), func(i int) bool {
+			c := compareValues(key(docs[i]), after.Value())
+			if c == 0 {
+				c = compareIDs(idOf(docs[i]), after.ID)
+			}
+			if desc {
+				return c < 0
+			}
+			return c > 0
+		})
+	}
+	docs = docs[start:]
+
+	limit := opts.PageSize()
+	if len(docs) <= limit {
+		return docs, "", nil
+	}
+	last := docs[limit-1]
+	return docs[:limit], storage.NewCursor(sortKey, idOf(last), key(last)).Encode(), nil
+}
+
+func compareIDs(a, b primitive.ObjectID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func compareValues(a, b any) int {
+	switch av := a.(type) {
+	case time.Time:
+		bv, _ := b.(time.Time)
+		return av.Compare(bv)
+	case string:
+		bv, _ := b.(string)
+		return strings.Compare(av, bv)
+	}
+	return 0
+}
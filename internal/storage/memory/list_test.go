@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type doc struct {
+	ID        primitive.ObjectID
+	Name      string
+	CreatedAt time.Time
+}
+
+var docKeys = sortKeys[doc]{
+	"name":       func(d *doc) any { return d.Name },
+	"created_at": func(d *doc) any { return d.CreatedAt },
+}
+
+func docID(d *doc) primitive.ObjectID { return d.ID }
+
+// testDocs returns documents whose names and creation times repeat, so the
+// order of equal sort keys falls back to the ID.
+func testDocs() []*doc {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var docs []*doc
+	for i, name := range []string{"b", "a", "c", "a", "b", "a", "c", "b", "a"} {
+		docs = append(docs, &doc{
+			ID:        primitive.NewObjectID(),
+			Name:      name,
+			CreatedAt: base.Add(time.Duration(i%3) * time.Hour),
+		})
+	}
+	return docs
+}
+
+// walk pages through docs with opts until there is no next cursor.
+func walk(t *testing.T, docs []*doc, opts storage.ListOptions) []*doc {
+	t.Helper()
+	var all []*doc
+	for pages := 0; ; pages++ {
+		if pages > len(docs) {
+			t.Fatal("paging does not terminate")
+		}
+		page, next, err := paginate(slices.Clone(docs), opts, []string{"name", "created_at"}, "created_at", docKeys, docID)
+		if err != nil {
+			t.Fatalf("paginate: %v", err)
+		}
+		if len(page) > opts.PageSize() {
+			t.Fatalf("page of %d documents exceeds the limit %d", len(page), opts.PageSize())
+		}
+		all = append(all, page...)
+		if next == "" {
+			return all
+		}
+		opts.Cursor = next
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	docs := testDocs()
+
+	for _, tc := range []struct {
+		sort  string
+		limit int
+		less  func(a, b *doc) int
+	}{
+		{"", 2, func(a, b *doc) int { return a.CreatedAt.Compare(b.CreatedAt) }},
+		{"created_at", 4, func(a, b *doc) int { return a.CreatedAt.Compare(b.CreatedAt) }},
+		{"-created_at", 2, func(a, b *doc) int { return b.CreatedAt.Compare(a.CreatedAt) }},
+		{"name", 1, func(a, b *doc) int { return compareValues(a.Name, b.Name) }},
+		{"-name", 3, func(a, b *doc) int { return compareValues(b.Name, a.Name) }},
+		{"name", 9, func(a, b *doc) int { return compareValues(a.Name, b.Name) }},
+		{"name", 0, func(a, b *doc) int { return compareValues(a.Name, b.Name) }},
+	} {
+		t.Run(tc.sort, func(t *testing.T) {
+			desc := len(tc.sort) > 0 && tc.sort[0] == '-'
+			want := slices.Clone(docs)
+			slices.SortFunc(want, func(a, b *doc) int {
+				if c := tc.less(a, b); c != 0 {
+					return c
+				}
+				if desc {
+					return compareIDs(b.ID, a.ID)
+				}
+				return compareIDs(a.ID, b.ID)
+			})
+
+			got := walk(t, docs, storage.ListOptions{Sort: tc.sort, Limit: tc.limit})
+			if !slices.Equal(got, want) {
+				t.Fatalf("walking all pages returned %v, want %v", names(got), names(want))
+			}
+		})
+	}
+}
+
+func TestPaginateInsertBetweenPages(t *testing.T) {
+	docs := testDocs()
+	opts := storage.ListOptions{Sort: "name", Limit: 4}
+	allowed := []string{"name", "created_at"}
+
+	first, next, err := paginate(slices.Clone(docs), opts, allowed, "created_at", docKeys, docID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Documents inserted after the first page was read show up on later pages
+	// when they sort after the cursor, and never shift what is returned.
+	before := &doc{ID: primitive.NewObjectID(), Name: "0"}
+	after := &doc{ID: primitive.NewObjectID(), Name: "z"}
+	docs = append(docs, before, after)
+	opts.Cursor = next
+	rest := walk(t, docs, opts)
+
+	seen := map[primitive.ObjectID]bool{}
+	for _, d := range append(first, rest...) {
+		if seen[d.ID] {
+			t.Fatalf("document %s returned twice", d.ID.Hex())
+		}
+		seen[d.ID] = true
+	}
+	if len(seen) != len(docs)-1 || seen[before.ID] || !seen[after.ID] {
+		t.Fatalf("got %v, want every document but the one sorting before the cursor", names(append(first, rest...)))
+	}
+}
+
+func TestPaginateErrors(t *testing.T) {
+	docs := testDocs()
+	allowed := []string{"name", "created_at"}
+	_, byName, err := paginate(slices.Clone(docs), storage.ListOptions{Sort: "name", Limit: 2}, allowed, "created_at", docKeys, docID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		opts    storage.ListOptions
+		wantErr error
+	}{
+		{"unknown sort field", storage.ListOptions{Sort: "password"}, storage.ErrInvalidSort},
+		{"garbage cursor", storage.ListOptions{Cursor: "not-a-cursor"}, storage.ErrInvalidCursor},
+		{"cursor of another sort", storage.ListOptions{Sort: "-name", Cursor: byName}, storage.ErrInvalidCursor},
+		{"cursor of the default sort", storage.ListOptions{Cursor: byName}, storage.ErrInvalidCursor},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := paginate(slices.Clone(docs), tc.opts, allowed, "created_at", docKeys, docID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("paginate error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func names(docs []*doc) []string {
+	var out []string
+	for _, d := range docs {
+		out = append(out, d.Name+"/"+d.ID.Hex()[18:])
+	}
+	return out
+}
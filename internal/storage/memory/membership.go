@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// membershipKey identifies a membership by its unique (user, restaurant) pair.
+type membershipKey struct {
+	user, restaurant primitive.ObjectID
+}
+
+// MembershipStore keeps restaurant staff memberships in memory.
+type MembershipStore struct {
+	mu          sync.RWMutex
+	memberships map[membershipKey]*types.Membership
+}
+
+var _ storage.MembershipStore = (*MembershipStore)(nil)
+
+func NewMembershipStore() *MembershipStore {
+	return &MembershipStore{memberships: make(map[membershipKey]*types.Membership)}
+}
+
+// SetMembership creates the membership or replaces the role of the existing one
+func (s *MembershipStore) SetMembership(ctx context.Context, m *types.Membership) (*types.Membership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := membershipKey{m.UserID, m.RestaurantID}
+	now := time.Now()
+	stored, ok := s.memberships[key]
+	if !ok {
+		stored = clone(m)
+		stored.ID = primitive.NewObjectID()
+		stored.CreatedAt = now
+		s.memberships[key] = stored
+	}
+	stored.Role = m.Role
+	stored.UpdatedAt = now
+	return clone(stored), nil
+}
+
+// GetMembership retrieves the membership of a user at a restaurant
+func (s *MembershipStore) GetMembership(ctx context.Context, userID, restaurantID primitive.ObjectID) (*types.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.memberships[membershipKey{userID, restaurantID}]
+	if !ok {
+		return nil, nil
+	}
+	return clone(m), nil
+}
+
+// ListUserMemberships returns every restaurant a user works at
+func (s *MembershipStore) ListUserMemberships(ctx context.Context, userID primitive.ObjectID) ([]*types.Membership, error) {
+	return s.find(func(m *types.Membership) bool { return m.UserID == userID }), nil
+}
+
+// ListRestaurantMembers returns the staff of a restaurant
+func (s *MembershipStore) ListRestaurantMembers(ctx context.Context, restaurantID primitive.ObjectID) ([]*types.Membership, error) {
+	return s.find(func(m *types.Membership) bool { return m.RestaurantID == restaurantID }), nil
+}
+
+// DeleteMembership removes a user from a restaurant's staff
+func (s *MembershipStore) DeleteMembership(ctx context.Context, userID, restaurantID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := membershipKey{userID, restaurantID}
+	if _, ok := s.memberships[key]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.memberships, key)
+	return nil
+}
+
+// find returns the matching memberships in creation order, like the MongoDB store.
+func (s *MembershipStore) find(match func(*types.Membership) bool) []*types.Membership {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*types.Membership
+	for _, m := range s.memberships {
+		if match(m) {
+			out = append(out, clone(m))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return compareIDs(out[i].ID, out[j].ID) < 0
+	})
+	return out
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Storage is a thread-safe, process-local storage backend.
+// Everything it holds is lost when the process exits.
+type Storage struct {
+	users       *UserStore
+	restaurants *RestaurantStore
+	menu        *MenuStore
+	orders      *OrderStore
+	tokens      *TokenStore
+	memberships *MembershipStore
+	oneTime     *OneTimeTokenStore
+	invitations *InvitationStore
+	apiKeys     *APIKeyStore
+	promotions  *PromotionStore
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// New creates an empty in-memory backend.
+func New() *Storage {
+	return &Storage{
+		users:       NewUserStore(),
+		restaurants: NewRestaurantStore(),
+		menu:        NewMenuStore(),
+		orders:      NewOrderStore(),
+		tokens:      NewTokenStore(),
+		memberships: NewMembershipStore(),
+		oneTime:     NewOneTimeTokenStore(),
+		invitations: NewInvitationStore(),
+		apiKeys:     NewAPIKeyStore(),
+		promotions:  NewPromotionStore(),
+	}
+}
+
+func (s *Storage) Users() storage.UserStore                 { return s.users }
+func (s *Storage) Restaurants() storage.RestaurantStore     { return s.restaurants }
+func (s *Storage) Menu() storage.MenuStore                  { return s.menu }
+func (s *Storage) Orders() storage.OrderStore               { return s.orders }
+func (s *Storage) Tokens() storage.TokenStore               { return s.tokens }
+func (s *Storage) Memberships() storage.MembershipStore     { return s.memberships }
+func (s *Storage) OneTimeTokens() storage.OneTimeTokenStore { return s.oneTime }
+func (s *Storage) Invitations() storage.InvitationStore     { return s.invitations }
+func (s *Storage) APIKeys() storage.APIKeyStore             { return s.apiKeys }
+func (s *Storage) Promotions() storage.PromotionStore       { return s.promotions }
+
+// clone deep-copies a document through its BSON encoding, so callers never share
+// memory with the store and see exactly what MongoDB would have persisted.
+func clone[T any](v *T) *T {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		panic("memory: cannot encode document: " + err.Error())
+	}
+	var out T
+	if err := bson.Unmarshal(data, &out); err != nil {
+		panic("memory: cannot decode document: " + err.Error())
+	}
+	return &out
+}
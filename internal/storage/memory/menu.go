@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MenuStore keeps menu items in memory.
+type MenuStore struct {
+	mu    sync.RWMutex
+	items map[primitive.ObjectID]*types.MenuItem
+}
+
+var _ storage.MenuStore = (*MenuStore)(nil)
+
+func NewMenuStore() *MenuStore {
+	return &MenuStore{items: make(map[primitive.ObjectID]*types.MenuItem)}
+}
+
+func (s *MenuStore) CreateMenuItem(ctx context.Context, item *types.MenuItem) (*types.MenuItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nameTaken(item.Restaurant, item.Name, primitive.NilObjectID) {
+		return nil, storage.ErrDuplicate
+	}
+	item.ID = primitive.NewObjectID()
+	s.items[item.ID] = clone(item)
+	return item, nil
+}
+
+// GetMenuItemByID fetches a single menu item by ID
+func (s *MenuStore) GetMenuItemByID(ctx context.Context, id primitive.ObjectID) (*types.MenuItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil, nil
+	}
+	return clone(item), nil
+}
+
+func (s *MenuStore) GetByNameAndRestaurant(ctx context.Context, name string, restaurantID primitive.ObjectID) (*types.MenuItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, item := range s.items {
+		if item.Name == name && item.Restaurant == restaurantID {
+			return clone(item), nil
+		}
+	}
+	return nil, nil
+}
+
+var menuItemSortKeys = sortKeys[types.MenuItem]{
+	"name":       func(m *types.MenuItem) any { return m.Name },
+	"category":   func(m *types.MenuItem) any { return m.Category },
+	"created_at": func(m *types.MenuItem) any { return m.CreatedAt },
+	"updated_at": func(m *types.MenuItem) any { return m.UpdatedAt },
+}
+
+// ListMenuItems returns one page of menu items matching the filter
+func (s *MenuStore) ListMenuItems(ctx context.Context, f storage.MenuItemFilter) ([]*types.MenuItem, string, error) {
+	s.mu.RLock()
+	var items []*types.MenuItem
+	for _, item := range s.items {
+		if !f.RestaurantID.IsZero() && item.Restaurant != f.RestaurantID {
+			continue
+		}
+		if f.Category != "" && item.Category != f.Category {
+			continue
+		}
+		if f.Available != nil && item.Available != *f.Available {
+			continue
+		}
+		items = append(items, clone(item))
+	}
+	s.mu.RUnlock()
+	return paginate(items, f.ListOptions, storage.MenuItemSorts, "name", menuItemSortKeys, func(m *types.MenuItem) primitive.ObjectID { return m.ID })
+}
+
+// UpdateMenuItem overwrites the editable fields of an existing menu item
+func (s *MenuStore) UpdateMenuItem(ctx context.Context, item *types.MenuItem) (*types.MenuItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.items[item.ID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if s.nameTaken(existing.Restaurant, item.Name, item.ID) {
+		return nil, storage.ErrDuplicate
+	}
+	existing.Name = item.Name
+	existing.Category = item.Category
+	existing.Price = item.Price
+	existing.Available = item.Available
+	existing.OptionGroups = item.OptionGroups
+	existing.UpdatedAt = time.Now()
+	return clone(existing), nil
+}
+
+// SetAvailability marks a menu item as orderable or sold out
+func (s *MenuStore) SetAvailability(ctx context.Context, id primitive.ObjectID, available bool) (*types.MenuItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.items[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	existing.Available = available
+	existing.UpdatedAt = time.Now()
+	return clone(existing), nil
+}
+
+// DeleteMenuItem removes a menu item
+func (s *MenuStore) DeleteMenuItem(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.items, id)
+	return nil
+}
+
+// nameTaken reports whether another item of the restaurant already uses name.
+// The caller must hold the lock.
+func (s *MenuStore) nameTaken(restaurantID primitive.ObjectID, name string, self primitive.ObjectID) bool {
+	for id, item := range s.items {
+		if item.Restaurant == restaurantID && item.Name == name && id != self {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OneTimeTokenStore keeps password reset and email verification tokens in memory.
+type OneTimeTokenStore struct {
+	mu     sync.Mutex
+	tokens map[primitive.ObjectID]*types.OneTimeToken
+}
+
+var _ storage.OneTimeTokenStore = (*OneTimeTokenStore)(nil)
+
+func NewOneTimeTokenStore() *OneTimeTokenStore {
+	return &OneTimeTokenStore{
+		tokens: make(map[primitive.ObjectID]*types.OneTimeToken),
+	}
+}
+
+// CreateOneTimeToken stores a newly issued token
+func (s *OneTimeTokenStore) CreateOneTimeToken(ctx context.Context, t *types.OneTimeToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, existing := range s.tokens {
+		if existing.ExpiresAt.Before(now) {
+			delete(s.tokens, id)
+			continue
+		}
+		if existing.TokenHash == t.TokenHash {
+			return storage.ErrDuplicate
+		}
+	}
+	if t.ID.IsZero() {
+		t.ID = primitive.NewObjectID()
+	}
+	s.tokens[t.ID] = clone(t)
+	return nil
+}
+
+// ConsumeOneTimeToken atomically marks a usable token as used and returns it
+func (s *OneTimeTokenStore) ConsumeOneTimeToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*types.OneTimeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.TokenHash != tokenHash || t.Purpose != purpose {
+			continue
+		}
+		if t.UsedAt != nil || !t.ExpiresAt.After(now) {
+			return nil, nil
+		}
+		usedAt := now
+		t.UsedAt = &usedAt
+		return clone(t), nil
+	}
+	return nil, nil
+}
+
+// InvalidateOneTimeTokens uses up every outstanding token of the user for purpose
+func (s *OneTimeTokenStore) InvalidateOneTimeTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
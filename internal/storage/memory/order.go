@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrderStore keeps orders in memory.
+type OrderStore struct {
+	mu     sync.RWMutex
+	orders map[primitive.ObjectID]*types.Order
+}
+
+var _ storage.OrderStore = (*OrderStore)(nil)
+
+func NewOrderStore() *OrderStore {
+	return &OrderStore{orders: make(map[primitive.ObjectID]*types.Order)}
+}
+
+// CreateOrder inserts a new order
+func (s *OrderStore) CreateOrder(ctx context.Context, order *types.Order) (*types.Order, error) {
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = time.Now()
+	order.ID = primitive.NewObjectID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[order.ID] = clone(order)
+	return order, nil
+}
+
+var orderSortKeys = sortKeys[types.Order]{
+	"created_at": func(o *types.Order) any { return o.CreatedAt },
+	"updated_at": func(o *types.Order) any { return o.UpdatedAt },
+}
+
+// ListOrders returns one page of orders matching the filter, newest first by default
+func (s *OrderStore) ListOrders(ctx context.Context, f storage.OrderFilter) ([]*types.Order, string, error) {
+	s.mu.RLock()
+	var orders []*types.Order
+	for _, o := range s.orders {
+		if !matchesOrder(o, f) {
+			continue
+		}
+		orders = append(orders, clone(o))
+	}
+	s.mu.RUnlock()
+	return paginate(orders, f.ListOptions, storage.OrderSorts, "-created_at", orderSortKeys, func(o *types.Order) primitive.ObjectID { return o.ID })
+}
+
+func matchesOrder(o *types.Order, f storage.OrderFilter) bool {
+	switch {
+	case f.Status != "" && o.Status != f.Status:
+		return false
+	case !f.RestaurantID.IsZero() && o.Restaurant != f.RestaurantID:
+		return false
+	case !f.UserID.IsZero() && o.UserID != f.UserID:
+		return false
+	case !f.CreatedFrom.IsZero() && o.CreatedAt.Before(f.CreatedFrom):
+		return false
+	case !f.CreatedTo.IsZero() && !o.CreatedAt.Before(f.CreatedTo):
+		return false
+	}
+	return true
+}
+
+// ListUserOrders pages through the orders placed by one user
+func (s *OrderStore) ListUserOrders(ctx context.Context, userID primitive.ObjectID, f storage.OrderFilter) ([]*types.Order, string, error) {
+	f.UserID = userID
+	return s.ListOrders(ctx, f)
+}
+
+// ListRestaurantOrders pages through the orders of one restaurant
+func (s *OrderStore) ListRestaurantOrders(ctx context.Context, restaurantID primitive.ObjectID, f storage.OrderFilter) ([]*types.Order, string, error) {
+	f.RestaurantID = restaurantID
+	return s.ListOrders(ctx, f)
+}
+
+// GetOrderByID fetches a single order by ID
+func (s *OrderStore) GetOrderByID(ctx context.Context, id primitive.ObjectID) (*types.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	return clone(o), nil
+}
+
+// UpdateOrderStatus applies a status transition as a compare-and-set on the current status
+func (s *OrderStore) UpdateOrderStatus(ctx context.Context, id primitive.ObjectID, change types.StatusChange) (*types.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if o.Status != change.From {
+		return nil, storage.ErrConflict
+	}
+	o.Status = change.To
+	o.UpdatedAt = change.At
+	o.StatusHistory = append(o.StatusHistory, change)
+	return clone(o), nil
+}
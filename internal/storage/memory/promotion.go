@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type redemptionKey struct {
+	promotionID, userID primitive.ObjectID
+}
+
+// PromotionStore keeps promotions and their redemption counts in memory.
+type PromotionStore struct {
+	mu          sync.RWMutex
+	promotions  map[primitive.ObjectID]*types.Promotion
+	redemptions map[redemptionKey]int
+}
+
+var _ storage.PromotionStore = (*PromotionStore)(nil)
+
+func NewPromotionStore() *PromotionStore {
+	return &PromotionStore{
+		promotions:  make(map[primitive.ObjectID]*types.Promotion),
+		redemptions: make(map[redemptionKey]int),
+	}
+}
+
+var promotionSortKeys = sortKeys[types.Promotion]{
+	"code":       func(p *types.Promotion) any { return p.Code },
+	"created_at": func(p *types.Promotion) any { return p.CreatedAt },
+}
+
+// CreatePromotion stores a new promotion
+func (s *PromotionStore) CreatePromotion(ctx context.Context, p *types.Promotion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.promotions {
+		if existing.Code == p.Code {
+			return storage.ErrDuplicate
+		}
+	}
+	if p.ID.IsZero() {
+		p.ID = primitive.NewObjectID()
+	}
+	s.promotions[p.ID] = clone(p)
+	return nil
+}
+
+// GetPromotion finds a promotion by ID
+func (s *PromotionStore) GetPromotion(ctx context.Context, id primitive.ObjectID) (*types.Promotion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.promotions[id]
+	if !ok {
+		return nil, nil
+	}
+	return clone(p), nil
+}
+
+// GetPromotionByCode finds a promotion by its code
+func (s *PromotionStore) GetPromotionByCode(ctx context.Context, code string) (*types.Promotion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.promotions {
+		if p.Code == code {
+			return clone(p), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListPromotions returns one page of promotions
+func (s *PromotionStore) ListPromotions(ctx context.Context, f storage.PromotionFilter) ([]*types.Promotion, string, error) {
+	s.mu.RLock()
+	var promotions []*types.Promotion
+	for _, p := range s.promotions {
+		if !f.RestaurantID.IsZero() && (p.RestaurantID == nil || *p.RestaurantID != f.RestaurantID) {
+			continue
+		}
+		if f.GlobalOnly && p.RestaurantID != nil {
+			continue
+		}
+		if f.Active != nil && p.Active != *f.Active {
+			continue
+		}
+		promotions = append(promotions, clone(p))
+	}
+	s.mu.RUnlock()
+	return paginate(promotions, f.ListOptions, storage.PromotionSorts, "-created_at", promotionSortKeys, func(p *types.Promotion) primitive.ObjectID { return p.ID })
+}
+
+// UpdatePromotion overwrites the editable fields of an existing promotion
+func (s *PromotionStore) UpdatePromotion(ctx context.Context, p *types.Promotion) (*types.Promotion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.promotions[p.ID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	existing.Description = p.Description
+	existing.StartsAt = p.StartsAt
+	existing.EndsAt = p.EndsAt
+	existing.MaxRedemptions = p.MaxRedemptions
+	existing.MaxPerUser = p.MaxPerUser
+	existing.MinOrder = p.MinOrder
+	existing.Active = p.Active
+	existing.UpdatedAt = time.Now()
+	return clone(existing), nil
+}
+
+// Redeem counts one use of a promotion while both of its limits allow it
+func (s *PromotionStore) Redeem(ctx context.Context, id, userID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.promotions[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	key := redemptionKey{id, userID}
+	if p.MaxPerUser > 0 && s.redemptions[key] >= p.MaxPerUser {
+		return storage.ErrUserRedemptionLimit
+	}
+	if p.MaxRedemptions > 0 && p.Redemptions >= p.MaxRedemptions {
+		return storage.ErrRedemptionLimit
+	}
+	s.redemptions[key]++
+	p.Redemptions++
+	return nil
+}
+
+// Release gives back one use of a promotion
+func (s *PromotionStore) Release(ctx context.Context, id, userID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.promotions[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	key := redemptionKey{id, userID}
+	if s.redemptions[key] > 0 {
+		s.redemptions[key]--
+	}
+	if p.Redemptions > 0 {
+		p.Redemptions--
+	}
+	return nil
+}
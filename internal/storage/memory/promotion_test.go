@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage/storagetest"
+)
+
+func TestPromotionRedeemConcurrently(t *testing.T) {
+	storagetest.RedeemConcurrently(t, NewPromotionStore())
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RestaurantStore keeps restaurants in memory.
+type RestaurantStore struct {
+	mu          sync.RWMutex
+	restaurants map[primitive.ObjectID]*types.Restaurant
+}
+
+var _ storage.RestaurantStore = (*RestaurantStore)(nil)
+
+func NewRestaurantStore() *RestaurantStore {
+	return &RestaurantStore{restaurants: make(map[primitive.ObjectID]*types.Restaurant)}
+}
+
+// Insert a new restaurant
+func (s *RestaurantStore) CreateRestaurant(ctx context.Context, r *types.Restaurant) (*types.Restaurant, error) {
+	now := time.Now()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nameTaken(r.Name, primitive.NilObjectID) {
+		return nil, storage.ErrDuplicate
+	}
+	r.ID = primitive.NewObjectID()
+	s.restaurants[r.ID] = clone(r)
+	return r, nil
+}
+
+// GetByName finds a restaurant by name
+func (s *RestaurantStore) GetByName(ctx context.Context, name string) (*types.Restaurant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.restaurants {
+		if r.Name == name {
+			return clone(r), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetByID finds a restaurant by its hex ID
+func (s *RestaurantStore) GetByID(ctx context.Context, id string) (*types.Restaurant, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid restaurant ID: %v", err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.restaurants[objID]
+	if !ok {
+		return nil, nil
+	}
+	return clone(r), nil
+}
+
+var restaurantSortKeys = sortKeys[types.Restaurant]{
+	"name":       func(r *types.Restaurant) any { return r.Name },
+	"created_at": func(r *types.Restaurant) any { return r.CreatedAt },
+	"updated_at": func(r *types.Restaurant) any { return r.UpdatedAt },
+}
+
+// ListRestaurants returns one page of restaurants matching the filter
+func (s *RestaurantStore) ListRestaurants(ctx context.Context, f storage.RestaurantFilter) ([]*types.Restaurant, string, error) {
+	name := strings.ToLower(f.Name)
+	s.mu.RLock()
+	var restaurants []*types.Restaurant
+	for _, r := range s.restaurants {
+		if f.ActiveOnly && !r.IsActive {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(r.Name), name) {
+			continue
+		}
+		restaurants = append(restaurants, clone(r))
+	}
+	s.mu.RUnlock()
+	return paginate(restaurants, f.ListOptions, storage.RestaurantSorts, "name", restaurantSortKeys, func(r *types.Restaurant) primitive.ObjectID { return r.ID })
+}
+
+// UpdateRestaurant overwrites the editable fields of an existing restaurant
+func (s *RestaurantStore) UpdateRestaurant(ctx context.Context, r *types.Restaurant) (*types.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.restaurants[r.ID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if s.nameTaken(r.Name, r.ID) {
+		return nil, storage.ErrDuplicate
+	}
+	existing.Name = r.Name
+	existing.Address = r.Address
+	existing.Phone = r.Phone
+	existing.Description = r.Description
+	existing.IsActive = r.IsActive
+	existing.UpdatedAt = time.Now()
+	return clone(existing), nil
+}
+
+func (s *RestaurantStore) SetTaxPolicy(ctx context.Context, id primitive.ObjectID, policy types.TaxPolicy) (*types.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.restaurants[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	// Copied, so the stored rules do not share the caller's slices
+	existing.Tax = *clone(&policy)
+	existing.UpdatedAt = time.Now()
+	return clone(existing), nil
+}
+
+// nameTaken reports whether a restaurant other than self already uses name.
+// The caller must hold the lock.
+func (s *RestaurantStore) nameTaken(name string, self primitive.ObjectID) bool {
+	for id, r := range s.restaurants {
+		if r.Name == name && id != self {
+			return true
+		}
+	}
+	return false
+}
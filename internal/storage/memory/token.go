@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenStore keeps refresh tokens and revocations in memory.
+type TokenStore struct {
+	mu              sync.RWMutex
+	refreshTokens   map[primitive.ObjectID]*types.RefreshToken
+	revokedTokens   map[string]time.Time // jti -> expiry
+	userRevocations map[primitive.ObjectID]time.Time
+}
+
+var _ storage.TokenStore = (*TokenStore)(nil)
+
+func NewTokenStore() *TokenStore {
+	return &TokenStore{
+		refreshTokens:   make(map[primitive.ObjectID]*types.RefreshToken),
+		revokedTokens:   make(map[string]time.Time),
+		userRevocations: make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// CreateRefreshToken stores a newly issued refresh token
+func (s *TokenStore) CreateRefreshToken(ctx context.Context, t *types.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneExpired()
+	return s.insert(t)
+}
+
+// GetRefreshToken finds a refresh token by the hash of its value
+func (s *TokenStore) GetRefreshToken(ctx context.Context, tokenHash string) (*types.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.refreshTokens {
+		if t.TokenHash == tokenHash {
+			return clone(t), nil
+		}
+	}
+	return nil, nil
+}
+
+// RotateRefreshToken revokes old, provided nobody used it yet, and stores next
+func (s *TokenStore) RotateRefreshToken(ctx context.Context, old primitive.ObjectID, next *types.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.refreshTokens[old]
+	if !ok || t.RevokedAt != nil {
+		return storage.ErrConflict
+	}
+	if next.ID.IsZero() {
+		next.ID = primitive.NewObjectID()
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	t.ReplacedBy = next.ID
+	return s.insert(next)
+}
+
+// RevokeRefreshFamily revokes every token descending from the same login
+func (s *TokenStore) RevokeRefreshFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.refreshTokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// RevokeAccessToken records a revoked jti until the token would have expired anyway
+func (s *TokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneExpired()
+	s.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the jti was revoked
+func (s *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.revokedTokens[jti]
+	return ok, nil
+}
+
+// RevokeUserTokens cuts off every session of a user
+func (s *TokenStore) RevokeUserTokens(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userRevocations[userID] = at
+	for _, t := range s.refreshTokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			revokedAt := at
+			t.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+// UserTokensRevokedAt returns when all sessions of the user were last revoked
+func (s *TokenStore) UserTokensRevokedAt(ctx context.Context, userID primitive.ObjectID) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userRevocations[userID], nil
+}
+
+// insert stores t under a fresh ID. The caller must hold the lock.
+func (s *TokenStore) insert(t *types.RefreshToken) error {
+	if t.ID.IsZero() {
+		t.ID = primitive.NewObjectID()
+	}
+	for _, existing := range s.refreshTokens {
+		if existing.TokenHash == t.TokenHash {
+			return storage.ErrDuplicate
+		}
+	}
+	s.refreshTokens[t.ID] = clone(t)
+	return nil
+}
+
+// pruneExpired drops what MongoDB's TTL indexes would have removed. The caller must hold the lock.
+func (s *TokenStore) pruneExpired() {
+	now := time.Now()
+	for id, t := range s.refreshTokens {
+		if t.ExpiresAt.Before(now) {
+			delete(s.refreshTokens, id)
+		}
+	}
+	for jti, exp := range s.revokedTokens {
+		if exp.Before(now) {
+			delete(s.revokedTokens, jti)
+		}
+	}
+}
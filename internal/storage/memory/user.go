@@ -0,0 +1,283 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserStore keeps users in memory.
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]*types.User
+	// bootstrapped is set once CreateFirstAdmin succeeded
+	bootstrapped bool
+}
+
+var _ storage.UserStore = (*UserStore)(nil)
+
+// NewUserStore initializes an empty UserStore.
+func NewUserStore() *UserStore {
+	return &UserStore{users: make(map[primitive.ObjectID]*types.User)}
+}
+
+// CreateUser stores a new user, assigning an ID when none is set.
+func (s *UserStore) CreateUser(ctx context.Context, u *types.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.users {
+		if existing.Email == u.Email {
+			return storage.ErrDuplicate
+		}
+	}
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	s.users[u.ID] = clone(u)
+	return nil
+}
+
+// CreateFirstAdmin creates u unless the first admin was created already.
+func (s *UserStore) CreateFirstAdmin(ctx context.Context, u *types.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bootstrapped {
+		return storage.ErrConflict
+	}
+	for _, existing := range s.users {
+		if existing.Email == u.Email {
+			return storage.ErrDuplicate
+		}
+	}
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	s.users[u.ID] = clone(u)
+	s.bootstrapped = true
+	return nil
+}
+
+// GetUserByEmail retrieves a user by email address.
+func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if u.Email == email {
+			return clone(u), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUserByID retrieves a user by its hex ID.
+func (s *UserStore) GetUserByID(ctx context.Context, id string) (*types.User, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[objID]
+	if !ok {
+		return nil, nil
+	}
+	return clone(u), nil
+}
+
+// GetUserByIdentity finds the user linked to subject at issuer.
+func (s *UserStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*types.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if hasIdentity(u, issuer, subject) {
+			return clone(u), nil
+		}
+	}
+	return nil, nil
+}
+
+// LinkIdentity adds an external identity unless some user already has it.
+func (s *UserStore) LinkIdentity(ctx context.Context, userID primitive.ObjectID, id types.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	for _, other := range s.users {
+		if hasIdentity(other, id.Issuer, id.Subject) {
+			return storage.ErrDuplicate
+		}
+	}
+	u.Identities = append(u.Identities, id)
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func hasIdentity(u *types.User, issuer, subject string) bool {
+	for _, id := range u.Identities {
+		if id.Issuer == issuer && id.Subject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+var userSortKeys = sortKeys[types.User]{
+	"name":       func(u *types.User) any { return u.Name },
+	"email":      func(u *types.User) any { return u.Email },
+	"created_at": func(u *types.User) any { return u.CreatedAt },
+}
+
+// ListUsers returns one page of users matching the filter.
+func (s *UserStore) ListUsers(ctx context.Context, f storage.UserFilter) ([]*types.User, string, error) {
+	s.mu.RLock()
+	var users []*types.User
+	for _, u := range s.users {
+		if f.Role != "" && u.Role != f.Role {
+			continue
+		}
+		if f.Email != "" && u.Email != f.Email {
+			continue
+		}
+		users = append(users, clone(u))
+	}
+	s.mu.RUnlock()
+	return paginate(users, f.ListOptions, storage.UserSorts, "created_at", userSortKeys, func(u *types.User) primitive.ObjectID { return u.ID })
+}
+
+// UpdateUser overwrites the profile fields, role and password hash of the user with u.ID.
+func (s *UserStore) UpdateUser(ctx context.Context, u *types.User) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.users[u.ID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	for id, other := range s.users {
+		if other.Email == u.Email && id != u.ID {
+			return nil, storage.ErrDuplicate
+		}
+	}
+	existing.Name = u.Name
+	existing.Email = u.Email
+	existing.Password = u.Password
+	existing.Role = u.Role
+	existing.EmailVerified = u.EmailVerified
+	existing.EmailVerifiedAt = u.EmailVerifiedAt
+	existing.UpdatedAt = time.Now()
+	return clone(existing), nil
+}
+
+// DeleteUser removes a user by ID.
+func (s *UserStore) DeleteUser(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// AddLockoutEvent appends e to the user's lockout history, trimmed to the newest entries.
+func (s *UserStore) AddLockoutEvent(ctx context.Context, userID primitive.ObjectID, e types.LockoutEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	u.LockoutEvents = append(u.LockoutEvents, e)
+	if n := len(u.LockoutEvents); n > storage.MaxLockoutEvents {
+		u.LockoutEvents = u.LockoutEvents[n-storage.MaxLockoutEvents:]
+	}
+	return nil
+}
+
+// SetPassword replaces the stored password hash.
+func (s *UserStore) SetPassword(ctx context.Context, userID primitive.ObjectID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	u.Password = hash
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// MarkEmailVerified flags email as verified if it is still the user's address.
+func (s *UserStore) MarkEmailVerified(ctx context.Context, userID primitive.ObjectID, email string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok || u.Email != email {
+		return storage.ErrNotFound
+	}
+	u.EmailVerified = true
+	u.EmailVerifiedAt = &at
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetMFA replaces the user's two-factor settings.
+func (s *UserStore) SetMFA(ctx context.Context, userID primitive.ObjectID, m types.MFA) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	u.MFA = *clone(&m)
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// UseMFAStep advances the last accepted TOTP step, refusing replays.
+func (s *UserStore) UseMFAStep(ctx context.Context, userID primitive.ObjectID, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok || u.MFA.LastUsedStep >= step {
+		return storage.ErrConflict
+	}
+	u.MFA.LastUsedStep = step
+	return nil
+}
+
+// UseRecoveryCode removes one unused recovery code.
+func (s *UserStore) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	i := slices.Index(u.MFA.RecoveryCodes, hash)
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	u.MFA.RecoveryCodes = slices.Delete(u.MFA.RecoveryCodes, i, i+1)
+	return nil
+}
+
+// SetRole changes the user's global role.
+func (s *UserStore) SetRole(ctx context.Context, userID primitive.ObjectID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	u.Role = role
+	u.UpdatedAt = time.Now()
+	return nil
+}
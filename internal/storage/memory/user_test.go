@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUseMFAStep(t *testing.T) {
+	ctx := context.Background()
+	s := NewUserStore()
+	u := &types.User{Email: "a@x.io"}
+	if err := s.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		step    int64
+		wantErr error
+	}{
+		{100, nil},
+		{100, storage.ErrConflict},
+		{99, storage.ErrConflict},
+		{101, nil},
+		{100, storage.ErrConflict},
+		{105, nil},
+	} {
+		if err := s.UseMFAStep(ctx, u.ID, tc.step); !errors.Is(err, tc.wantErr) {
+			t.Fatalf("UseMFAStep(%d) = %v, want %v", tc.step, err, tc.wantErr)
+		}
+	}
+	got, _ := s.GetUserByID(ctx, u.ID.Hex())
+	if got.MFA.LastUsedStep != 105 {
+		t.Errorf("LastUsedStep = %d, want 105", got.MFA.LastUsedStep)
+	}
+
+	if err := s.UseMFAStep(ctx, primitive.NewObjectID(), 1); err == nil {
+		t.Error("UseMFAStep succeeded for an unknown user")
+	}
+}
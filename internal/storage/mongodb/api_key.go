@@ -0,0 +1,107 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// APIKeyStore keeps the API keys of machine clients.
+type APIKeyStore struct {
+	Collection *mongo.Collection
+}
+
+var _ storage.APIKeyStore = (*APIKeyStore)(nil)
+
+func NewAPIKeyStore(collection *mongo.Collection) *APIKeyStore {
+	return &APIKeyStore{
+		Collection: collection,
+	}
+}
+
+// CreateAPIKey stores a new API key
+func (s *APIKeyStore) CreateAPIKey(ctx context.Context, k *types.APIKey) error {
+	if k.ID.IsZero() {
+		k.ID = primitive.NewObjectID()
+	}
+	_, err := s.Collection.InsertOne(ctx, k)
+	return translateWriteError(err)
+}
+
+// GetAPIKey finds an API key by ID
+func (s *APIKeyStore) GetAPIKey(ctx context.Context, id primitive.ObjectID) (*types.APIKey, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetAPIKeyByPrefix finds an API key by the public prefix of the key
+func (s *APIKeyStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*types.APIKey, error) {
+	return s.findOne(ctx, bson.M{"prefix": prefix})
+}
+
+func (s *APIKeyStore) findOne(ctx context.Context, filter bson.M) (*types.APIKey, error) {
+	var k types.APIKey
+	err := s.Collection.FindOne(ctx, filter).Decode(&k)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+// ListAPIKeys returns one page of API keys
+func (s *APIKeyStore) ListAPIKeys(ctx context.Context, f storage.APIKeyFilter) ([]*types.APIKey, string, error) {
+	filter := bson.M{}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if !f.ActiveAt.IsZero() {
+		filter["revoked_at"] = nil
+		filter["$or"] = bson.A{
+			bson.M{"expires_at": nil},
+			bson.M{"expires_at": bson.M{"$gt": f.ActiveAt}},
+		}
+	}
+	return findPage[types.APIKey](ctx, s.Collection, filter, f.ListOptions, storage.APIKeySorts, "-created_at")
+}
+
+// TouchAPIKey records when the key was last used
+func (s *APIKeyStore) TouchAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := s.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": at}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// RevokeAPIKey disables a key that is not revoked yet
+func (s *APIKeyStore) RevokeAPIKey(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	res, err := s.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	n, err := s.Collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return storage.ErrConflict
+}
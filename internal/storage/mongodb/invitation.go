@@ -0,0 +1,118 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvitationStore keeps role invitations.
+type InvitationStore struct {
+	Collection *mongo.Collection
+}
+
+var _ storage.InvitationStore = (*InvitationStore)(nil)
+
+func NewInvitationStore(collection *mongo.Collection) *InvitationStore {
+	return &InvitationStore{
+		Collection: collection,
+	}
+}
+
+// CreateInvitation stores a new invitation
+func (s *InvitationStore) CreateInvitation(ctx context.Context, inv *types.Invitation) error {
+	if inv.ID.IsZero() {
+		inv.ID = primitive.NewObjectID()
+	}
+	_, err := s.Collection.InsertOne(ctx, inv)
+	return translateWriteError(err)
+}
+
+// GetInvitation finds an invitation by ID
+func (s *InvitationStore) GetInvitation(ctx context.Context, id primitive.ObjectID) (*types.Invitation, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetInvitationByToken finds an invitation by the hash of its token
+func (s *InvitationStore) GetInvitationByToken(ctx context.Context, tokenHash string) (*types.Invitation, error) {
+	return s.findOne(ctx, bson.M{"token_hash": tokenHash})
+}
+
+func (s *InvitationStore) findOne(ctx context.Context, filter bson.M) (*types.Invitation, error) {
+	var inv types.Invitation
+	err := s.Collection.FindOne(ctx, filter).Decode(&inv)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ListInvitations returns one page of invitations
+func (s *InvitationStore) ListInvitations(ctx context.Context, f storage.InvitationFilter) ([]*types.Invitation, string, error) {
+	filter := bson.M{}
+	if !f.RestaurantID.IsZero() {
+		filter["restaurant_id"] = f.RestaurantID
+	}
+	if f.Email != "" {
+		filter["email"] = f.Email
+	}
+	if !f.PendingAt.IsZero() {
+		filter["accepted_at"] = nil
+		filter["revoked_at"] = nil
+		filter["expires_at"] = bson.M{"$gt": f.PendingAt}
+	}
+	return findPage[types.Invitation](ctx, s.Collection, filter, f.ListOptions, storage.InvitationSorts, "-created_at")
+}
+
+// AcceptInvitation marks a pending invitation as accepted
+func (s *InvitationStore) AcceptInvitation(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error {
+	return s.transition(ctx,
+		bson.M{"_id": id, "accepted_at": nil, "revoked_at": nil, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"accepted_at": now, "accepted_by": userID}},
+	)
+}
+
+// ReopenInvitation clears the acceptance of an invitation
+func (s *InvitationStore) ReopenInvitation(ctx context.Context, id primitive.ObjectID) error {
+	return s.transition(ctx,
+		bson.M{"_id": id},
+		bson.M{"$unset": bson.M{"accepted_at": "", "accepted_by": ""}},
+	)
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (s *InvitationStore) RevokeInvitation(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return s.transition(ctx,
+		bson.M{"_id": id, "accepted_at": nil, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+}
+
+// transition applies update when filter matches, telling a missing invitation
+// (storage.ErrNotFound) apart from one in the wrong state (storage.ErrConflict).
+func (s *InvitationStore) transition(ctx context.Context, filter, update bson.M) error {
+	res, err := s.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	n, err := s.Collection.CountDocuments(ctx, bson.M{"_id": filter["_id"]})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return storage.ErrConflict
+}
@@ -0,0 +1,84 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// findPage runs a bounded, cursor-paginated query. filter holds the field filters;
+// the documents are ordered by the requested sort field and then by _id.
+func findPage[T any](ctx context.Context, coll *mongo.Collection, filter bson.M, opts storage.ListOptions, allowed []string, defaultSort string) ([]*T, string, error) {
+	field, desc, err := opts.SortField(allowed, defaultSort)
+	if err != nil {
+		return nil, "", err
+	}
+	sortKey := field
+	dir, cmp := 1, "$gt"
+	if desc {
+		sortKey = "-" + field
+		dir, cmp = -1, "$lt"
+	}
+
+	after, err := storage.DecodeCursor(opts.Cursor, sortKey)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != nil {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{cmp: after.Value()}},
+			bson.M{field: after.Value(), "_id": bson.M{cmp: after.ID}},
+		}}}}
+	}
+
+	limit := opts.PageSize()
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: field, Value: dir}, {Key: "_id", Value: dir}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := coll.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(ctx)
+
+	var (
+		docs []*T
+		last bson.Raw
+	)
+	for cursor.Next(ctx) {
+		if len(docs) == limit {
+			// There is one more document, so the client gets a cursor to continue
+			id, _ := last.Lookup("_id").ObjectIDOK()
+			return docs, storage.NewCursor(sortKey, id, rawSortValue(last.Lookup(field))).Encode(), nil
+		}
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, "", err
+		}
+		docs = append(docs, &doc)
+		last = append(bson.Raw(nil), cursor.Current...)
+	}
+	return docs, "", cursor.Err()
+}
+
+func rawSortValue(v bson.RawValue) any {
+	switch v.Type {
+	case bson.TypeDateTime:
+		return v.Time()
+	case bson.TypeString:
+		return v.StringValue()
+	}
+	return nil
+}
+
+// objectIDOrAny adds an equality filter on key unless id is zero.
+func objectIDOrAny(filter bson.M, key string, id primitive.ObjectID) {
+	if !id.IsZero() {
+		filter[key] = id
+	}
+}
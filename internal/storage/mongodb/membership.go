@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MembershipStore defines MongoDB operations for restaurant staff memberships.
+type MembershipStore struct {
+	Collection *mongo.Collection
+}
+
+var _ storage.MembershipStore = (*MembershipStore)(nil)
+
+// NewMembershipStore initializes a new MembershipStore.
+func NewMembershipStore(collection *mongo.Collection) *MembershipStore {
+	return &MembershipStore{
+		Collection: collection,
+	}
+}
+
+// SetMembership upserts the membership on the unique (user_id, restaurant_id) pair.
+func (s *MembershipStore) SetMembership(ctx context.Context, m *types.Membership) (*types.Membership, error) {
+	now := time.Now()
+	var stored types.Membership
+	err := s.Collection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": m.UserID, "restaurant_id": m.RestaurantID},
+		bson.M{
+			"$set": bson.M{"role": m.Role, "updated_at": now},
+			"$setOnInsert": bson.M{
+				"created_by": m.CreatedBy,
+				"created_at": now,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&stored)
+	if err != nil {
+		return nil, translateWriteError(err)
+	}
+	return &stored, nil
+}
+
+// GetMembership retrieves the membership of a user at a restaurant.
+func (s *MembershipStore) GetMembership(ctx context.Context, userID, restaurantID primitive.ObjectID) (*types.Membership, error) {
+	var m types.Membership
+	err := s.Collection.FindOne(ctx, bson.M{"user_id": userID, "restaurant_id": restaurantID}).Decode(&m)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ListUserMemberships returns every restaurant a user works at.
+func (s *MembershipStore) ListUserMemberships(ctx context.Context, userID primitive.ObjectID) ([]*types.Membership, error) {
+	return s.find(ctx, bson.M{"user_id": userID})
+}
+
+// ListRestaurantMembers returns the staff of a restaurant.
+func (s *MembershipStore) ListRestaurantMembers(ctx context.Context, restaurantID primitive.ObjectID) ([]*types.Membership, error) {
+	return s.find(ctx, bson.M{"restaurant_id": restaurantID})
+}
+
+// DeleteMembership removes a user from a restaurant's staff.
+func (s *MembershipStore) DeleteMembership(ctx context.Context, userID, restaurantID primitive.ObjectID) error {
+	res, err := s.Collection.DeleteOne(ctx, bson.M{"user_id": userID, "restaurant_id": restaurantID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *MembershipStore) find(ctx context.Context, filter bson.M) ([]*types.Membership, error) {
+	cursor, err := s.Collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var memberships []*types.Membership
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
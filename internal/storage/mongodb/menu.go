@@ -3,17 +3,22 @@ package mongodb
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MenuStore struct {
 	Collection *mongo.Collection
 }
 
+var _ storage.MenuStore = (*MenuStore)(nil)
+
 func NewMenuStore(collection *mongo.Collection) *MenuStore {
 	return &MenuStore{Collection: collection}
 }
@@ -21,12 +26,25 @@ func NewMenuStore(collection *mongo.Collection) *MenuStore {
 func (s *MenuStore) CreateMenuItem(ctx context.Context, item *types.MenuItem) (*types.MenuItem, error) {
 	result, err := s.Collection.InsertOne(ctx, item)
 	if err != nil {
-		return nil, err
+		return nil, translateWriteError(err)
 	}
 	item.ID = result.InsertedID.(primitive.ObjectID)
 	return item, nil
 }
 
+// GetMenuItemByID fetches a single menu item by ID
+func (s *MenuStore) GetMenuItemByID(ctx context.Context, id primitive.ObjectID) (*types.MenuItem, error) {
+	var item types.MenuItem
+	err := s.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&item)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
 func (s *MenuStore) GetByNameAndRestaurant(ctx context.Context, name string, restaurantID primitive.ObjectID) (*types.MenuItem, error) {
 	filter := bson.M{"name": name, "restaurant_id": restaurantID}
 	var item types.MenuItem
@@ -40,29 +58,58 @@ func (s *MenuStore) GetByNameAndRestaurant(ctx context.Context, name string, res
 	return &item, nil
 }
 
-func (s *MenuStore) GetByRestaurant(ctx context.Context, restaurantID string) ([]*types.MenuItem, error) {
+// ListMenuItems returns one page of menu items matching the filter
+func (s *MenuStore) ListMenuItems(ctx context.Context, f storage.MenuItemFilter) ([]*types.MenuItem, string, error) {
 	filter := bson.M{}
-	if restaurantID != "" {
-		id, err := primitive.ObjectIDFromHex(restaurantID)
-		if err != nil {
-			return nil, err
-		}
-		filter["restaurant_id"] = id
+	objectIDOrAny(filter, "restaurant_id", f.RestaurantID)
+	if f.Category != "" {
+		filter["category"] = f.Category
 	}
+	if f.Available != nil {
+		filter["available"] = *f.Available
+	}
+	return findPage[types.MenuItem](ctx, s.Collection, filter, f.ListOptions, storage.MenuItemSorts, "name")
+}
+
+// UpdateMenuItem overwrites the editable fields of an existing menu item
+func (s *MenuStore) UpdateMenuItem(ctx context.Context, item *types.MenuItem) (*types.MenuItem, error) {
+	item.UpdatedAt = time.Now()
+	return s.findAndSet(ctx, item.ID, bson.M{
+		"name":          item.Name,
+		"category":      item.Category,
+		"price":         item.Price,
+		"available":     item.Available,
+		"option_groups": item.OptionGroups,
+		"updated_at":    item.UpdatedAt,
+	})
+}
 
-	cursor, err := s.Collection.Find(ctx, filter)
+// SetAvailability marks a menu item as orderable or sold out
+func (s *MenuStore) SetAvailability(ctx context.Context, id primitive.ObjectID, available bool) (*types.MenuItem, error) {
+	return s.findAndSet(ctx, id, bson.M{"available": available, "updated_at": time.Now()})
+}
+
+// DeleteMenuItem removes a menu item. Existing orders keep their snapshot of it.
+func (s *MenuStore) DeleteMenuItem(ctx context.Context, id primitive.ObjectID) error {
+	result, err := s.Collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer cursor.Close(ctx)
+	if result.DeletedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
 
-	var items []*types.MenuItem
-	for cursor.Next(ctx) {
-		var m types.MenuItem
-		if err := cursor.Decode(&m); err != nil {
-			return nil, err
+func (s *MenuStore) findAndSet(ctx context.Context, id primitive.ObjectID, fields bson.M) (*types.MenuItem, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var item types.MenuItem
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, opts).Decode(&item)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, storage.ErrNotFound
 		}
-		items = append(items, &m)
+		return nil, translateWriteError(err)
 	}
-	return items, nil
+	return &item, nil
 }
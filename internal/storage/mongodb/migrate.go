@@ -0,0 +1,388 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationsCollection records which schema versions have been applied.
+const migrationsCollection = "schema_migrations"
+
+// Migration is one versioned schema change. Up must be idempotent: if two
+// instances start at the same time both may run it before either records it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, m *MongoDb) error
+}
+
+// appliedMigration is the schema_migrations document for an applied version.
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// migrations must stay sorted by version. Never edit an applied migration,
+// add a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "indexes for paginated list queries",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			// Every index ends with _id because pages are ordered by (sort field, _id)
+			"orders": {
+				{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+				{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+				// "my orders" and per-restaurant order boards filtered by status
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+			},
+			"restaurants": {
+				{Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+			},
+			"menu": {
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "category", Value: 1}, {Key: "_id", Value: 1}}},
+			},
+			"users": {
+				{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+				{Keys: bson.D{{Key: "role", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     2,
+		Description: "unique user emails, restaurant names and menu item names per restaurant",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"users": {
+				{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true).SetName("uniq_email")},
+			},
+			"restaurants": {
+				{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true).SetName("uniq_name")},
+			},
+			"menu": {
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetUnique(true).SetName("uniq_restaurant_name")},
+			},
+		}),
+	},
+	{
+		Version:     3,
+		Description: "refresh tokens and revoked access tokens",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"refresh_tokens": {
+				{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "family_id", Value: 1}}},
+				{Keys: bson.D{{Key: "user_id", Value: 1}}},
+				{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+			},
+			"revoked_tokens": {
+				{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+			},
+		}),
+	},
+	{
+		Version:     4,
+		Description: "restaurant staff memberships",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"memberships": {
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "restaurant_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("uniq_user_restaurant")},
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     5,
+		Description: "lowercase user emails",
+		Up:          migrateEmailCase,
+	},
+	{
+		Version:     6,
+		Description: "password reset and email verification tokens",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"one_time_tokens": {
+				{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+				{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+			},
+		}),
+	},
+	{
+		Version:     7,
+		Description: "role invitations",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"invitations": {
+				{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: 1}}},
+				{Keys: bson.D{{Key: "email", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     8,
+		Description: "admin bootstrap marker for databases that already have an admin",
+		Up:          migrateBootstrapMarker,
+	},
+	{
+		Version:     9,
+		Description: "API keys of machine clients",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"api_keys": {
+				{Keys: bson.D{{Key: "prefix", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     10,
+		Description: "external identities linked to users",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			// Partial, so the many users without identities do not collide on
+			// null. Being multikey, it also pairs issuers and subjects of
+			// different identities of one user, which is harmless while only
+			// one provider is configured.
+			"users": {
+				{
+					Keys: bson.D{{Key: "identities.issuer", Value: 1}, {Key: "identities.subject", Value: 1}},
+					Options: options.Index().SetUnique(true).SetName("uniq_identity").
+						SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+				},
+			},
+		}),
+	},
+	{
+		Version:     11,
+		Description: "restaurant currencies and prices in integer minor units",
+		Up:          migrateMoney,
+	},
+	{
+		Version:     12,
+		Description: "promotions and their per-user redemption counts",
+		Up: createIndexes(map[string][]mongo.IndexModel{
+			"promotions": {
+				{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true).SetName("uniq_code")},
+				{Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+			},
+			// Redeem relies on this index to enforce the per-user limit
+			"promotion_redemptions": {
+				{Keys: bson.D{{Key: "promotion_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			},
+		}),
+	},
+}
+
+// createIndexes returns a migration step creating the given indexes per collection.
+func createIndexes(indexes map[string][]mongo.IndexModel) func(ctx context.Context, m *MongoDb) error {
+	return func(ctx context.Context, m *MongoDb) error {
+		for collection, models := range indexes {
+			if _, err := m.Db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+				return fmt.Errorf("create indexes on %s: %w", collection, err)
+			}
+		}
+		return nil
+	}
+}
+
+// Migrate applies every migration that is not yet recorded in schema_migrations,
+// in version order, and returns the versions it applied.
+func (m *MongoDb) Migrate(ctx context.Context) ([]int, error) {
+	coll := m.Db.Collection(migrationsCollection)
+
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var done []appliedMigration
+	if err := cursor.All(ctx, &done); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool, len(done))
+	for _, d := range done {
+		applied[d.Version] = true
+	}
+
+	var versions []int
+	for _, mig := range migrations {
+		if applied[mig.Version] {
+			continue
+		}
+		slog.Info("Applying migration", slog.Int("version", mig.Version), slog.String("description", mig.Description))
+		if err := mig.Up(ctx, m); err != nil {
+			return versions, fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, err)
+		}
+		_, err := coll.InsertOne(ctx, appliedMigration{
+			Version:     mig.Version,
+			Description: mig.Description,
+			AppliedAt:   time.Now(),
+		})
+		// Another instance finishing the same migration first is fine
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return versions, fmt.Errorf("record migration %d: %w", mig.Version, err)
+		}
+		versions = append(versions, mig.Version)
+	}
+	return versions, nil
+}
+
+// migrateMoney gives every restaurant without a currency the configured
+// default and rewrites the float prices of its menu items and orders as Money
+// in its currency. Only numeric prices are rewritten, so it can run again.
+func migrateMoney(ctx context.Context, m *MongoDb) error {
+	restaurants := m.Db.Collection("restaurants")
+	noCurrency := bson.M{"$or": bson.A{bson.M{"currency": bson.M{"$exists": false}}, bson.M{"currency": ""}}}
+	if _, err := restaurants.UpdateMany(ctx, noCurrency, bson.M{"$set": bson.M{"currency": m.DefaultCurrency}}); err != nil {
+		return fmt.Errorf("set restaurant currencies: %w", err)
+	}
+
+	cursor, err := restaurants.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"currency": 1}))
+	if err != nil {
+		return err
+	}
+	var docs []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Currency string             `bson:"currency"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return err
+	}
+	currencies := make(map[primitive.ObjectID]string, len(docs))
+	for _, d := range docs {
+		currencies[d.ID] = d.Currency
+	}
+	currencyOf := func(restaurantID primitive.ObjectID) string {
+		if c, ok := currencies[restaurantID]; ok {
+			return c
+		}
+		return m.DefaultCurrency
+	}
+
+	numeric := bson.M{"$type": "number"}
+	type legacyDoc struct {
+		ID           primitive.ObjectID `bson:"_id"`
+		RestaurantID primitive.ObjectID `bson:"restaurant_id"`
+		Price        any                `bson:"price"`
+		TotalPrice   any                `bson:"total_price"`
+		Items        []bson.M           `bson:"items"`
+	}
+
+	menu := m.Db.Collection("menu")
+	cursor, err = menu.Find(ctx, bson.M{"price": numeric})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc legacyDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		price, err := legacyMoney(doc.Price, currencyOf(doc.RestaurantID))
+		if err != nil {
+			return fmt.Errorf("menu item %s: %w", doc.ID.Hex(), err)
+		}
+		if _, err := menu.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"price": price}}); err != nil {
+			return fmt.Errorf("menu item %s: %w", doc.ID.Hex(), err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	orders := m.Db.Collection("orders")
+	cursor, err = orders.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"total_price": numeric},
+		bson.M{"items.price": numeric},
+		bson.M{"items.line_total": numeric},
+	}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc legacyDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		currency := currencyOf(doc.RestaurantID)
+		for _, item := range doc.Items {
+			for _, field := range []string{"price", "line_total"} {
+				if item[field], err = legacyMoney(item[field], currency); err != nil {
+					return fmt.Errorf("order %s: %s: %w", doc.ID.Hex(), field, err)
+				}
+			}
+		}
+		total, err := legacyMoney(doc.TotalPrice, currency)
+		if err != nil {
+			return fmt.Errorf("order %s: total_price: %w", doc.ID.Hex(), err)
+		}
+		update := bson.M{"$set": bson.M{
+			"total_price": total,
+			"items":       doc.Items,
+		}}
+		if _, err := orders.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+			return fmt.Errorf("order %s: %w", doc.ID.Hex(), err)
+		}
+	}
+	return cursor.Err()
+}
+
+// migrateEmailCase stores every email in the form of types.NormalizeEmail,
+// which is how emails are looked up. Two accounts whose emails differ only in
+// case make it fail on the unique email index; they must be merged by hand.
+func migrateEmailCase(ctx context.Context, m *MongoDb) error {
+	notLower := bson.M{"email": bson.M{"$regex": `[A-Z]|^\s|\s$`}}
+	normalize := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"email": bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}},
+	}}}}
+	if _, err := m.Db.Collection("users").UpdateMany(ctx, notLower, normalize); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("users: emails differing only in case, merge those accounts first: %w", err)
+		}
+		return fmt.Errorf("users: %w", err)
+	}
+	return nil
+}
+
+// migrateBootstrapMarker claims the bootstrap_admin marker when an admin
+// exists already, so the Admin-Secret cannot create another one.
+func migrateBootstrapMarker(ctx context.Context, m *MongoDb) error {
+	var admin types.User
+	err := m.Db.Collection("users").FindOne(ctx, bson.M{"role": "admin"}).Decode(&admin)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = m.Db.Collection("markers").UpdateOne(ctx,
+		bson.M{"_id": bootstrapAdminMarker},
+		bson.M{"$setOnInsert": bson.M{"user_id": admin.ID, "created_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// legacyMoney converts a float amount in major units to Money in currency,
+// rounded to its minor unit, and returns anything else, such as an already
+// migrated amount, unchanged.
+func legacyMoney(v any, currency string) (any, error) {
+	switch n := v.(type) {
+	case float64:
+		return types.RoundMoneyFromMajor(n, currency)
+	case int32:
+		return types.MoneyFromMajor(float64(n), currency)
+	case int64:
+		return types.MoneyFromMajor(float64(n), currency)
+	}
+	return v, nil
+}
@@ -0,0 +1,215 @@
+package mongodb
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestMigrateBaseline migrates documents shaped like the ones the first
+// release wrote, then migrates again and reruns every step, which must not
+// change them further.
+func TestMigrateBaseline(t *testing.T) {
+	m := emptyTestDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	adminID, customerID := primitive.NewObjectID(), primitive.NewObjectID()
+	diner, sushi := primitive.NewObjectID(), primitive.NewObjectID()
+	burger, nigiri := primitive.NewObjectID(), primitive.NewObjectID()
+	orderID := primitive.NewObjectID()
+	now := time.Now()
+	seed := map[string][]any{
+		"users": {
+			bson.M{"_id": adminID, "name": "Admin", "email": " Admin@Example.COM", "password": "$2a$10$hash", "role": "admin", "created_at": now, "updated_at": now},
+			bson.M{"_id": customerID, "name": "Bob", "email": "bob@example.com", "password": "$2a$10$hash", "role": "customer", "created_at": now, "updated_at": now},
+		},
+		"restaurants": {
+			bson.M{"_id": diner, "name": "Diner", "address": "1 Main St", "phone": "+15550100", "is_active": true, "created_at": now, "updated_at": now},
+			// Already given a currency by hand
+			bson.M{"_id": sushi, "name": "Sushi", "address": "2 Main St", "phone": "+15550101", "is_active": true, "currency": "JPY", "created_at": now, "updated_at": now},
+		},
+		"menu": {
+			bson.M{"_id": burger, "restaurant_id": diner, "name": "Burger", "category": "Mains", "price": 12.5, "available": true, "created_at": now, "updated_at": now},
+			bson.M{"_id": nigiri, "restaurant_id": sushi, "name": "Nigiri", "category": "Mains", "price": float64(1250), "available": true, "created_at": now, "updated_at": now},
+		},
+		"orders": {
+			bson.M{
+				"_id": orderID, "user_id": customerID, "restaurant_id": diner, "status": "pending",
+				"items":       bson.A{bson.M{"menu_item_id": burger, "quantity": 2, "price": 12.5}},
+				"total_price": 25.0,
+				"created_at":  now, "updated_at": now,
+			},
+		},
+	}
+	for collection, docs := range seed {
+		if _, err := m.Db.Collection(collection).InsertMany(ctx, docs); err != nil {
+			t.Fatalf("seed %s: %v", collection, err)
+		}
+	}
+
+	applied, err := m.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %v, want all %d migrations", applied, len(migrations))
+	}
+	checkMigrated := func(when string) {
+		t.Helper()
+		var user bson.M
+		if err := m.Db.Collection("users").FindOne(ctx, bson.M{"_id": adminID}).Decode(&user); err != nil {
+			t.Fatal(err)
+		}
+		if user["email"] != "admin@example.com" {
+			t.Errorf("%s: admin email = %q", when, user["email"])
+		}
+
+		var marker bson.M
+		if err := m.Db.Collection("markers").FindOne(ctx, bson.M{"_id": bootstrapAdminMarker}).Decode(&marker); err != nil {
+			t.Fatalf("%s: bootstrap marker: %v", when, err)
+		}
+		if marker["user_id"] != adminID {
+			t.Errorf("%s: bootstrap marker claimed by %v", when, marker["user_id"])
+		}
+
+		for id, want := range map[primitive.ObjectID]string{diner: "USD", sushi: "JPY"} {
+			var restaurant bson.M
+			if err := m.Db.Collection("restaurants").FindOne(ctx, bson.M{"_id": id}).Decode(&restaurant); err != nil {
+				t.Fatal(err)
+			}
+			if restaurant["currency"] != want {
+				t.Errorf("%s: restaurant %s currency = %v, want %s", when, restaurant["name"], restaurant["currency"], want)
+			}
+		}
+
+		for id, want := range map[primitive.ObjectID]bson.M{
+			burger: {"amount": int64(1250), "currency": "USD"},
+			nigiri: {"amount": int64(1250), "currency": "JPY"},
+		} {
+			var item bson.M
+			if err := m.Db.Collection("menu").FindOne(ctx, bson.M{"_id": id}).Decode(&item); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(item["price"], want) {
+				t.Errorf("%s: %s price = %#v, want %v", when, item["name"], item["price"], want)
+			}
+		}
+
+		var order bson.M
+		if err := m.Db.Collection("orders").FindOne(ctx, bson.M{"_id": orderID}).Decode(&order); err != nil {
+			t.Fatal(err)
+		}
+		if want := (bson.M{"amount": int64(2500), "currency": "USD"}); !reflect.DeepEqual(order["total_price"], want) {
+			t.Errorf("%s: order total = %#v, want %v", when, order["total_price"], want)
+		}
+		items, _ := order["items"].(bson.A)
+		if len(items) != 1 {
+			t.Fatalf("%s: order items = %#v", when, order["items"])
+		}
+		item, _ := items[0].(bson.M)
+		if want := (bson.M{"amount": int64(1250), "currency": "USD"}); !reflect.DeepEqual(item["price"], want) {
+			t.Errorf("%s: order item price = %#v, want %v", when, item["price"], want)
+		}
+
+		for collection, name := range map[string]string{"users": "uniq_email", "menu": "uniq_restaurant_name", "promotions": "uniq_code"} {
+			specs, err := m.Db.Collection(collection).Indexes().ListSpecifications(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.ContainsFunc(specs, func(s *mongo.IndexSpecification) bool { return s.Name == name }) {
+				t.Errorf("%s: %s has no %s index", when, collection, name)
+			}
+		}
+	}
+	checkMigrated("first run")
+
+	// Nothing is left to apply
+	applied, err = m.Migrate(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second migrate applied %v, %v", applied, err)
+	}
+	if n, err := m.Db.Collection(migrationsCollection).CountDocuments(ctx, bson.M{}); err != nil || n != int64(len(migrations)) {
+		t.Errorf("schema_migrations has %d documents, %v", n, err)
+	}
+
+	// Two instances may both run a migration before either records it
+	for _, mig := range migrations {
+		if err := mig.Up(ctx, m); err != nil {
+			t.Fatalf("rerun migration %d: %v", mig.Version, err)
+		}
+	}
+	checkMigrated("rerun")
+}
+
+func TestLegacyMoney(t *testing.T) {
+	migrated := types.NewMoney(1250, "USD")
+	for _, tc := range []struct {
+		in       any
+		currency string
+		want     any
+	}{
+		{12.5, "USD", types.NewMoney(1250, "USD")},
+		{3.3000000000000003, "USD", types.NewMoney(330, "USD")},
+		{0.1 + 0.2, "USD", types.NewMoney(30, "USD")},
+		{19.999, "USD", types.NewMoney(2000, "USD")},
+		{1249.6, "JPY", types.NewMoney(1250, "JPY")},
+		{int32(12), "USD", types.NewMoney(1200, "USD")},
+		{int64(12), "KWD", types.NewMoney(12000, "KWD")},
+		{nil, "USD", nil},
+		{migrated, "USD", migrated},
+	} {
+		got, err := legacyMoney(tc.in, tc.currency)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("legacyMoney(%v, %s) = %v, %v, want %v", tc.in, tc.currency, got, err, tc.want)
+		}
+	}
+}
+
+// TestMigrateMoneyFloatDrift migrates prices whose float sums drifted off
+// the cent, as order totals did before Money.
+func TestMigrateMoneyFloatDrift(t *testing.T) {
+	m := emptyTestDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	restaurant, item, order := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	docs := map[string]any{
+		"restaurants": bson.M{"_id": restaurant, "name": "Diner"},
+		"menu":        bson.M{"_id": item, "restaurant_id": restaurant, "name": "Tea", "price": 1.1},
+		"orders": bson.M{
+			"_id": order, "restaurant_id": restaurant, "status": "pending",
+			"items":       bson.A{bson.M{"menu_item_id": item, "quantity": 3, "price": 1.1}},
+			"total_price": 1.1 + 1.1 + 1.1, // 3.3000000000000003
+		},
+	}
+	for collection, doc := range docs {
+		if _, err := m.Db.Collection(collection).InsertOne(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := m.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var got bson.M
+	if err := m.Db.Collection("orders").FindOne(ctx, bson.M{"_id": order}).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.M{"amount": int64(330), "currency": "USD"}); !reflect.DeepEqual(got["total_price"], want) {
+		t.Errorf("order total = %#v, want %v", got["total_price"], want)
+	}
+	if err := m.Db.Collection("menu").FindOne(ctx, bson.M{"_id": item}).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.M{"amount": int64(110), "currency": "USD"}); !reflect.DeepEqual(got["price"], want) {
+		t.Errorf("menu price = %#v, want %v", got["price"], want)
+	}
+}
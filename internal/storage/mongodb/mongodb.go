@@ -5,12 +5,15 @@ import (
 	"time"
 
 	"github.com/shubhamjaiswar43/restify/internal/config"
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoDb struct {
 	Db *mongo.Database
+	// DefaultCurrency is given to restaurants without one when prices are migrated.
+	DefaultCurrency string
 }
 
 func New(cfg *config.Config) (*MongoDb, error) {
@@ -22,6 +25,74 @@ func New(cfg *config.Config) (*MongoDb, error) {
 	}
 	db := client.Database(cfg.DatabaseName)
 	return &MongoDb{
-		Db: db,
+		Db:              db,
+		DefaultCurrency: cfg.DefaultCurrency,
 	}, nil
 }
+
+var _ storage.Storage = (*MongoDb)(nil)
+
+// Users returns the user repository backed by the "users" and "markers" collections.
+func (m *MongoDb) Users() storage.UserStore {
+	return NewUserStore(m.Db.Collection("users"), m.Db.Collection("markers"))
+}
+
+// Restaurants returns the restaurant repository backed by the "restaurants" collection.
+func (m *MongoDb) Restaurants() storage.RestaurantStore {
+	return NewRestaurantStore(m.Db.Collection("restaurants"))
+}
+
+// Menu returns the menu item repository backed by the "menu" collection.
+func (m *MongoDb) Menu() storage.MenuStore {
+	return NewMenuStore(m.Db.Collection("menu"))
+}
+
+// Orders returns the order repository backed by the "orders" collection.
+func (m *MongoDb) Orders() storage.OrderStore {
+	return NewOrderStore(m.Db.Collection("orders"))
+}
+
+// Tokens returns the token repository backed by the "refresh_tokens",
+// "revoked_tokens" and "user_revocations" collections.
+func (m *MongoDb) Tokens() storage.TokenStore {
+	return NewTokenStore(
+		m.Db.Collection("refresh_tokens"),
+		m.Db.Collection("revoked_tokens"),
+		m.Db.Collection("user_revocations"),
+	)
+}
+
+// Memberships returns the staff membership repository backed by the "memberships" collection.
+func (m *MongoDb) Memberships() storage.MembershipStore {
+	return NewMembershipStore(m.Db.Collection("memberships"))
+}
+
+// OneTimeTokens returns the password reset and email verification token
+// repository backed by the "one_time_tokens" collection.
+func (m *MongoDb) OneTimeTokens() storage.OneTimeTokenStore {
+	return NewOneTimeTokenStore(m.Db.Collection("one_time_tokens"))
+}
+
+// Invitations returns the invitation repository backed by the "invitations" collection.
+func (m *MongoDb) Invitations() storage.InvitationStore {
+	return NewInvitationStore(m.Db.Collection("invitations"))
+}
+
+// APIKeys returns the API key repository backed by the "api_keys" collection.
+func (m *MongoDb) APIKeys() storage.APIKeyStore {
+	return NewAPIKeyStore(m.Db.Collection("api_keys"))
+}
+
+// Promotions returns the promotion repository backed by the "promotions" and
+// "promotion_redemptions" collections.
+func (m *MongoDb) Promotions() storage.PromotionStore {
+	return NewPromotionStore(m.Db.Collection("promotions"), m.Db.Collection("promotion_redemptions"))
+}
+
+// translateWriteError maps driver errors to the backend-neutral storage errors.
+func translateWriteError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return storage.ErrDuplicate
+	}
+	return err
+}
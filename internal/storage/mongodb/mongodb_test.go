@@ -0,0 +1,49 @@
+package mongodb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/config"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testDB migrates a fresh database on the server named by
+// RESTIFY_TEST_MONGODB_URI and drops it when the test ends. Tests are skipped
+// without a server.
+func testDB(t *testing.T) *MongoDb {
+	t.Helper()
+	m := emptyTestDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := m.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return m
+}
+
+// emptyTestDB is testDB without the migrations.
+func emptyTestDB(t *testing.T) *MongoDb {
+	t.Helper()
+	uri := os.Getenv("RESTIFY_TEST_MONGODB_URI")
+	if uri == "" {
+		t.Skip("RESTIFY_TEST_MONGODB_URI not set")
+	}
+	m, err := New(&config.Config{
+		StoragePath:     uri,
+		DatabaseName:    "restify_test_" + primitive.NewObjectID().Hex(),
+		DefaultCurrency: "USD",
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		m.Db.Drop(ctx)
+		m.Db.Client().Disconnect(ctx)
+	})
+	return m
+}
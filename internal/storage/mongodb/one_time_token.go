@@ -0,0 +1,68 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OneTimeTokenStore keeps password reset and email verification tokens.
+// Expired tokens are removed by the TTL index created in the migrations.
+type OneTimeTokenStore struct {
+	Collection *mongo.Collection
+}
+
+var _ storage.OneTimeTokenStore = (*OneTimeTokenStore)(nil)
+
+func NewOneTimeTokenStore(collection *mongo.Collection) *OneTimeTokenStore {
+	return &OneTimeTokenStore{
+		Collection: collection,
+	}
+}
+
+// CreateOneTimeToken stores a newly issued token
+func (s *OneTimeTokenStore) CreateOneTimeToken(ctx context.Context, t *types.OneTimeToken) error {
+	if t.ID.IsZero() {
+		t.ID = primitive.NewObjectID()
+	}
+	_, err := s.Collection.InsertOne(ctx, t)
+	return translateWriteError(err)
+}
+
+// ConsumeOneTimeToken atomically marks a usable token as used and returns it
+func (s *OneTimeTokenStore) ConsumeOneTimeToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*types.OneTimeToken, error) {
+	var t types.OneTimeToken
+	err := s.Collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": tokenHash,
+			"purpose":    purpose,
+			"used_at":    nil,
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// InvalidateOneTimeTokens uses up every outstanding token of the user for purpose
+func (s *OneTimeTokenStore) InvalidateOneTimeTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	_, err := s.Collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "purpose": purpose, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	)
+	return err
+}
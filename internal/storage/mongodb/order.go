@@ -5,16 +5,20 @@ import (
 	"errors"
 	"time"
 
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrderStore struct {
 	Collection *mongo.Collection
 }
 
+var _ storage.OrderStore = (*OrderStore)(nil)
+
 func NewOrderStore(collection *mongo.Collection) *OrderStore {
 	return &OrderStore{Collection: collection}
 }
@@ -31,23 +35,37 @@ func (s *OrderStore) CreateOrder(ctx context.Context, order *types.Order) (*type
 	return order, nil
 }
 
-// GetAllOrders - for admin
-func (s *OrderStore) GetAllOrders(ctx context.Context) ([]*types.Order, error) {
-	cursor, err := s.Collection.Find(ctx, bson.M{})
-	if err != nil {
-		return nil, err
+// ListOrders returns one page of orders matching the filter, newest first by default
+func (s *OrderStore) ListOrders(ctx context.Context, f storage.OrderFilter) ([]*types.Order, string, error) {
+	filter := bson.M{}
+	if f.Status != "" {
+		filter["status"] = f.Status
 	}
-	defer cursor.Close(ctx)
-
-	var orders []*types.Order
-	for cursor.Next(ctx) {
-		var o types.Order
-		if err := cursor.Decode(&o); err != nil {
-			return nil, err
+	objectIDOrAny(filter, "restaurant_id", f.RestaurantID)
+	objectIDOrAny(filter, "user_id", f.UserID)
+	if !f.CreatedFrom.IsZero() || !f.CreatedTo.IsZero() {
+		created := bson.M{}
+		if !f.CreatedFrom.IsZero() {
+			created["$gte"] = f.CreatedFrom
+		}
+		if !f.CreatedTo.IsZero() {
+			created["$lt"] = f.CreatedTo
 		}
-		orders = append(orders, &o)
+		filter["created_at"] = created
 	}
-	return orders, nil
+	return findPage[types.Order](ctx, s.Collection, filter, f.ListOptions, storage.OrderSorts, "-created_at")
+}
+
+// ListUserOrders pages through the orders placed by one user
+func (s *OrderStore) ListUserOrders(ctx context.Context, userID primitive.ObjectID, f storage.OrderFilter) ([]*types.Order, string, error) {
+	f.UserID = userID
+	return s.ListOrders(ctx, f)
+}
+
+// ListRestaurantOrders pages through the orders of one restaurant
+func (s *OrderStore) ListRestaurantOrders(ctx context.Context, restaurantID primitive.ObjectID, f storage.OrderFilter) ([]*types.Order, string, error) {
+	f.RestaurantID = restaurantID
+	return s.ListOrders(ctx, f)
 }
 
 // GetOrderByID fetches a single order by ID
@@ -62,3 +80,32 @@ func (s *OrderStore) GetOrderByID(ctx context.Context, id primitive.ObjectID) (*
 	}
 	return &order, nil
 }
+
+// UpdateOrderStatus applies a status transition as a compare-and-set on the current status
+func (s *OrderStore) UpdateOrderStatus(ctx context.Context, id primitive.ObjectID, change types.StatusChange) (*types.Order, error) {
+	filter := bson.M{"_id": id, "status": change.From}
+	update := bson.M{
+		"$set":  bson.M{"status": change.To, "updated_at": change.At},
+		"$push": bson.M{"status_history": change},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var order types.Order
+	err := s.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&order)
+	if err == nil {
+		return &order, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	// Nothing matched: either the order is gone or its status changed meanwhile
+	existing, err := s.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, storage.ErrNotFound
+	}
+	return nil, storage.ErrConflict
+}
@@ -0,0 +1,196 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PromotionStore keeps promotions, and in Redemptions how often each user
+// redeemed each of them.
+type PromotionStore struct {
+	Collection  *mongo.Collection
+	Redemptions *mongo.Collection
+}
+
+var _ storage.PromotionStore = (*PromotionStore)(nil)
+
+func NewPromotionStore(collection, redemptions *mongo.Collection) *PromotionStore {
+	return &PromotionStore{
+		Collection:  collection,
+		Redemptions: redemptions,
+	}
+}
+
+// CreatePromotion stores a new promotion
+func (s *PromotionStore) CreatePromotion(ctx context.Context, p *types.Promotion) error {
+	if p.ID.IsZero() {
+		p.ID = primitive.NewObjectID()
+	}
+	_, err := s.Collection.InsertOne(ctx, p)
+	return translateWriteError(err)
+}
+
+// GetPromotion finds a promotion by ID
+func (s *PromotionStore) GetPromotion(ctx context.Context, id primitive.ObjectID) (*types.Promotion, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetPromotionByCode finds a promotion by its code
+func (s *PromotionStore) GetPromotionByCode(ctx context.Context, code string) (*types.Promotion, error) {
+	return s.findOne(ctx, bson.M{"code": code})
+}
+
+func (s *PromotionStore) findOne(ctx context.Context, filter bson.M) (*types.Promotion, error) {
+	var p types.Promotion
+	err := s.Collection.FindOne(ctx, filter).Decode(&p)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListPromotions returns one page of promotions
+func (s *PromotionStore) ListPromotions(ctx context.Context, f storage.PromotionFilter) ([]*types.Promotion, string, error) {
+	filter := bson.M{}
+	objectIDOrAny(filter, "restaurant_id", f.RestaurantID)
+	if f.GlobalOnly {
+		filter["restaurant_id"] = bson.M{"$exists": false}
+	}
+	if f.Active != nil {
+		filter["active"] = *f.Active
+	}
+	return findPage[types.Promotion](ctx, s.Collection, filter, f.ListOptions, storage.PromotionSorts, "-created_at")
+}
+
+// UpdatePromotion overwrites the editable fields of an existing promotion
+func (s *PromotionStore) UpdatePromotion(ctx context.Context, p *types.Promotion) (*types.Promotion, error) {
+	p.UpdatedAt = time.Now()
+	set := bson.M{
+		"description":     p.Description,
+		"max_redemptions": p.MaxRedemptions,
+		"max_per_user":    p.MaxPerUser,
+		"min_order":       p.MinOrder,
+		"active":          p.Active,
+		"updated_at":      p.UpdatedAt,
+	}
+	unset := bson.M{}
+	if p.StartsAt != nil {
+		set["starts_at"] = p.StartsAt
+	} else {
+		unset["starts_at"] = ""
+	}
+	if p.EndsAt != nil {
+		set["ends_at"] = p.EndsAt
+	} else {
+		unset["ends_at"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated types.Promotion
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": p.ID}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Redeem counts one use of a promotion while both of its limits allow it.
+// The user's counter is created first by an upsert that matches on the key
+// alone, so racing first redemptions agree on one counter, and is then
+// incremented by an update that only matches below the per-user limit. The
+// global count is taken the same way, and the user's count is given back when
+// that fails.
+func (s *PromotionStore) Redeem(ctx context.Context, id, userID primitive.ObjectID) error {
+	var limits struct {
+		MaxPerUser int `bson:"max_per_user"`
+	}
+	err := s.Collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"max_per_user": 1})).Decode(&limits)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return storage.ErrNotFound
+		}
+		return err
+	}
+
+	key := bson.M{"promotion_id": id, "user_id": userID}
+	_, err = s.Redemptions.UpdateOne(ctx, key, bson.M{"$setOnInsert": bson.M{"count": 0}}, options.Update().SetUpsert(true))
+	// A concurrent upsert losing the race on the unique index means the
+	// counter exists, which is all this step needs
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	userFilter := bson.M{"promotion_id": id, "user_id": userID}
+	if limits.MaxPerUser > 0 {
+		userFilter["count"] = bson.M{"$lt": limits.MaxPerUser}
+	}
+	res, err := s.Redemptions.UpdateOne(ctx, userFilter, bson.M{"$inc": bson.M{"count": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrUserRedemptionLimit
+	}
+
+	res, err = s.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "$expr": bson.M{"$or": bson.A{
+			bson.M{"$lte": bson.A{"$max_redemptions", 0}},
+			bson.M{"$lt": bson.A{"$redemptions", "$max_redemptions"}},
+		}}},
+		bson.M{"$inc": bson.M{"redemptions": 1}},
+	)
+	if err == nil && res.MatchedCount == 1 {
+		return nil
+	}
+	if _, undoErr := s.Redemptions.UpdateOne(ctx, key, bson.M{"$inc": bson.M{"count": -1}}); undoErr != nil && err == nil {
+		err = undoErr
+	}
+	if err != nil {
+		return err
+	}
+	return storage.ErrRedemptionLimit
+}
+
+// Release gives back one use of a promotion. The total and the user's count
+// are each decremented when above zero, so one being zero already does not
+// keep the other from going down.
+func (s *PromotionStore) Release(ctx context.Context, id, userID primitive.ObjectID) error {
+	res, err := s.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "redemptions": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"redemptions": -1}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		n, err := s.Collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return storage.ErrNotFound
+		}
+	}
+	_, err = s.Redemptions.UpdateOne(ctx,
+		bson.M{"promotion_id": id, "user_id": userID, "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	return err
+}
@@ -0,0 +1,11 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage/storagetest"
+)
+
+func TestPromotionRedeemConcurrently(t *testing.T) {
+	storagetest.RedeemConcurrently(t, testDB(t).Promotions())
+}
@@ -4,18 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type RestaurantStore struct {
 	Collection *mongo.Collection
 }
 
+var _ storage.RestaurantStore = (*RestaurantStore)(nil)
+
 func NewRestaurantStore(collection *mongo.Collection) *RestaurantStore {
 	return &RestaurantStore{Collection: collection}
 }
@@ -27,7 +32,7 @@ func (s *RestaurantStore) CreateRestaurant(ctx context.Context, r *types.Restaur
 	r.UpdatedAt = now
 	res, err := s.Collection.InsertOne(ctx, r)
 	if err != nil {
-		return nil, err
+		return nil, translateWriteError(err)
 	}
 	r.ID = res.InsertedID.(primitive.ObjectID)
 	return r, nil
@@ -56,7 +61,6 @@ func (s *RestaurantStore) GetByID(ctx context.Context, id string) (*types.Restau
 	filter := bson.M{"_id": objId}
 	var restaurant types.Restaurant
 	err = s.Collection.FindOne(ctx, filter).Decode(&restaurant)
-	fmt.Print(id, restaurant, err)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil // not found
@@ -66,21 +70,54 @@ func (s *RestaurantStore) GetByID(ctx context.Context, id string) (*types.Restau
 	return &restaurant, nil
 }
 
-// Get all restaurants
-func (s *RestaurantStore) GetAllRestaurants(ctx context.Context) ([]*types.Restaurant, error) {
-	cursor, err := s.Collection.Find(ctx, bson.M{})
+// ListRestaurants returns one page of restaurants matching the filter
+func (s *RestaurantStore) ListRestaurants(ctx context.Context, f storage.RestaurantFilter) ([]*types.Restaurant, string, error) {
+	filter := bson.M{}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	if f.Name != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(f.Name), "$options": "i"}
+	}
+	return findPage[types.Restaurant](ctx, s.Collection, filter, f.ListOptions, storage.RestaurantSorts, "name")
+}
+
+// UpdateRestaurant overwrites the editable fields of an existing restaurant
+func (s *RestaurantStore) UpdateRestaurant(ctx context.Context, r *types.Restaurant) (*types.Restaurant, error) {
+	r.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"name":        r.Name,
+		"address":     r.Address,
+		"phone":       r.Phone,
+		"description": r.Description,
+		"is_active":   r.IsActive,
+		"updated_at":  r.UpdatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated types.Restaurant
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": r.ID}, update, opts).Decode(&updated)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, translateWriteError(err)
 	}
-	defer cursor.Close(ctx)
+	return &updated, nil
+}
+
+// SetTaxPolicy replaces the tax policy of an existing restaurant
+func (s *RestaurantStore) SetTaxPolicy(ctx context.Context, id primitive.ObjectID, policy types.TaxPolicy) (*types.Restaurant, error) {
+	update := bson.M{"$set": bson.M{"tax": policy, "updated_at": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	var restaurants []*types.Restaurant
-	for cursor.Next(ctx) {
-		var r types.Restaurant
-		if err := cursor.Decode(&r); err != nil {
-			return nil, err
+	var updated types.Restaurant
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, storage.ErrNotFound
 		}
-		restaurants = append(restaurants, &r)
+		return nil, err
 	}
-	return restaurants, nil
+	return &updated, nil
 }
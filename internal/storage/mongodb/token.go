@@ -0,0 +1,129 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenStore keeps refresh tokens and revocations. Expired documents are
+// removed by the TTL indexes created in the migrations.
+type TokenStore struct {
+	RefreshTokens   *mongo.Collection
+	RevokedTokens   *mongo.Collection
+	UserRevocations *mongo.Collection
+}
+
+var _ storage.TokenStore = (*TokenStore)(nil)
+
+func NewTokenStore(refreshTokens, revokedTokens, userRevocations *mongo.Collection) *TokenStore {
+	return &TokenStore{
+		RefreshTokens:   refreshTokens,
+		RevokedTokens:   revokedTokens,
+		UserRevocations: userRevocations,
+	}
+}
+
+// CreateRefreshToken stores a newly issued refresh token
+func (s *TokenStore) CreateRefreshToken(ctx context.Context, t *types.RefreshToken) error {
+	if t.ID.IsZero() {
+		t.ID = primitive.NewObjectID()
+	}
+	_, err := s.RefreshTokens.InsertOne(ctx, t)
+	return translateWriteError(err)
+}
+
+// GetRefreshToken finds a refresh token by the hash of its value
+func (s *TokenStore) GetRefreshToken(ctx context.Context, tokenHash string) (*types.RefreshToken, error) {
+	var t types.RefreshToken
+	err := s.RefreshTokens.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RotateRefreshToken revokes old, provided nobody used it yet, and stores next
+func (s *TokenStore) RotateRefreshToken(ctx context.Context, old primitive.ObjectID, next *types.RefreshToken) error {
+	if next.ID.IsZero() {
+		next.ID = primitive.NewObjectID()
+	}
+	res, err := s.RefreshTokens.UpdateOne(ctx,
+		bson.M{"_id": old, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now(), "replaced_by": next.ID}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrConflict
+	}
+	return s.CreateRefreshToken(ctx, next)
+}
+
+// RevokeRefreshFamily revokes every token descending from the same login
+func (s *TokenStore) RevokeRefreshFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	_, err := s.RefreshTokens.UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeAccessToken records a revoked jti until the token would have expired anyway
+func (s *TokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := s.RevokedTokens.UpdateOne(ctx,
+		bson.M{"_id": jti},
+		bson.M{"$set": bson.M{"expires_at": expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// IsAccessTokenRevoked reports whether the jti was revoked
+func (s *TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := s.RevokedTokens.CountDocuments(ctx, bson.M{"_id": jti}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// RevokeUserTokens cuts off every session of a user
+func (s *TokenStore) RevokeUserTokens(ctx context.Context, userID primitive.ObjectID, at time.Time) error {
+	_, err := s.UserRevocations.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = s.RefreshTokens.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	return err
+}
+
+// UserTokensRevokedAt returns when all sessions of the user were last revoked
+func (s *TokenStore) UserTokensRevokedAt(ctx context.Context, userID primitive.ObjectID) (time.Time, error) {
+	var doc struct {
+		RevokedAt time.Time `bson:"revoked_at"`
+	}
+	err := s.UserRevocations.FindOne(ctx, bson.M{"_id": userID}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return doc.RevokedAt, nil
+}
@@ -4,29 +4,61 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/shubhamjaiswar43/restify/internal/storage"
 	"github.com/shubhamjaiswar43/restify/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// UserStore defines MongoDB operations for users.
+// UserStore defines MongoDB operations for users. Markers holds one-time
+// documents keyed by _id, such as the admin bootstrap marker.
 type UserStore struct {
 	Collection *mongo.Collection
+	Markers    *mongo.Collection
 }
 
+var _ storage.UserStore = (*UserStore)(nil)
+
+// bootstrapAdminMarker is the _id of the marker claimed by the first admin.
+const bootstrapAdminMarker = "bootstrap_admin"
+
 // NewUserStore initializes a new UserStore.
-func NewUserStore(collection *mongo.Collection) *UserStore {
+func NewUserStore(collection, markers *mongo.Collection) *UserStore {
 	return &UserStore{
 		Collection: collection,
+		Markers:    markers,
 	}
 }
 
-// CreateUser inserts a new user document.
+// CreateUser inserts a new user document. A taken email yields storage.ErrDuplicate.
 func (s *UserStore) CreateUser(ctx context.Context, u *types.User) error {
 	_, err := s.Collection.InsertOne(ctx, u)
-	return err
+	return translateWriteError(err)
+}
+
+// CreateFirstAdmin inserts the bootstrap marker before the user; the unique
+// _id makes concurrent bootstraps fail on the marker instead of both passing.
+func (s *UserStore) CreateFirstAdmin(ctx context.Context, u *types.User) error {
+	_, err := s.Markers.InsertOne(ctx, bson.M{
+		"_id":        bootstrapAdminMarker,
+		"user_id":    u.ID,
+		"created_at": time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return storage.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.Collection.InsertOne(ctx, u); err != nil {
+		s.Markers.DeleteOne(ctx, bson.M{"_id": bootstrapAdminMarker, "user_id": u.ID})
+		return translateWriteError(err)
+	}
+	return nil
 }
 
 // GetUserByEmail retrieves a user by email address.
@@ -59,34 +91,155 @@ func (s *UserStore) GetUserByID(ctx context.Context, id string) (*types.User, er
 	return &user, nil
 }
 
-// GetAllUsers returns all users (useful for admin panel).
-func (s *UserStore) GetAllUsers(ctx context.Context) ([]types.User, error) {
-	cursor, err := s.Collection.Find(ctx, bson.M{})
+// GetUserByIdentity finds the user linked to subject at issuer.
+func (s *UserStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*types.User, error) {
+	var user types.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"issuer": issuer, "subject": subject}}}
+	err := s.Collection.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	return &user, nil
+}
 
-	var users []types.User
-	for cursor.Next(ctx) {
-		var u types.User
-		if err := cursor.Decode(&u); err != nil {
-			return nil, err
-		}
-		users = append(users, u)
+// LinkIdentity adds an external identity; the unique index on identities
+// turns a second link of the same identity into storage.ErrDuplicate.
+func (s *UserStore) LinkIdentity(ctx context.Context, userID primitive.ObjectID, id types.Identity) error {
+	err := s.updateUserWith(ctx, bson.M{"_id": userID}, bson.M{
+		"$push": bson.M{"identities": id},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	return translateWriteError(err)
+}
+
+// ListUsers returns one page of users (useful for admin panel).
+func (s *UserStore) ListUsers(ctx context.Context, f storage.UserFilter) ([]*types.User, string, error) {
+	filter := bson.M{}
+	if f.Role != "" {
+		filter["role"] = f.Role
 	}
+	if f.Email != "" {
+		filter["email"] = f.Email
+	}
+	return findPage[types.User](ctx, s.Collection, filter, f.ListOptions, storage.UserSorts, "created_at")
+}
 
-	return users, cursor.Err()
+// AddLockoutEvent pushes e onto the user's lockout history, trimmed to the newest entries.
+func (s *UserStore) AddLockoutEvent(ctx context.Context, userID primitive.ObjectID, e types.LockoutEvent) error {
+	res, err := s.Collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$push": bson.M{"lockout_events": bson.M{
+			"$each":  bson.A{e},
+			"$slice": -storage.MaxLockoutEvents,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateUser overwrites the profile fields, role and password hash of the user with u.ID.
+func (s *UserStore) UpdateUser(ctx context.Context, u *types.User) (*types.User, error) {
+	u.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"name":              u.Name,
+		"email":             u.Email,
+		"password":          u.Password,
+		"role":              u.Role,
+		"email_verified":    u.EmailVerified,
+		"email_verified_at": u.EmailVerifiedAt,
+		"updated_at":        u.UpdatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated types.User
+	err := s.Collection.FindOneAndUpdate(ctx, bson.M{"_id": u.ID}, update, opts).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, translateWriteError(err)
+	}
+	return &updated, nil
 }
 
 // DeleteUser removes a user by ID (admin operation).
-func (s *UserStore) DeleteUser(ctx context.Context, id string) error {
+func (s *UserStore) DeleteUser(ctx context.Context, id primitive.ObjectID) error {
 	result, err := s.Collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		return err
 	}
 	if result.DeletedCount == 0 {
-		return mongo.ErrNoDocuments
+		return storage.ErrNotFound
 	}
 	return nil
 }
+
+// SetPassword replaces the stored password hash.
+func (s *UserStore) SetPassword(ctx context.Context, userID primitive.ObjectID, hash string) error {
+	return s.updateUser(ctx, bson.M{"_id": userID}, bson.M{"password": hash, "updated_at": time.Now()})
+}
+
+// MarkEmailVerified flags email as verified if it is still the user's address.
+func (s *UserStore) MarkEmailVerified(ctx context.Context, userID primitive.ObjectID, email string, at time.Time) error {
+	return s.updateUser(ctx,
+		bson.M{"_id": userID, "email": email},
+		bson.M{"email_verified": true, "email_verified_at": at, "updated_at": time.Now()},
+	)
+}
+
+// updateUser sets fields on the user matching filter, or returns storage.ErrNotFound.
+func (s *UserStore) updateUser(ctx context.Context, filter, fields bson.M) error {
+	return s.updateUserWith(ctx, filter, bson.M{"$set": fields})
+}
+
+// updateUserWith applies update to the user matching filter, or returns storage.ErrNotFound.
+func (s *UserStore) updateUserWith(ctx context.Context, filter, update bson.M) error {
+	res, err := s.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SetMFA replaces the user's two-factor settings.
+func (s *UserStore) SetMFA(ctx context.Context, userID primitive.ObjectID, m types.MFA) error {
+	return s.updateUser(ctx, bson.M{"_id": userID}, bson.M{"mfa": m, "updated_at": time.Now()})
+}
+
+// UseMFAStep advances the last accepted TOTP step, refusing replays.
+func (s *UserStore) UseMFAStep(ctx context.Context, userID primitive.ObjectID, step int64) error {
+	res, err := s.Collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "mfa.last_used_step": bson.M{"$not": bson.M{"$gte": step}}},
+		bson.M{"$set": bson.M{"mfa.last_used_step": step}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrConflict
+	}
+	return nil
+}
+
+// UseRecoveryCode removes one unused recovery code.
+func (s *UserStore) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, hash string) error {
+	return s.updateUserWith(ctx,
+		bson.M{"_id": userID, "mfa.recovery_codes": hash},
+		bson.M{"$pull": bson.M{"mfa.recovery_codes": hash}},
+	)
+}
+
+// SetRole changes the user's global role.
+func (s *UserStore) SetRole(ctx context.Context, userID primitive.ObjectID, role string) error {
+	return s.updateUser(ctx, bson.M{"_id": userID}, bson.M{"role": role, "updated_at": time.Now()})
+}
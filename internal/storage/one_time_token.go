@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OneTimeTokenStore persists password reset and email verification tokens.
+type OneTimeTokenStore interface {
+	CreateOneTimeToken(ctx context.Context, t *types.OneTimeToken) error
+	// ConsumeOneTimeToken marks the unused, unexpired token with the given
+	// purpose and hash as used at now and returns it. It returns (nil, nil)
+	// when there is no such token, so each token works exactly once.
+	ConsumeOneTimeToken(ctx context.Context, purpose, tokenHash string, now time.Time) (*types.OneTimeToken, error)
+	// InvalidateOneTimeTokens marks every unused token of the user with the given purpose as used.
+	InvalidateOneTimeTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrRedemptionLimit is returned by Redeem when a promotion has been used
+	// as often as it may be.
+	ErrRedemptionLimit = errors.New("storage: promotion redemption limit reached")
+	// ErrUserRedemptionLimit is returned by Redeem when the user has used a
+	// promotion as often as one user may.
+	ErrUserRedemptionLimit = errors.New("storage: promotion redemption limit reached for user")
+)
+
+// PromotionStore persists promotions and counts their redemptions.
+type PromotionStore interface {
+	// CreatePromotion returns ErrDuplicate when the code is taken.
+	CreatePromotion(ctx context.Context, p *types.Promotion) error
+	GetPromotion(ctx context.Context, id primitive.ObjectID) (*types.Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*types.Promotion, error)
+	// ListPromotions returns one page of promotions and the cursor of the next page, if any.
+	ListPromotions(ctx context.Context, f PromotionFilter) ([]*types.Promotion, string, error)
+	// UpdatePromotion overwrites the editable fields of the promotion with p.ID:
+	// description, validity window, limits, minimum order and active.
+	UpdatePromotion(ctx context.Context, p *types.Promotion) (*types.Promotion, error)
+	// Redeem counts one use of the promotion by userID. Checking the limits
+	// and counting happen atomically, so concurrent orders cannot exceed them.
+	Redeem(ctx context.Context, id, userID primitive.ObjectID) error
+	// Release gives back a redemption whose order was not placed or was cancelled.
+	Release(ctx context.Context, id, userID primitive.ObjectID) error
+}
@@ -1,4 +1,121 @@
 package storage
 
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned by write operations whose target document does not exist.
+// Lookups keep returning (nil, nil) for a missing document.
+var ErrNotFound = errors.New("storage: document not found")
+
+// ErrConflict is returned when a conditional write finds the document in a
+// different state than the caller expected.
+var ErrConflict = errors.New("storage: document was modified concurrently")
+
+// ErrDuplicate is returned when a write would break a uniqueness rule, such as
+// a second user with the same email.
+var ErrDuplicate = errors.New("storage: duplicate key")
+
+// Storage is implemented by every persistence backend and hands out the
+// repositories used by the handlers.
 type Storage interface {
+	Users() UserStore
+	Restaurants() RestaurantStore
+	Menu() MenuStore
+	Orders() OrderStore
+	Tokens() TokenStore
+	Memberships() MembershipStore
+	OneTimeTokens() OneTimeTokenStore
+	Invitations() InvitationStore
+	APIKeys() APIKeyStore
+	Promotions() PromotionStore
+}
+
+// UserStore defines persistence operations for users.
+type UserStore interface {
+	CreateUser(ctx context.Context, u *types.User) error
+	// CreateFirstAdmin claims the one-time bootstrap_admin marker and then
+	// creates u. It returns ErrConflict when the marker was already claimed,
+	// and gives the marker back when creating u fails.
+	CreateFirstAdmin(ctx context.Context, u *types.User) error
+	GetUserByEmail(ctx context.Context, email string) (*types.User, error)
+	GetUserByID(ctx context.Context, id string) (*types.User, error)
+	// GetUserByIdentity finds the user linked to subject at issuer.
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*types.User, error)
+	// LinkIdentity adds an external identity to the user. It returns
+	// ErrDuplicate when the identity is already linked to any user.
+	LinkIdentity(ctx context.Context, userID primitive.ObjectID, id types.Identity) error
+	// ListUsers returns one page of users and the cursor of the next page, if any.
+	ListUsers(ctx context.Context, f UserFilter) ([]*types.User, string, error)
+	// UpdateUser overwrites the profile fields, role and password hash of the
+	// user with u.ID.
+	UpdateUser(ctx context.Context, u *types.User) (*types.User, error)
+	DeleteUser(ctx context.Context, id primitive.ObjectID) error
+	// SetRole changes the user's global role.
+	SetRole(ctx context.Context, userID primitive.ObjectID, role string) error
+	// SetPassword replaces the user's password hash.
+	SetPassword(ctx context.Context, userID primitive.ObjectID, hash string) error
+	// MarkEmailVerified flags the user's email as verified, provided it is
+	// still email. It returns ErrNotFound otherwise.
+	MarkEmailVerified(ctx context.Context, userID primitive.ObjectID, email string, at time.Time) error
+	// SetMFA replaces the user's two-factor settings.
+	SetMFA(ctx context.Context, userID primitive.ObjectID, m types.MFA) error
+	// UseMFAStep records step as the last accepted TOTP step. It returns
+	// ErrConflict when a code of that step or a later one was already used.
+	UseMFAStep(ctx context.Context, userID primitive.ObjectID, step int64) error
+	// UseRecoveryCode removes the recovery code with the given hash, returning
+	// ErrNotFound when the user has no such unused code.
+	UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, hash string) error
+	// AddLockoutEvent appends to the user's lockout history, keeping only the
+	// most recent MaxLockoutEvents entries.
+	AddLockoutEvent(ctx context.Context, userID primitive.ObjectID, e types.LockoutEvent) error
+}
+
+// MaxLockoutEvents bounds the lockout history kept on a user document.
+const MaxLockoutEvents = 20
+
+// RestaurantStore defines persistence operations for restaurants.
+type RestaurantStore interface {
+	CreateRestaurant(ctx context.Context, r *types.Restaurant) (*types.Restaurant, error)
+	GetByName(ctx context.Context, name string) (*types.Restaurant, error)
+	GetByID(ctx context.Context, id string) (*types.Restaurant, error)
+	// ListRestaurants returns one page of restaurants and the cursor of the next page, if any.
+	ListRestaurants(ctx context.Context, f RestaurantFilter) ([]*types.Restaurant, string, error)
+	// UpdateRestaurant overwrites the editable fields of the restaurant with r.ID.
+	UpdateRestaurant(ctx context.Context, r *types.Restaurant) (*types.Restaurant, error)
+	// SetTaxPolicy replaces the tax policy of the restaurant with id.
+	SetTaxPolicy(ctx context.Context, id primitive.ObjectID, policy types.TaxPolicy) (*types.Restaurant, error)
+}
+
+// MenuStore defines persistence operations for menu items.
+type MenuStore interface {
+	CreateMenuItem(ctx context.Context, item *types.MenuItem) (*types.MenuItem, error)
+	GetMenuItemByID(ctx context.Context, id primitive.ObjectID) (*types.MenuItem, error)
+	GetByNameAndRestaurant(ctx context.Context, name string, restaurantID primitive.ObjectID) (*types.MenuItem, error)
+	// ListMenuItems returns one page of menu items and the cursor of the next page, if any.
+	ListMenuItems(ctx context.Context, f MenuItemFilter) ([]*types.MenuItem, string, error)
+	// UpdateMenuItem overwrites the editable fields of the menu item with item.ID.
+	UpdateMenuItem(ctx context.Context, item *types.MenuItem) (*types.MenuItem, error)
+	SetAvailability(ctx context.Context, id primitive.ObjectID, available bool) (*types.MenuItem, error)
+	DeleteMenuItem(ctx context.Context, id primitive.ObjectID) error
+}
+
+// OrderStore defines persistence operations for orders.
+type OrderStore interface {
+	CreateOrder(ctx context.Context, order *types.Order) (*types.Order, error)
+	// ListOrders returns one page of orders and the cursor of the next page, if any.
+	ListOrders(ctx context.Context, f OrderFilter) ([]*types.Order, string, error)
+	// ListUserOrders pages through the orders placed by one user; f.UserID is ignored.
+	ListUserOrders(ctx context.Context, userID primitive.ObjectID, f OrderFilter) ([]*types.Order, string, error)
+	// ListRestaurantOrders pages through the orders of one restaurant; f.RestaurantID is ignored.
+	ListRestaurantOrders(ctx context.Context, restaurantID primitive.ObjectID, f OrderFilter) ([]*types.Order, string, error)
+	GetOrderByID(ctx context.Context, id primitive.ObjectID) (*types.Order, error)
+	// UpdateOrderStatus moves the order to change.To only if its current status
+	// is still change.From, appending change to the status history.
+	UpdateOrderStatus(ctx context.Context, id primitive.ObjectID, change types.StatusChange) (*types.Order, error)
 }
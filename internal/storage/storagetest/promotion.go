@@ -0,0 +1,127 @@
+// Package storagetest holds the behavior every storage backend must share,
+// written once and run by each backend's own tests.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/storage"
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RedeemConcurrently checks that Redeem holds both limits of a promotion when
+// many orders redeem it at the same time, and that Release gives uses back.
+func RedeemConcurrently(t *testing.T, s storage.PromotionStore) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	newPromotion := func(t *testing.T, maxRedemptions, maxPerUser int) *types.Promotion {
+		t.Helper()
+		p := &types.Promotion{
+			Code:           "P" + primitive.NewObjectID().Hex()[16:],
+			Type:           types.PromoPercentage,
+			Percent:        1000,
+			MaxRedemptions: maxRedemptions,
+			MaxPerUser:     maxPerUser,
+			Active:         true,
+			CreatedAt:      time.Now(),
+		}
+		if err := s.CreatePromotion(ctx, p); err != nil {
+			t.Fatalf("CreatePromotion: %v", err)
+		}
+		return p
+	}
+	// redeem runs n concurrent redemptions, user(i) being the i-th one's
+	// user, and counts the outcomes by error
+	redeem := func(t *testing.T, p *types.Promotion, n int, user func(i int) primitive.ObjectID) map[error]int {
+		t.Helper()
+		var (
+			mu       sync.Mutex
+			wg       sync.WaitGroup
+			outcomes = map[error]int{}
+		)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := s.Redeem(ctx, p.ID, user(i))
+				mu.Lock()
+				defer mu.Unlock()
+				outcomes[err]++
+			}(i)
+		}
+		wg.Wait()
+		for err := range outcomes {
+			if err != nil && !errors.Is(err, storage.ErrRedemptionLimit) && !errors.Is(err, storage.ErrUserRedemptionLimit) {
+				t.Fatalf("Redeem: %v", err)
+			}
+		}
+		return outcomes
+	}
+
+	t.Run("per user limit", func(t *testing.T) {
+		p := newPromotion(t, 0, 2)
+		userID := primitive.NewObjectID()
+		got := redeem(t, p, 20, func(int) primitive.ObjectID { return userID })
+		if got[nil] != 2 || got[storage.ErrUserRedemptionLimit] != 18 {
+			t.Fatalf("outcomes = %v, want 2 redeemed and 18 over the per-user limit", got)
+		}
+
+		if err := s.Release(ctx, p.ID, userID); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		if err := s.Redeem(ctx, p.ID, userID); err != nil {
+			t.Fatalf("Redeem after Release: %v", err)
+		}
+		if err := s.Redeem(ctx, p.ID, userID); !errors.Is(err, storage.ErrUserRedemptionLimit) {
+			t.Fatalf("Redeem over the limit = %v, want ErrUserRedemptionLimit", err)
+		}
+	})
+
+	t.Run("global limit", func(t *testing.T) {
+		p := newPromotion(t, 5, 1)
+		users := make([]primitive.ObjectID, 20)
+		for i := range users {
+			users[i] = primitive.NewObjectID()
+		}
+		got := redeem(t, p, len(users), func(i int) primitive.ObjectID { return users[i] })
+		if got[nil] != 5 || got[storage.ErrRedemptionLimit] != 15 {
+			t.Fatalf("outcomes = %v, want 5 redeemed and 15 over the global limit", got)
+		}
+
+		stored, err := s.GetPromotion(ctx, p.ID)
+		if err != nil || stored == nil {
+			t.Fatalf("GetPromotion = %v, %v", stored, err)
+		}
+		if stored.Redemptions != 5 {
+			t.Fatalf("Redemptions = %d, want 5", stored.Redemptions)
+		}
+	})
+
+	t.Run("release", func(t *testing.T) {
+		if err := s.Release(ctx, primitive.NewObjectID(), primitive.NewObjectID()); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("Release of an unknown promotion = %v, want ErrNotFound", err)
+		}
+
+		// The per-user count is given back even when the total is already zero
+		p := newPromotion(t, 0, 1)
+		userID := primitive.NewObjectID()
+		if err := s.Redeem(ctx, p.ID, userID); err != nil {
+			t.Fatalf("Redeem: %v", err)
+		}
+		if err := s.Release(ctx, p.ID, primitive.NewObjectID()); err != nil {
+			t.Fatalf("Release by another user: %v", err)
+		}
+		if err := s.Release(ctx, p.ID, userID); err != nil {
+			t.Fatalf("Release with no redemptions left: %v", err)
+		}
+		if err := s.Redeem(ctx, p.ID, userID); err != nil {
+			t.Fatalf("Redeem after Release: %v", err)
+		}
+	})
+}
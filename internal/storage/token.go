@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/shubhamjaiswar43/restify/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenStore persists refresh tokens and access token revocations.
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, t *types.RefreshToken) error
+	// GetRefreshToken looks a token up by the hash of its value, revoked or not.
+	GetRefreshToken(ctx context.Context, tokenHash string) (*types.RefreshToken, error)
+	// RotateRefreshToken revokes old and stores next in its place. It fails with
+	// ErrConflict when old was already revoked, which means it is being replayed.
+	RotateRefreshToken(ctx context.Context, old primitive.ObjectID, next *types.RefreshToken) error
+	RevokeRefreshFamily(ctx context.Context, familyID primitive.ObjectID) error
+
+	// RevokeAccessToken blocks one access token, identified by its jti, until it expires.
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	// RevokeUserTokens revokes every refresh token of the user and blocks all
+	// access tokens issued to them, and API keys created for them, before at.
+	RevokeUserTokens(ctx context.Context, userID primitive.ObjectID, at time.Time) error
+	// UserTokensRevokedAt returns the last RevokeUserTokens time, or the zero time.
+	UserTokensRevokedAt(ctx context.Context, userID primitive.ObjectID) (time.Time, error)
+}
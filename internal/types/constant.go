@@ -7,3 +7,12 @@ const (
 	OrderStatusCompleted = "completed"
 	OrderStatusCancelled = "cancelled"
 )
+
+// OrderStatuses lists every order status.
+var OrderStatuses = []string{
+	OrderStatusPending,
+	OrderStatusPreparing,
+	OrderStatusReady,
+	OrderStatusCompleted,
+	OrderStatusCancelled,
+}
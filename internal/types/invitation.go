@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invitation lets an admin or manager hand a role to someone by email. The
+// invitee signs up, or accepts while logged in, with the mailed token, which
+// works once and only for that email. Admin invitations grant the global admin
+// role; staff roles are granted at RestaurantID. Only the SHA-256 hash of the
+// token is stored.
+type Invitation struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Email        string              `bson:"email" json:"email"`
+	Role         string              `bson:"role" json:"role"`
+	RestaurantID *primitive.ObjectID `bson:"restaurant_id,omitempty" json:"restaurant_id,omitempty"`
+	TokenHash    string              `bson:"token_hash" json:"-"`
+	InvitedBy    primitive.ObjectID  `bson:"invited_by" json:"invited_by"`
+	ExpiresAt    time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	AcceptedAt   *time.Time          `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	AcceptedBy   *primitive.ObjectID `bson:"accepted_by,omitempty" json:"accepted_by,omitempty"`
+	RevokedAt    *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Pending reports whether the invitation can still be accepted at now.
+func (i *Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
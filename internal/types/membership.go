@@ -0,0 +1,35 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Staff roles a user can hold at a single restaurant.
+const (
+	StaffRoleManager = "manager"
+	StaffRoleCashier = "cashier"
+	StaffRoleKitchen = "kitchen"
+	StaffRoleWaiter  = "waiter"
+)
+
+// StaffRoles lists every restaurant staff role.
+var StaffRoles = []string{
+	StaffRoleManager,
+	StaffRoleCashier,
+	StaffRoleKitchen,
+	StaffRoleWaiter,
+}
+
+// Membership gives a user a staff role at one restaurant. A user has at most
+// one membership per restaurant but may work at several restaurants.
+type Membership struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	RestaurantID primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id"`
+	Role         string             `bson:"role" json:"role" validate:"required,oneof=manager cashier kitchen waiter"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
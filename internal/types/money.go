@@ -0,0 +1,260 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// legacyExponent is the number of decimals of the float64 amounts stored
+// before Money existed; the server always rounded those to cents.
+const legacyExponent = 2
+
+var ErrCurrencyMismatch = errors.New("money: currency mismatch")
+
+// ErrMoneyOverflow is returned when an amount does not fit in an int64.
+var ErrMoneyOverflow = errors.New("money: amount out of range")
+
+// currencyExponents lists the ISO 4217 currencies whose minor unit is not a
+// hundredth of the major unit. Every other currency has two decimals.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent returns how many decimals the currency's minor unit has,
+// e.g. 2 for USD (cents) and 0 for JPY.
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[currency]; ok {
+		return exp
+	}
+	return legacyExponent
+}
+
+// Money is an amount in the minor units of an ISO 4217 currency, e.g. 1250
+// with USD for $12.50. Amounts are never floats, so sums do not drift.
+//
+// A Money without a currency is a legacy amount in hundredths, decoded from a
+// plain number in JSON or BSON; In gives it a currency.
+type Money struct {
+	Amount   int64  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// moneyDoc has Money's fields without its codecs.
+type moneyDoc Money
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// MoneyFromMajor converts an amount in major units, e.g. dollars. Like
+// ParseMoney it fails, rather than rounds, when major has more decimals than
+// the currency. It is meant for the plain numbers legacy clients send.
+func MoneyFromMajor(major float64, currency string) (Money, error) {
+	s := strconv.FormatFloat(major, 'f', -1, 64)
+	amount, err := parseDecimal(s, CurrencyExponent(currency))
+	if err != nil {
+		return Money{}, fmt.Errorf("money: invalid amount %s: %w", s, err)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// RoundMoneyFromMajor converts an amount in major units, rounding it to the
+// nearest minor unit of the currency. Floats summed before Money existed
+// drift, e.g. to 3.3000000000000003, so this is how stored legacy amounts
+// are read; amounts sent by clients go through MoneyFromMajor instead.
+func RoundMoneyFromMajor(major float64, currency string) (Money, error) {
+	places := CurrencyExponent(currency)
+	s := strconv.FormatFloat(major, 'f', places, 64)
+	amount, err := parseDecimal(s, places)
+	if err != nil {
+		return Money{}, fmt.Errorf("money: invalid amount %s: %w", s, err)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseMoney reads a decimal amount in major units such as "12.5" without
+// going through a float. It fails when s has more decimals than the currency.
+func ParseMoney(s, currency string) (Money, error) {
+	amount, err := parseDecimal(s, CurrencyExponent(currency))
+	if err != nil {
+		return Money{}, fmt.Errorf("money: invalid %s amount %q", currency, s)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// parseDecimal reads a decimal number with at most places decimals as an
+// integer count of its 10^-places units, e.g. "1.5" with 2 places as 150.
+func parseDecimal(s string, places int) (int64, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if (whole == "" && frac == "") || len(frac) > places {
+		return 0, errors.New("not a decimal with at most " + strconv.Itoa(places) + " places")
+	}
+	digits := whole + frac + strings.Repeat("0", places-len(frac))
+	if strings.ContainsAny(digits, "+-") {
+		return 0, errors.New("misplaced sign")
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// In returns m in currency. A legacy amount without a currency is rescaled
+// to the currency's minor unit; an amount in another currency is an error,
+// since Money does not convert between currencies.
+func (m Money) In(currency string) (Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+	if m.Currency != "" {
+		return Money{}, fmt.Errorf("%w: %s is not %s", ErrCurrencyMismatch, m.Currency, currency)
+	}
+	return Money{Amount: rescale(m.Amount, legacyExponent, CurrencyExponent(currency)), Currency: currency}, nil
+}
+
+// rescale converts amount from one number of decimals to another, rounding
+// half away from zero when decimals are dropped.
+func rescale(amount int64, from, to int) int64 {
+	if to >= from {
+		return amount * int64(math.Pow10(to-from))
+	}
+	div := int64(math.Pow10(from - to))
+	q, r := amount/div, amount%div
+	if 2*abs(r) >= div {
+		if amount < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Add returns m + o, which must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: cannot add %s to %s", ErrCurrencyMismatch, o.Currency, m.Currency)
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrMoneyOverflow, m, o)
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Mul returns m times n, e.g. a unit price times a quantity.
+func (m Money) Mul(n int64) (Money, error) {
+	product := m.Amount * n
+	if m.Amount != 0 && (product/m.Amount != n || (m.Amount == -1 && n == math.MinInt64)) {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrMoneyOverflow, m, n)
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats m in major units followed by its currency, e.g. "12.50 USD".
+func (m Money) String() string {
+	exp := legacyExponent
+	if m.Currency != "" {
+		exp = CurrencyExponent(m.Currency)
+	}
+	return strings.TrimSpace(formatDecimal(m.Amount, exp) + " " + m.Currency)
+}
+
+// formatDecimal writes n units of 10^-places as a decimal, e.g. 150 with 2
+// places as "1.50"; it is the inverse of parseDecimal.
+func formatDecimal(n int64, places int) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatInt(abs(n), 10)
+	if places > 0 {
+		if len(digits) <= places {
+			digits = strings.Repeat("0", places-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+	}
+	return sign + digits
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyDoc(m))
+}
+
+// UnmarshalJSON accepts {"amount": 1250, "currency": "USD"} as well as the
+// plain decimal numbers, e.g. 12.5, that clients sent before Money existed.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var major float64
+	if err := json.Unmarshal(data, &major); err == nil {
+		legacy, err := MoneyFromMajor(major, "")
+		if err != nil {
+			return err
+		}
+		*m = legacy
+		return nil
+	}
+	var doc moneyDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*m = Money(doc)
+	return nil
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(moneyDoc(m))
+}
+
+// UnmarshalBSONValue reads the embedded document Money is stored as, or the
+// plain number a document written before Money holds until it is migrated,
+// rounded to hundredths.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	var err error
+	switch t {
+	case bsontype.EmbeddedDocument:
+		var doc moneyDoc
+		if err := raw.Unmarshal(&doc); err != nil {
+			return err
+		}
+		*m = Money(doc)
+	case bsontype.Double:
+		*m, err = RoundMoneyFromMajor(raw.Double(), "")
+	case bsontype.Int32:
+		*m, err = MoneyFromMajor(float64(raw.Int32()), "")
+	case bsontype.Int64:
+		*m, err = MoneyFromMajor(float64(raw.Int64()), "")
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+	default:
+		return fmt.Errorf("money: cannot decode BSON %s", t)
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `{"amount": 1250, "currency": "USD"}`, want: Money{1250, "USD"}},
+		{in: `{"amount": 500, "currency": "JPY"}`, want: Money{500, "JPY"}},
+		// Plain numbers are legacy amounts in hundredths without a currency
+		{in: `12.5`, want: Money{1250, ""}},
+		{in: `12.50`, want: Money{1250, ""}},
+		{in: `9.99`, want: Money{999, ""}},
+		{in: `0.1`, want: Money{10, ""}},
+		{in: `7`, want: Money{700, ""}},
+		{in: `1e2`, want: Money{10000, ""}},
+		{in: `-3.1`, want: Money{-310, ""}},
+		{in: `12.555`, wantErr: true},
+		{in: `0.001`, wantErr: true},
+		{in: `"12.50"`, wantErr: true},
+	} {
+		var got Money
+		err := json.Unmarshal([]byte(tc.in), &got)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %v, want an error", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("Unmarshal(%s) = %#v, %v, want %#v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	in := Money{Amount: 1999, Currency: "EUR"}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":1999,"currency":"EUR"}` {
+		t.Errorf("Marshal = %s", data)
+	}
+	var out Money
+	if err := json.Unmarshal(data, &out); err != nil || out != in {
+		t.Errorf("round trip = %#v, %v", out, err)
+	}
+}
+
+func TestMoneyUnmarshalBSON(t *testing.T) {
+	type doc struct {
+		Price Money `bson:"price"`
+	}
+	for _, tc := range []struct {
+		name    string
+		stored  bson.M
+		want    Money
+		wantErr bool
+	}{
+		{"document", bson.M{"price": bson.M{"amount": int64(1250), "currency": "USD"}}, Money{1250, "USD"}, false},
+		{"legacy double", bson.M{"price": 12.5}, Money{1250, ""}, false},
+		{"legacy double with cents", bson.M{"price": 19.99}, Money{1999, ""}, false},
+		{"legacy int32", bson.M{"price": int32(12)}, Money{1200, ""}, false},
+		{"legacy int64", bson.M{"price": int64(12)}, Money{1200, ""}, false},
+		{"null", bson.M{"price": nil}, Money{}, false},
+		// Sums of floats drift off the cent; they are rounded back
+		{"legacy double with float drift", bson.M{"price": 3.3000000000000003}, Money{330, ""}, false},
+		{"legacy double sum", bson.M{"price": 0.1 + 0.2}, Money{30, ""}, false},
+		{"legacy negative double with float drift", bson.M{"price": -1.1 - 2.2}, Money{-330, ""}, false},
+		{"legacy double with excess decimals", bson.M{"price": 19.999}, Money{2000, ""}, false},
+		{"legacy NaN", bson.M{"price": math.NaN()}, Money{}, true},
+		{"string", bson.M{"price": "12.50"}, Money{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := bson.Marshal(tc.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got doc
+			err = bson.Unmarshal(data, &got)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal = %#v, want an error", got.Price)
+				}
+				return
+			}
+			if err != nil || got.Price != tc.want {
+				t.Fatalf("Unmarshal = %#v, %v, want %#v", got.Price, err, tc.want)
+			}
+		})
+	}
+
+	// What is written back is the document form
+	data, _ := bson.Marshal(doc{Price: Money{1250, "USD"}})
+	var raw bson.M
+	bson.Unmarshal(data, &raw)
+	if price, ok := raw["price"].(bson.M); !ok || price["amount"] != int64(1250) || price["currency"] != "USD" {
+		t.Errorf("marshaled as %#v", raw["price"])
+	}
+}
+
+func TestMoneyFromMajor(t *testing.T) {
+	for _, tc := range []struct {
+		major    float64
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{12.5, "USD", 1250, false},
+		{12.5, "JPY", 0, true},
+		{1250, "JPY", 1250, false},
+		{1.234, "KWD", 1234, false},
+		{1.2345, "KWD", 0, true},
+		{12.555, "USD", 0, true},
+	} {
+		got, err := MoneyFromMajor(tc.major, tc.currency)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("MoneyFromMajor(%v, %s) = %v, want an error", tc.major, tc.currency, got)
+			}
+			continue
+		}
+		if err != nil || got != (Money{tc.want, tc.currency}) {
+			t.Errorf("MoneyFromMajor(%v, %s) = %#v, %v, want %d", tc.major, tc.currency, got, err, tc.want)
+		}
+	}
+}
+
+func TestRoundMoneyFromMajor(t *testing.T) {
+	for _, tc := range []struct {
+		major    float64
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{12.5, "USD", 1250, false},
+		{3.3000000000000003, "USD", 330, false},
+		{0.1 + 0.2, "USD", 30, false},
+		{12.499, "USD", 1250, false},
+		{-0.004, "USD", 0, false},
+		{1249.6, "JPY", 1250, false},
+		{1.2346, "KWD", 1235, false},
+		{math.Inf(1), "USD", 0, true},
+		{1e300, "USD", 0, true},
+	} {
+		got, err := RoundMoneyFromMajor(tc.major, tc.currency)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("RoundMoneyFromMajor(%v, %s) = %v, want an error", tc.major, tc.currency, got)
+			}
+			continue
+		}
+		if err != nil || got != (Money{tc.want, tc.currency}) {
+			t.Errorf("RoundMoneyFromMajor(%v, %s) = %#v, %v, want %d", tc.major, tc.currency, got, err, tc.want)
+		}
+	}
+}
+
+func TestLegacyMoneyIn(t *testing.T) {
+	for _, tc := range []struct {
+		legacy   int64
+		currency string
+		want     int64
+	}{
+		{1250, "USD", 1250},
+		{1250, "JPY", 13},
+		{1249, "JPY", 12},
+		{-1250, "JPY", -13},
+		{1250, "KWD", 12500},
+	} {
+		got, err := Money{Amount: tc.legacy}.In(tc.currency)
+		if err != nil || got != (Money{tc.want, tc.currency}) {
+			t.Errorf("Money{%d}.In(%s) = %#v, %v, want %d", tc.legacy, tc.currency, got, err, tc.want)
+		}
+	}
+	if _, err := (Money{100, "EUR"}).In("USD"); err == nil {
+		t.Error("In converted between currencies")
+	}
+}
+
+func TestMoneyArithmeticOverflow(t *testing.T) {
+	usd := func(amount int64) Money { return NewMoney(amount, "USD") }
+
+	for _, tc := range []struct {
+		m        Money
+		n        int64
+		want     int64
+		overflow bool
+	}{
+		{usd(999), 2, 1998, false},
+		{usd(0), math.MaxInt64, 0, false},
+		{usd(-3), 4, -12, false},
+		{usd(math.MaxInt64 / 2), 3, 0, true},
+		{usd(1 << 40), 1 << 30, 0, true},
+		{usd(-1), math.MinInt64, 0, true},
+	} {
+		got, err := tc.m.Mul(tc.n)
+		if tc.overflow != errors.Is(err, ErrMoneyOverflow) || (!tc.overflow && got != usd(tc.want)) {
+			t.Errorf("%v.Mul(%d) = %v, %v", tc.m, tc.n, got, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		a, b     int64
+		overflow bool
+	}{
+		{math.MaxInt64 - 1, 1, false},
+		{math.MaxInt64, 1, true},
+		{math.MinInt64, -1, true},
+		{math.MinInt64, math.MaxInt64, false},
+	} {
+		got, err := usd(tc.a).Add(usd(tc.b))
+		if tc.overflow != errors.Is(err, ErrMoneyOverflow) || (!tc.overflow && got != usd(tc.a+tc.b)) {
+			t.Errorf("Add(%d, %d) = %v, %v", tc.a, tc.b, got, err)
+		}
+	}
+}
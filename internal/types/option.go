@@ -0,0 +1,45 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// OptionGroup is a choice offered with a menu item, e.g. "Size" or "Toppings".
+type OptionGroup struct {
+	// ID is assigned by the server and kept across edits, so orders can name
+	// the group they chose from.
+	ID   primitive.ObjectID `bson:"_id" json:"id"`
+	Name string             `bson:"name" json:"name" validate:"required,min=1,max=50"`
+	// Required groups need at least one selection, or MinSelections if higher.
+	Required      bool `bson:"required" json:"required"`
+	MinSelections int  `bson:"min_selections" json:"min_selections" validate:"gte=0"`
+	// MaxSelections caps the selections; zero means any number of options.
+	MaxSelections int      `bson:"max_selections" json:"max_selections" validate:"gte=0"`
+	Options       []Option `bson:"options" json:"options" validate:"required,min=1,max=50,unique=Name,dive"`
+}
+
+// Option is one choice of a group, e.g. "Large" or "Extra cheese".
+type Option struct {
+	ID   primitive.ObjectID `bson:"_id" json:"id"`
+	Name string             `bson:"name" json:"name" validate:"required,min=1,max=50"`
+	// PriceDelta is added to the item's price when the option is selected;
+	// it may be negative, e.g. for a small size.
+	PriceDelta Money `bson:"price_delta" json:"price_delta"`
+}
+
+// MinRequired returns how many options of the group an order must select.
+func (g OptionGroup) MinRequired() int {
+	if g.Required && g.MinSelections < 1 {
+		return 1
+	}
+	return g.MinSelections
+}
+
+// SelectedOption is an option chosen on an order line. The client sends
+// GroupID and OptionID; the names and price delta are snapshotted from the
+// menu when the order is placed.
+type SelectedOption struct {
+	GroupID    primitive.ObjectID `bson:"group_id" json:"group_id" validate:"required"`
+	OptionID   primitive.ObjectID `bson:"option_id" json:"option_id" validate:"required"`
+	Group      string             `bson:"group" json:"group"`
+	Name       string             `bson:"name" json:"name"`
+	PriceDelta Money              `bson:"price_delta" json:"price_delta"`
+}
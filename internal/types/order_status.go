@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// orderTransitions lists, for each order status, the statuses it may move to.
+// Completed and cancelled orders are final. Cancellation is only possible
+// until the food is ready.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:   {OrderStatusPreparing, OrderStatusCancelled},
+	OrderStatusPreparing: {OrderStatusReady, OrderStatusCancelled},
+	OrderStatusReady:     {OrderStatusCompleted},
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another.
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOrderStatuses returns the statuses an order in the given status may move to.
+func NextOrderStatuses(from string) []string {
+	return orderTransitions[from]
+}
+
+// StatusChange records a single order status transition.
+type StatusChange struct {
+	From      string             `bson:"from,omitempty" json:"from,omitempty"`
+	To        string             `bson:"to" json:"to"`
+	ActorID   primitive.ObjectID `bson:"actor_id" json:"actor_id"`
+	ActorRole string             `bson:"actor_role" json:"actor_role"`
+	At        time.Time          `bson:"at" json:"at"`
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanTransitionOrder(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{OrderStatusPending, OrderStatusPreparing}:   true,
+		{OrderStatusPending, OrderStatusCancelled}:   true,
+		{OrderStatusPreparing, OrderStatusReady}:     true,
+		{OrderStatusPreparing, OrderStatusCancelled}: true,
+		{OrderStatusReady, OrderStatusCompleted}:     true,
+	}
+	// Every other pair is refused, including staying in the same status and
+	// leaving the final statuses
+	for _, from := range OrderStatuses {
+		for _, to := range OrderStatuses {
+			want := allowed[[2]string{from, to}]
+			if got := CanTransitionOrder(from, to); got != want {
+				t.Errorf("CanTransitionOrder(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	if CanTransitionOrder("", OrderStatusPending) || CanTransitionOrder(OrderStatusPending, "shipped") {
+		t.Error("transition involving an unknown status allowed")
+	}
+}
+
+func TestNextOrderStatuses(t *testing.T) {
+	for _, tc := range []struct {
+		from string
+		want []string
+	}{
+		{OrderStatusPending, []string{OrderStatusPreparing, OrderStatusCancelled}},
+		{OrderStatusPreparing, []string{OrderStatusReady, OrderStatusCancelled}},
+		{OrderStatusReady, []string{OrderStatusCompleted}},
+		{OrderStatusCompleted, nil},
+		{OrderStatusCancelled, nil},
+	} {
+		if got := NextOrderStatuses(tc.from); !slices.Equal(got, tc.want) {
+			t.Errorf("NextOrderStatuses(%s) = %v, want %v", tc.from, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// PromoPercentage takes Percent off the whole order.
+	PromoPercentage = "percentage"
+	// PromoFixedAmount takes Amount off the whole order, at most its value.
+	PromoFixedAmount = "fixed_amount"
+	// PromoBuyXGetY makes GetQuantity of every BuyQuantity+GetQuantity
+	// qualifying items free, the cheapest ones.
+	PromoBuyXGetY = "buy_x_get_y"
+	// PromoCategory takes Percent off the items of Categories.
+	PromoCategory = "category"
+)
+
+// Promotion is a discount customers get by entering its code with an order.
+// Without RestaurantID it is valid at every restaurant whose currency its
+// amounts are in.
+type Promotion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Code        string             `bson:"code" json:"code" validate:"required,min=3,max=32,alphanum"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty" validate:"max=200"`
+	Type        string             `bson:"type" json:"type" validate:"required,oneof=percentage fixed_amount buy_x_get_y category"`
+
+	Percent Percent `bson:"percent,omitempty" json:"percent,omitempty" validate:"gte=0,lte=1000000"`
+	Amount  Money   `bson:"amount,omitempty" json:"amount"`
+	// Categories and MenuItemIDs limit which items a category or
+	// buy-X-get-Y promotion applies to; an item matching either qualifies.
+	Categories  []string             `bson:"categories,omitempty" json:"categories,omitempty" validate:"max=50,dive,required,max=50"`
+	MenuItemIDs []primitive.ObjectID `bson:"menu_item_ids,omitempty" json:"menu_item_ids,omitempty" validate:"max=100"`
+	BuyQuantity int                  `bson:"buy_quantity,omitempty" json:"buy_quantity,omitempty" validate:"gte=0"`
+	GetQuantity int                  `bson:"get_quantity,omitempty" json:"get_quantity,omitempty" validate:"gte=0"`
+
+	RestaurantID *primitive.ObjectID `bson:"restaurant_id,omitempty" json:"restaurant_id,omitempty"`
+	// MinOrder is the least the items must add up to, before discounts and tax.
+	MinOrder Money      `bson:"min_order,omitempty" json:"min_order"`
+	StartsAt *time.Time `bson:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt   *time.Time `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	// MaxRedemptions and MaxPerUser cap how often the code can be used in
+	// total and by one user; zero means unlimited.
+	MaxRedemptions int  `bson:"max_redemptions" json:"max_redemptions" validate:"gte=0"`
+	MaxPerUser     int  `bson:"max_per_user" json:"max_per_user" validate:"gte=0"`
+	Redemptions    int  `bson:"redemptions" json:"redemptions"`
+	Active         bool `bson:"active" json:"active"`
+
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Qualifies reports whether an order line counts for a category or
+// buy-X-get-Y promotion.
+func (p *Promotion) Qualifies(item OrderItem) bool {
+	if len(p.Categories) == 0 && len(p.MenuItemIDs) == 0 {
+		return true
+	}
+	for _, c := range p.Categories {
+		if c == item.Category {
+			return true
+		}
+	}
+	for _, id := range p.MenuItemIDs {
+		if id == item.MenuItemID {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscountLine is what a promotion took off an order.
+type DiscountLine struct {
+	PromotionID primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	Code        string             `bson:"code" json:"code"`
+	Type        string             `bson:"type" json:"type"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Amount      Money              `bson:"amount" json:"amount"`
+}
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// percentPlaces is how many decimals a Percent keeps.
+const percentPlaces = 4
+
+// Percent is a percentage with up to four decimals, kept as an integer count
+// of ten-thousandths of a percent so tax math stays exact: 8.875% is 88750.
+// In JSON it is the plain percentage, e.g. 8.875.
+type Percent int64
+
+// Hundred is 100%.
+const Hundred Percent = 1_000_000
+
+func ParsePercent(s string) (Percent, error) {
+	n, err := parseDecimal(s, percentPlaces)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q: %w", s, err)
+	}
+	return Percent(n), nil
+}
+
+// String formats p without trailing zeros, e.g. "8.875".
+func (p Percent) String() string {
+	s := formatDecimal(int64(p), percentPlaces)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON reads the number's digits rather than a float, so 8.875 is
+// exactly 88750.
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	text := string(bytes.TrimSpace(data))
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	} else if !json.Valid(data) {
+		return fmt.Errorf("invalid percentage %s", text)
+	}
+	parsed, err := ParsePercent(text)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// TaxPolicy is how a restaurant charges tax. Rules apply in order.
+type TaxPolicy struct {
+	// Inclusive means menu prices already contain the taxes, which are then
+	// worked back out of them; otherwise taxes are added on top.
+	Inclusive bool      `bson:"inclusive" json:"inclusive"`
+	Rules     []TaxRule `bson:"rules" json:"rules" validate:"max=20,unique=Name,dive"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	// UpdatedBy is the user who last edited the policy.
+	UpdatedBy primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+}
+
+// TaxRule is one tax, e.g. VAT or a city sales tax.
+type TaxRule struct {
+	Name string `bson:"name" json:"name" validate:"required,min=1,max=50"`
+	// Rate applies to every menu category without its own rate.
+	Rate          Percent        `bson:"rate" json:"rate" validate:"gte=0,lte=1000000"`
+	CategoryRates []CategoryRate `bson:"category_rates,omitempty" json:"category_rates,omitempty" validate:"max=50,unique=Category,dive"`
+	// Compound taxes are charged on the amount plus the taxes of the rules
+	// before them, e.g. a provincial tax levied on top of a federal one.
+	Compound bool `bson:"compound" json:"compound"`
+}
+
+// CategoryRate overrides a rule's rate for one menu category; a zero rate
+// exempts the category.
+type CategoryRate struct {
+	Category string  `bson:"category" json:"category" validate:"required,min=1,max=50"`
+	Rate     Percent `bson:"rate" json:"rate" validate:"gte=0,lte=1000000"`
+}
+
+// RateFor returns the rate the rule charges on items of category.
+func (r TaxRule) RateFor(category string) Percent {
+	for _, cr := range r.CategoryRates {
+		if cr.Category == category {
+			return cr.Rate
+		}
+	}
+	return r.Rate
+}
+
+// TaxLine is what one rule charged on an order.
+type TaxLine struct {
+	Name     string  `bson:"name" json:"name"`
+	Rate     Percent `bson:"rate" json:"rate"` // the rule's base rate; category rates may differ
+	Compound bool    `bson:"compound" json:"compound"`
+	// Taxable is the amount the tax was charged on.
+	Taxable Money `bson:"taxable" json:"taxable"`
+	Amount  Money `bson:"amount" json:"amount"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a long-lived, single-use credential exchanged for a new
+// access token. Only the SHA-256 hash of the token is stored. Every rotation
+// of one login shares a FamilyID, so replaying an already rotated token can
+// revoke the whole chain.
+type RefreshToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	FamilyID   primitive.ObjectID `bson:"family_id" json:"family_id"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ReplacedBy primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+}
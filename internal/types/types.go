@@ -22,6 +22,11 @@ type Restaurant struct {
 	Description string   `bson:"description,omitempty" json:"description,omitempty" validate:"max=500"`
 	MenuItems   []string `bson:"menu_items,omitempty" json:"menu_items,omitempty"`
 	IsActive    bool     `bson:"is_active" json:"is_active"`
+	// Currency is the ISO 4217 code every price at the restaurant is in; it
+	// cannot change once the restaurant exists.
+	Currency string `bson:"currency" json:"currency" validate:"omitempty,iso4217"`
+	// Tax is edited through its own endpoint only.
+	Tax TaxPolicy `bson:"tax" json:"tax"`
 }
 
 // MenuItem entity
@@ -30,8 +35,10 @@ type MenuItem struct {
 	Restaurant primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id" validate:"required"`
 	Name       string             `bson:"name" json:"name" validate:"required,min=1,max=100"`
 	Category   string             `bson:"category" json:"category" validate:"required,min=1,max=50"`
-	Price      float64            `bson:"price" json:"price" validate:"required,gt=0"`
+	Price      Money              `bson:"price" json:"price" validate:"required,gt=0"`
 	Available  bool               `bson:"available" json:"available"`
+	// OptionGroups are the choices customers make when ordering the item.
+	OptionGroups []OptionGroup `bson:"option_groups,omitempty" json:"option_groups,omitempty" validate:"max=20,unique=Name,dive"`
 }
 
 // Order entity
@@ -40,13 +47,36 @@ type Order struct {
 	UserID     primitive.ObjectID `bson:"user_id" json:"user_id" validate:"required"`
 	Restaurant primitive.ObjectID `bson:"restaurant_id" json:"restaurant_id" validate:"required"`
 	Items      []OrderItem        `bson:"items" json:"items" validate:"required,min=1,dive"` // at least 1 item
-	Status     string             `bson:"status" json:"status" validate:"required,oneof=pending preparing ready completed cancelled"`
-	TotalPrice float64            `bson:"total_price" json:"total_price" validate:"required,gte=0"`
+	Status     string             `bson:"status" json:"status" validate:"omitempty,oneof=pending preparing ready completed cancelled"`
+	// PromoCode is the code the customer entered, if any.
+	PromoCode string `bson:"promo_code,omitempty" json:"promo_code,omitempty" validate:"omitempty,max=32"`
+
+	// Discounts, Subtotal, Taxes and the totals are computed by the server.
+	// Subtotal is after discounts and excludes tax, and TotalPrice is
+	// Subtotal plus TaxTotal; with tax-inclusive pricing TotalPrice is what
+	// the discounted menu prices add up to.
+	Discounts     []DiscountLine `bson:"discounts,omitempty" json:"discounts,omitempty"`
+	DiscountTotal Money          `bson:"discount_total" json:"discount_total"`
+	Subtotal      Money          `bson:"subtotal" json:"subtotal"`
+	Taxes         []TaxLine      `bson:"taxes" json:"taxes"`
+	TaxTotal      Money          `bson:"tax_total" json:"tax_total"`
+	TaxInclusive  bool           `bson:"tax_inclusive" json:"tax_inclusive"`
+	TotalPrice    Money          `bson:"total_price" json:"total_price"`
+
+	StatusHistory []StatusChange `bson:"status_history" json:"status_history"`
 }
 
-// OrderItem sub-document
+// OrderItem sub-document. Name, Category, Price and LineTotal are snapshotted
+// from the menu when the order is placed; Price is the unit price including
+// the selected options.
 type OrderItem struct {
 	MenuItemID primitive.ObjectID `bson:"menu_item_id" json:"menu_item_id" validate:"required"`
-	Quantity   int                `bson:"quantity" json:"quantity" validate:"required,gt=0"`
-	Price      float64            `bson:"price" json:"price" validate:"required,gt=0"`
+	Quantity   int                `bson:"quantity" json:"quantity" validate:"required,gt=0,max=1000"`
+	Options    []SelectedOption   `bson:"options,omitempty" json:"options,omitempty" validate:"max=100,dive"`
+	Name       string             `bson:"name" json:"name"`
+	Category   string             `bson:"category" json:"category"`
+	Price      Money              `bson:"price" json:"price"`
+	LineTotal  Money              `bson:"line_total" json:"line_total"`
+	// Discount is the part of LineTotal taken off by the order's promotion.
+	Discount Money `bson:"discount" json:"discount"`
 }
@@ -1,17 +1,69 @@
 package types
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name" json:"name" validate:"required,min=2,max=100"`
-	Email     string             `bson:"email" json:"email" validate:"required,email"`
-	Password  string             `bson:"password,omitempty" json:"password,omitempty" validate:"required,min=8"`
-	Role      string             `bson:"role" json:"role" validate:"omitempty,oneof=admin customer"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name     string             `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Email    string             `bson:"email" json:"email" validate:"required,email"`
+	Password string             `bson:"password,omitempty" json:"-"`
+	Role     string             `bson:"role" json:"role" validate:"omitempty,oneof=admin customer"`
+	// EmailVerified is set once the user redeems the token mailed to Email.
+	EmailVerified   bool       `bson:"email_verified" json:"email_verified"`
+	EmailVerifiedAt *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `bson:"updated_at" json:"updated_at"`
+	MFA             MFA        `bson:"mfa" json:"mfa"`
+	// LockoutEvents is the recent history of login lockouts and unlocks.
+	LockoutEvents []LockoutEvent `bson:"lockout_events,omitempty" json:"lockout_events,omitempty"`
+	// Identities are the external identity provider accounts that log in as this user.
+	Identities []Identity `bson:"identities,omitempty" json:"identities,omitempty"`
+}
+
+// Identity links a user to the subject of an OpenID Connect issuer.
+type Identity struct {
+	Issuer   string    `bson:"issuer" json:"issuer"`
+	Subject  string    `bson:"subject" json:"subject"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in, so
+// addresses differing only in case or surrounding spaces are one account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// MFA holds a user's TOTP two-factor settings. Only whether it is enabled is
+// ever serialized to clients.
+type MFA struct {
+	Enabled bool   `bson:"enabled" json:"enabled"`
+	Secret  string `bson:"secret,omitempty" json:"-"`
+	// PendingSecret is the secret being enrolled until a first code confirms it.
+	PendingSecret string `bson:"pending_secret,omitempty" json:"-"`
+	// RecoveryCodes are the SHA-256 hashes of the unused recovery codes.
+	RecoveryCodes []string `bson:"recovery_codes,omitempty" json:"-"`
+	// LastUsedStep is the time step of the last accepted code; older or equal
+	// steps are refused so a code works only once.
+	LastUsedStep int64      `bson:"last_used_step,omitempty" json:"-"`
+	EnabledAt    *time.Time `bson:"enabled_at,omitempty" json:"enabled_at,omitempty"`
+}
+
+const (
+	LockoutEventLocked   = "locked"
+	LockoutEventUnlocked = "unlocked"
+)
+
+// LockoutEvent records an account being locked after repeated failed logins,
+// or unlocked by an admin.
+type LockoutEvent struct {
+	Type        string              `bson:"type" json:"type"`
+	IP          string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	LockedUntil *time.Time          `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	ActorID     *primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	At          time.Time           `bson:"at" json:"at"`
 }